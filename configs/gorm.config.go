@@ -1,5 +1,11 @@
 package configs
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type GormPropertyType string
 
 const (
@@ -18,6 +24,23 @@ const (
 	GormFilterTypeLTE   GormFilterType = "lte"
 	GormFilterTypeGTE   GormFilterType = "gte"
 	GormFilterTypeRegex GormFilterType = "regex"
+	// GormFilterTypeDateRange reads the key's `_from` and `_to` values (dates or RFC 3339
+	// times, either one optional) and matches the column between them, inclusive. A
+	// date-only `_to` covers that whole day.
+	GormFilterTypeDateRange GormFilterType = "date_range"
+	// GormFilterTypeArrayContains matches rows whose array column (a Postgres array, or a
+	// JSON array elsewhere) holds the value, or all of the values, e.g. ?tags=go.
+	GormFilterTypeArrayContains GormFilterType = "array_contains"
+)
+
+type GormSearchStrategy string
+
+const (
+	// GormSearchStrategyLike matches with lower(col) LIKE '%term%' (the default).
+	GormSearchStrategyLike GormSearchStrategy = "like"
+	// GormSearchStrategyFullText uses the database full-text search: MATCH ... AGAINST on
+	// MySQL and to_tsvector/plainto_tsquery on Postgres. Other databases fall back to LIKE.
+	GormSearchStrategyFullText GormSearchStrategy = "fulltext"
 )
 
 type GormRegexConcat struct {
@@ -28,16 +51,50 @@ type GormRegexConcat struct {
 type GormFilterProperty struct {
 	ColumnName string
 	FilterType GormFilterType
+	// Group OR-s this filter with the other keys of the same group (groups and ungrouped
+	// keys are AND-ed). A member absent from the filter takes the value sent under the
+	// group name, so one value can match several columns.
+	Group string
 }
 
+type GormSearchMode string
+
+const (
+	GormSearchModeContains GormSearchMode = "contains" // %term% (the default)
+	GormSearchModePrefix   GormSearchMode = "prefix"   // term%
+	GormSearchModeSuffix   GormSearchMode = "suffix"   // %term
+	GormSearchModeExact    GormSearchMode = "exact"    // = term
+)
+
+// GormSearchProperty is a column matched against the `search` query param.
 type GormSearchProperty struct {
-	Key string
+	Key  string         // column name, optionally table-qualified (see SearchJoins)
+	Mode GormSearchMode // defaults to GormSearchModeContains
+
+	// CaseSensitive compares the column as stored instead of lower-casing both sides.
+	CaseSensitive bool
+
+	// Weight ranks search results: when any searchable column has a weight and the client
+	// doesn't pick a sort_key, rows are ordered by the sum of the weights of the columns
+	// they match, before the default sort.
+	Weight int
+}
+
+// SearchColumns builds Searchable entries that use the default options from column names:
+//
+//	Searchable: configs.SearchColumns("name_en", "name_ar")
+func SearchColumns(columns ...string) []GormSearchProperty {
+	properties := make([]GormSearchProperty, len(columns))
+	for i, column := range columns {
+		properties[i] = GormSearchProperty{Key: column}
+	}
+	return properties
 }
 
 type GormConfig struct {
 	Model         interface{}
 	Filterable    map[string]GormFilterProperty
-	Searchable    []string
+	Searchable    []GormSearchProperty
 	DefaultSort   string
 	SelectHandler func(lang string) []GormSelectField
 	Preloads      []GormPreloadConfig
@@ -49,8 +106,244 @@ type GormConfig struct {
 	// When set, these take precedence over SelectHandler/Preloads for list queries.
 	ListSelectHandler func(lang string) []GormSelectField
 	ListPreloads      []GormPreloadConfig
+
+	// AdditionalPreloads are merged (by Relation) into the preload set instead of replacing it.
+	// In a per-call config without Preloads, they extend the repository's default preloads.
+	AdditionalPreloads []GormPreloadConfig
+
+	// Sortable lists the sort_key values a client may use. When set, other keys fail the
+	// query with dto.ErrInvalidFilter (a 400); when nil, sort_key is used as the column as
+	// is. Prefer to-one relations for joined columns: a to-many join repeats parent rows.
+	Sortable map[string]GormSortProperty
+
+	// SortExpressions maps sort_key values to server-defined SQL expressions, for orders that
+	// are not a column, e.g. "score": "upvotes - downvotes". Clients only send the key, never
+	// SQL. When set (like Sortable), keys found in neither map are rejected.
+	SortExpressions map[string]string
+
+	// StrictFilters makes QueryBuilder reject query params that match no Filterable key (or
+	// group), filter DTO field or base param, instead of ignoring them -- so a typo such as
+	// ?statuss=active fails with a 400 rather than returning unfiltered results.
+	StrictFilters bool
+
+	// SearchStrategy selects how Searchable columns match the search term (defaults to
+	// GormSearchStrategyLike). Full-text search needs a matching full-text index.
+	SearchStrategy GormSearchStrategy
+
+	// RankedSearch makes weighted Searchable columns (see GormSearchProperty.Weight) score by
+	// how well they match: an exact match counts three times the weight, a prefix match twice
+	// and a substring match once, so autocomplete lists the closest matches first.
+	RankedSearch bool
+
+	// SearchJoins maps the table of a qualified Searchable entry (e.g. "authors" for
+	// "authors.name") to the join that brings it in. QueryBuilder requests the join only when
+	// a search term is given. Prefer to-one relations: a to-many join repeats parent rows.
+	SearchJoins map[string]string
+
+	// MinSearchLength ignores search terms shorter than this many characters (after trimming
+	// surrounding whitespace), so a one-letter search doesn't scan the whole table.
+	MinSearchLength int
+
+	// Includable lists the relations a client may ask for with `?include=a,b` on list queries,
+	// keyed by the name used in the query string. They are preloaded only when requested;
+	// BuildBaseQuery rejects other names with dto.ErrInvalidInclude, answered with a 400.
+	Includable map[string]GormPreloadConfig
+
+	// MaxIncludeDepth caps how many relations deep an include path may go ("a.b.c" is 3),
+	// so clients can't ask for expensive nested preloads; deeper paths are a 400. Zero means
+	// no limit beyond Includable.
+	MaxIncludeDepth int
+
+	// Paginator replaces the offset/limit scope FindAllWithPaging applies (repositories.Paginate),
+	// e.g. to cap the offset or use a database-specific hint. It gets the normalized page and
+	// per-page values.
+	Paginator func(page, perPage int) func(db *gorm.DB) *gorm.DB
+
+	// WindowCount makes FindAllWithPaging fetch the total with the page in a single query
+	// (COUNT(*) OVER()) on Postgres. Queries with a SelectHandler, preloads, Group or an
+	// after_id, other databases and empty pages keep the separate count query.
+	WindowCount bool
+
+	// CountUnfiltered makes FindAllWithPaging run a second count without the conditions
+	// and report both totals in the response Metadata (models.ListMetadata).
+	CountUnfiltered bool
+
+	// IDChunkSize caps how many ids FindByIDs/DeleteByIDs put in a single IN clause
+	// (defaults to DefaultIDChunkSize).
+	IDChunkSize int
+
+	// MaxIDList caps how many ids FindAllIDs may return (defaults to DefaultMaxIDList).
+	MaxIDList int
+
+	// CreateBatchSize caps how many rows BulkCreate/BulkCreateReturning insert per statement
+	// (defaults to DefaultCreateBatchSize). All batches share one transaction.
+	CreateBatchSize int
+
+	// Selectable restricts the columns callers may pick explicitly (e.g. FindOneColumns).
+	// When empty, any well-formed column name is accepted.
+	Selectable []string
+
+	// Fields, when set, makes FindOne and FindOneByPK load only these columns (validated
+	// against Selectable); the other fields come back zero-valued. Select the primary key
+	// too when preloading has-many relations.
+	Fields []string
+
+	// IDGenerator, when set, generates the primary key of entities created with a zero-valued
+	// one (client-side UUIDs, ULIDs...), so the id is known without a database round trip.
+	// The returned value must be assignable to the primary key field.
+	IDGenerator func() any
+
+	// FindOneOrder is the ORDER BY FindOne and FindOneColumns pick their row by, e.g.
+	// "created_at DESC" for the latest match. The primary key breaks ties, and is the only
+	// order when this is empty.
+	FindOneOrder string
+
+	// CreatableColumns and UpdatableColumns restrict the columns (or field names) that
+	// Create*/CreateOrUpdate and Update/UpdateByPK may set; other fields of the payload are
+	// dropped. Primary keys and auto-managed timestamps are always written. Empty means no
+	// restriction. UpdateColumnsByPK writes the columns it is given as-is.
+	CreatableColumns []string
+	UpdatableColumns []string
+
+	// CheckReferences maps foreign key columns (or field names) to the rows they reference.
+	// Create, CreateOrUpdate and the bulk creates check those rows exist (and aren't
+	// soft-deleted) before inserting, and fail with a 422 instead of the database's foreign
+	// key violation. Unset (zero or NULL) keys are not checked.
+	CheckReferences map[string]GormReference
+
+	// AllowFullTableDelete lets Delete run with empty conditions (nil, an empty map or a
+	// zero struct), deleting every row. Without it such calls fail with ErrFullTableDelete.
+	AllowFullTableDelete bool
+
+	// AllowFullTableUpdate lets Update run with empty conditions, updating every row.
+	// Without it such calls fail with ErrFullTableUpdate.
+	AllowFullTableUpdate bool
+
+	// VersionColumn enables optimistic locking (e.g. "version", an integer column):
+	// UpdateByPKVersioned updates a row only while it still has the version the client read,
+	// and increments it.
+	VersionColumn string
+
+	// CascadeSoftDelete lists has-one/has-many relations (by field name, e.g. "Comments")
+	// that are soft-deleted together with the parent, in the same transaction.
+	CascadeSoftDelete []string
+
+	// CascadeRestore makes Restore/RestoreByConditions also restore the CascadeSoftDelete
+	// relations that were deleted with the parent (or after it).
+	CascadeRestore bool
+
+	// SoftDeleteColumn enables soft delete on a column of your own (e.g. "removed_at" or
+	// "is_deleted") for models without a gorm.DeletedAt field. Delete marks the column,
+	// Restore clears it and reads skip marked rows unless UnScoped is set.
+	SoftDeleteColumn string
+
+	// SoftDeleteStrategy tells how SoftDeleteColumn marks a row (defaults to SoftDeleteTimestamp).
+	SoftDeleteStrategy SoftDeleteStrategy
+
+	// TrashedUniqueColumns lists unique columns (or field names, e.g. "email") whose values
+	// soft-deleted rows keep holding, which blocks re-creating the record. Leave it empty
+	// when the unique indexes are partial (WHERE deleted_at IS NULL).
+	// TrashedUniqueStrategy tells how to handle them (defaults to TrashedUniqueReject).
+	TrashedUniqueColumns  []string
+	TrashedUniqueStrategy TrashedUniqueStrategy
+
+	// SQLComment prefixes every query with a comment naming the model, the repository
+	// method and the request id (see middlewares.RequestIDMiddleware), e.g.
+	// /* model=User op=FindAll req=4f1c... */, so slow-query logs and pg_stat_activity
+	// can be traced back to the request.
+	SQLComment bool
+
+	// Retry re-runs the main reads (FindAll*, FindOne*, Count) when they fail with a
+	// transient error, such as a deadlock, a serialization failure or a dropped connection,
+	// and the writes (Create, Update*, Delete*, Transaction) on the errors that leave them
+	// unapplied: deadlocks, serialization failures and bad connections. Calls inside a
+	// transaction are not retried on their own: the outermost Transaction is. Nil disables
+	// retries.
+	Retry *GormRetry
 }
 
+// GormRetry is the retry policy of GormConfig.Retry.
+type GormRetry struct {
+	// Attempts caps the runs, the first one included (defaults to DefaultRetryAttempts).
+	Attempts int
+
+	// Backoff is the wait before the second run, doubled before each following one and
+	// capped by MaxBackoff when set (defaults to DefaultRetryBackoff).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// IsTransient decides which errors of reads are retried (defaults to
+	// repositories.IsTransientError).
+	IsTransient func(err error) bool
+	// IsRetryableWrite decides which errors of writes (Create, Update*, Delete*,
+	// Transaction) are retried. It must only accept errors that leave the write unapplied,
+	// or a retry could apply it twice (defaults to repositories.IsRetryableWriteError).
+	IsRetryableWrite func(err error) bool
+}
+
+// DefaultIDChunkSize is the IDChunkSize used when none is configured.
+const DefaultIDChunkSize = 1000
+
+// DefaultCreateBatchSize is the CreateBatchSize used when none is configured.
+const DefaultCreateBatchSize = 500
+
+// DefaultMaxIDList is the MaxIDList used when none is configured.
+const DefaultMaxIDList = 10000
+
+// DefaultRetryAttempts and DefaultRetryBackoff are the GormRetry values used when unset.
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 50 * time.Millisecond
+)
+
+type TrashedUniqueStrategy string
+
+const (
+	// TrashedUniqueReject makes the creates fail with a 409 (repositories.TrashedDuplicateError)
+	// naming the soft-deleted row to restore instead.
+	TrashedUniqueReject TrashedUniqueStrategy = "reject"
+	// TrashedUniqueRelease lets the creates take the values: the column of the soft-deleted
+	// row holding one is set to NULL first (it must be nullable), so that row comes back
+	// without it when restored.
+	TrashedUniqueRelease TrashedUniqueStrategy = "release"
+)
+
+type SoftDeleteStrategy string
+
+const (
+	// SoftDeleteTimestamp stores the deletion time; NULL means not deleted.
+	SoftDeleteTimestamp SoftDeleteStrategy = "timestamp"
+	// SoftDeleteBoolean stores true; false means not deleted.
+	SoftDeleteBoolean SoftDeleteStrategy = "boolean"
+)
+
+// GormReference is a row referenced by a foreign key column (see GormConfig.CheckReferences).
+type GormReference struct {
+	Table     string
+	Column    string // defaults to "id"
+	MessageID string // i18n message ID of the error, defaults to "reference_not_found"
+
+	// SoftDeleteColumn marks the soft-deleted rows of Table, which don't count as existing.
+	// Defaults to "deleted_at" when Table has that column.
+	SoftDeleteColumn   string
+	SoftDeleteStrategy SoftDeleteStrategy // how SoftDeleteColumn marks a row (defaults to SoftDeleteTimestamp)
+}
+
+// GormSortProperty is the column a Sortable key orders by. Join is added to the query when
+// the key is requested, for columns of related tables (e.g. Column "products.name").
+type GormSortProperty struct {
+	Column string // defaults to the key
+	Join   string
+	Nulls  GormSortNulls // where NULLs go, in either direction; database default when empty
+}
+
+type GormSortNulls string
+
+const (
+	GormSortNullsFirst GormSortNulls = "first"
+	GormSortNullsLast  GormSortNulls = "last"
+)
+
 type GormSelectField struct {
 	Column string
 	Alias  string
@@ -66,6 +359,42 @@ type GormQueryField struct {
 	Operation string
 	Column    string
 	Value     any
+	// Values holds the bounds for BETWEEN / NOT BETWEEN (low, high).
+	Values []any
+}
+
+// Operations understood by GormConditionBuilder besides plain binary operators ("=", ">", "LIKE", ...).
+const (
+	GormOperationIn         = "IN"
+	GormOperationNotIn      = "NOT IN"
+	GormOperationBetween    = "BETWEEN"
+	GormOperationNotBetween = "NOT BETWEEN"
+	GormOperationIsNull     = "IS NULL"
+	GormOperationIsNotNull  = "IS NOT NULL"
+)
+
+// BatchSize returns CreateBatchSize, or DefaultCreateBatchSize when unset.
+func (c *GormConfig) BatchSize() int {
+	if c.CreateBatchSize > 0 {
+		return c.CreateBatchSize
+	}
+	return DefaultCreateBatchSize
+}
+
+// IDListLimit returns MaxIDList, or DefaultMaxIDList when unset.
+func (c *GormConfig) IDListLimit() int {
+	if c.MaxIDList > 0 {
+		return c.MaxIDList
+	}
+	return DefaultMaxIDList
+}
+
+// ChunkSize returns IDChunkSize, or DefaultIDChunkSize when unset.
+func (c *GormConfig) ChunkSize() int {
+	if c.IDChunkSize > 0 {
+		return c.IDChunkSize
+	}
+	return DefaultIDChunkSize
 }
 
 // Implement RepositoryConfig interface
@@ -1,13 +1,21 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
 	"github.com/aghiadodeh/go-crud/services"
 )
 
@@ -15,29 +23,47 @@ type BaseCrudController[T any, C any, CreateDto any, UpdateDto any, FilterDto dt
 	Service services.IBaseCrudService[T, C]
 	Filter  func(ctx *fiber.Ctx) (FilterDto, error)
 	Mapper  CreateDtoMapper[CreateDto, UpdateDto, T]
+
+	// NotFoundMessageID is the i18n message ID of this controller's 404s, e.g.
+	// "user_not_found" (defaults to models.ErrNotFound's "item_not_found").
+	NotFoundMessageID string
 }
 
+// NewBaseCrudController creates a controller for service. A nil filter parses FindAll's query
+// with DefaultFilter.
 func NewBaseCrudController[T any, C any, CreateDto any, UpdateDto any, FilterDto dto.FilterDto](service services.IBaseCrudService[T, C], filter func(ctx *fiber.Ctx) (FilterDto, error)) *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto] {
+	if filter == nil {
+		filter = DefaultFilter[FilterDto]
+	}
 	return &BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]{Service: service, Filter: filter}
 }
 
+// ErrNoMapper is returned by the WithMapper constructors when no mapper is given.
+var ErrNoMapper = errors.New("controller needs a mapper for Create/Update")
+
+// NewBaseCrudControllerWithMapper is NewBaseCrudController with the Mapper set, failing at wiring
+// time instead of answering "No Mapper" on the first Create/Update.
+func NewBaseCrudControllerWithMapper[T any, C any, CreateDto any, UpdateDto any, FilterDto dto.FilterDto](service services.IBaseCrudService[T, C], filter func(ctx *fiber.Ctx) (FilterDto, error), mapper CreateDtoMapper[CreateDto, UpdateDto, T]) (*BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto], error) {
+	if mapper == nil {
+		return nil, ErrNoMapper
+	}
+	controller := NewBaseCrudController[T, C, CreateDto, UpdateDto](service, filter)
+	controller.Mapper = mapper
+	return controller, nil
+}
+
 func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Create(ctx *fiber.Ctx) error {
 	var createDto CreateDto
 
-	// 1. Try parsing JSON
+	// 1. Try parsing JSON; a malformed body is a 400
 	if err := ctx.BodyParser(&createDto); err != nil {
-		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	// 2. Validate parsed data
+	// 2. Validate parsed data; a well-formed body failing validation is a 422
 	var validate = validator.New()
 	if err := validate.Struct(createDto); err != nil {
-		// Collect error messages
-		var messages []string
-		for _, err := range err.(validator.ValidationErrors) {
-			messages = append(messages, fmt.Sprintf("%s must be %s %s", err.Field(), err.Tag(), err.Param()))
-		}
-		return fiber.NewError(fiber.StatusBadRequest, strings.Join(messages, ", "))
+		return validationError(ctx, err, fiber.StatusUnprocessableEntity)
 	}
 
 	// 3. Map Dto to Entity
@@ -52,7 +78,7 @@ func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Create(ctx *
 	// 4. Continue to business logic
 	item, err := c.Service.Create(ctx.UserContext(), entity, nil)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return serviceError(err)
 	}
 
 	return ctx.JSON(item)
@@ -62,20 +88,15 @@ func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Update(ctx *
 	id := ctx.Params("id")
 	var updateDto UpdateDto
 
-	// 1. Try parsing JSON
+	// 1. Try parsing JSON; a malformed body is a 400
 	if err := ctx.BodyParser(&updateDto); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	// 2. Validate parsed data
+	// 2. Validate parsed data; a well-formed body failing validation is a 422
 	var validate = validator.New()
 	if err := validate.Struct(updateDto); err != nil {
-		// Collect error messages
-		var messages []string
-		for _, err := range err.(validator.ValidationErrors) {
-			messages = append(messages, fmt.Sprintf("%s must be %s %s", err.Field(), err.Tag(), err.Param()))
-		}
-		return fiber.NewError(fiber.StatusBadRequest, strings.Join(messages, ", "))
+		return validationError(ctx, err, fiber.StatusUnprocessableEntity)
 	}
 
 	// 3. Map Dto to Entity
@@ -87,16 +108,46 @@ func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Update(ctx *
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	// 4. Continue to business logic
-	item, err := c.Service.Update(ctx.UserContext(), id, entity, nil)
+	// 4. Continue to business logic; an If-Match header makes the update conditional
+	var item *T
+	if version := ifMatchVersion(ctx); version != "" {
+		item, err = c.Service.UpdateVersioned(ctx.UserContext(), id, version, entity, nil)
+	} else {
+		item, err = c.Service.Update(ctx.UserContext(), id, entity, nil)
+	}
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return serviceError(err)
 	}
-
 	if item == nil {
-		return fiber.NewError(fiber.ErrNotFound.Code, "item_not_found")
+		return c.notFound()
+	}
+
+	return ctx.JSON(item)
+}
+
+// ifMatchVersion reads the entity version from the If-Match header ("3", "\"3\"" or W/"3").
+func ifMatchVersion(ctx *fiber.Ctx) string {
+	version := strings.TrimPrefix(strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch)), "W/")
+	return strings.Trim(version, `"`)
+}
+
+// PatchColumns updates the columns sent as a JSON object, zero values and null included,
+// within the repository's UpdatableColumns (other keys are a 400). It answers with the
+// updated entity or 404. Register it as `PATCH /:id`.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) PatchColumns(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	var columns map[string]any
+	if err := ctx.BodyParser(&columns); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	item, err := c.Service.PatchColumns(ctx.UserContext(), id, columns, nil)
+	if err != nil {
+		return serviceError(err)
+	}
+	if item == nil {
+		return c.notFound()
+	}
 	return ctx.JSON(item)
 }
 
@@ -105,52 +156,154 @@ func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) FindAll(ctx
 	if err != nil {
 		return fiber.NewError(fiber.ErrBadRequest.Code, err.Error())
 	}
+	if err := validateFilter(ctx, filter); err != nil {
+		return err
+	}
 
 	if c.Service == nil {
 		return fiber.NewError(fiber.ErrBadRequest.Code, "BaseService is null, check controller injection")
 	}
 
 	filterDto := filter.GetBase()
+	if filterDto.Params == nil {
+		// a custom Filter may skip BindQuery; StrictFilters still needs the request's params
+		filterDto.Params = slices.Sorted(maps.Keys(ctx.Queries()))
+	}
 	conditions, err := c.Service.QueryBuilder(ctx.UserContext(), filter, nil)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return serviceError(err)
 	}
 
 	if filterDto.Pagination == nil || *filterDto.Pagination {
 		response, err := c.Service.FindAllWithPaging(ctx.UserContext(), conditions, filter, nil)
 		if err != nil {
-			return err
+			return serviceError(err)
 		}
 		return ctx.JSON(response)
 	}
 
 	items, err := c.Service.FindAll(ctx.UserContext(), conditions, filter, nil)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return serviceError(err)
 	}
 	return ctx.JSON(items)
 }
 
+// Search is FindAll taking its filter from a JSON object in the body instead of the query
+// string, for filters that outgrow URL length limits (long id lists...). Keys and values
+// are those of the query string, arrays standing for repeated params:
+//
+//	{"status": "active", "id": [1, 2, 3], "page": 2}
+//
+// Register it as `POST /search`.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Search(ctx *fiber.Ctx) error {
+	var body map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(ctx.Body()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	// the body becomes the query, so FindAll binds and validates it as usual
+	args := ctx.Request().URI().QueryArgs()
+	for key, value := range body {
+		args.Del(key)
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, value := range values {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				args.Add(key, v)
+			case json.Number, bool:
+				args.Add(key, fmt.Sprint(v))
+			default:
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("search: %s must be a string, number, boolean or an array of them", key))
+			}
+		}
+	}
+	return c.FindAll(ctx)
+}
+
+// FindTrashed is FindAll over the soft-deleted entities only (a trash view to review and
+// restore from). Register it behind admin authorization, e.g. as `GET /trash`.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) FindTrashed(ctx *fiber.Ctx) error {
+	ctx.SetUserContext(repositories.ContextWithOnlyTrashed(ctx.UserContext()))
+	return c.FindAll(ctx)
+}
+
 func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) FindOne(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
-	item, err := c.Service.FindOneByPK(ctx.UserContext(), id, nil)
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	userCtx := ctx.UserContext()
+	if fields := ctx.Query("fields"); fields != "" {
+		columns := strings.Split(fields, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		userCtx = repositories.ContextWithFields(userCtx, columns) // ?fields=id,name
 	}
-	if item == nil {
-		return fiber.ErrNotFound
+	item, err := c.Service.FindOneByPKOrError(userCtx, id, nil)
+	if errors.Is(err, models.ErrNotFound) {
+		return c.notFound()
+	}
+	if err != nil {
+		return serviceError(err)
 	}
 	return ctx.JSON(item)
 }
 
+// Exists answers 204 when the entity exists and 404 when it doesn't, without a body.
+// Register it as `HEAD /:id` or `GET /:id/exists`.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Exists(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	exists, err := c.Service.ExistsByPK(ctx.UserContext(), id)
+	if err != nil {
+		return serviceError(err)
+	}
+
+	middlewares.SkipResponseTransform(ctx)
+	if !exists {
+		return ctx.SendStatus(fiber.StatusNotFound)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
 func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Delete(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
-	if err := c.Service.DeleteOneByPK(ctx.UserContext(), id); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	item, err := c.Service.DeleteOneByPKReturning(ctx.UserContext(), id, nil)
+	if err != nil {
+		return serviceError(err)
+	}
+	if item == nil {
+		return c.notFound()
 	}
 	return ctx.JSON(nil)
 }
 
+// Restore undoes a soft delete and answers with the restored entity, or 404.
+// Register it as `POST /:id/restore`.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) Restore(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	item, err := c.Service.Restore(ctx.UserContext(), id, nil)
+	if err != nil {
+		return serviceError(err)
+	}
+	if item == nil {
+		return c.notFound()
+	}
+	return ctx.JSON(item)
+}
+
+// notFound is the 404 of this controller, carrying NotFoundMessageID when set.
+func (c *BaseCrudController[T, C, CreateDto, UpdateDto, FilterDto]) notFound() error {
+	if c.NotFoundMessageID == "" {
+		return models.ErrNotFound
+	}
+	return models.NewNotFoundError(c.NotFoundMessageID)
+}
+
 type CreateDtoMapper[CreateDto any, UpdateDto any, T any] interface {
 	MapCreateDtoToEntity(createDto CreateDto) (T, error)
 	MapUpdateDtoToEntity(updateDto UpdateDto) (T, error)
@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/crudtest"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
+)
+
+type role struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type roleDto struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type roleFilter struct {
+	dto.BaseFilterDto
+	Status *string `query:"status"`
+}
+
+type roleMapper struct{}
+
+func (roleMapper) MapCreateDtoToEntity(createDto roleDto) (role, error) {
+	return role{Name: createDto.Name}, nil
+}
+
+func (roleMapper) MapUpdateDtoToEntity(updateDto roleDto) (role, error) {
+	return role{Name: updateDto.Name}, nil
+}
+
+type mockService = crudtest.MockService[role, configs.GormConfig]
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"crud error", models.NewConflictError("duplicate_name", nil), http.StatusConflict},
+		{"wrapped not found", fmt.Errorf("load: %w", models.ErrNotFound), http.StatusNotFound},
+		{"invalid include", fmt.Errorf("%w: secrets", dto.ErrInvalidInclude), http.StatusBadRequest},
+		{"unknown filter", fmt.Errorf("%w: colour", dto.ErrUnknownFilter), http.StatusBadRequest},
+		{"invalid filter", fmt.Errorf("%w: after_id", dto.ErrInvalidFilter), http.StatusBadRequest},
+		{"invalid column", fmt.Errorf("%w: password", repositories.ErrInvalidColumn), http.StatusBadRequest},
+		{"no version column", repositories.ErrNoVersionColumn, http.StatusBadRequest},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusRequestTimeout},
+		{"cancelled", context.Canceled, http.StatusRequestTimeout},
+		{"stale version", repositories.ErrStaleVersion, http.StatusPreconditionFailed},
+		{"unreferenced row", models.NewCrudError(http.StatusUnprocessableEntity, "reference_not_found", repositories.ErrReferenceNotFound), http.StatusUnprocessableEntity},
+		{"other", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serviceError(tt.err)
+			status := 0
+			var fiberErr *fiber.Error
+			var crudErr *models.CrudError
+			switch {
+			case errors.As(err, &fiberErr):
+				status = fiberErr.Code
+			case errors.As(err, &crudErr):
+				status = crudErr.Code
+			}
+			if status != tt.status {
+				t.Errorf("serviceError(%v) status = %d, want %d", tt.err, status, tt.status)
+			}
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"3", "3"},
+		{`"3"`, "3"},
+		{`W/"3"`, "3"},
+		{` "12" `, "12"},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Get("/", func(ctx *fiber.Ctx) error { return ctx.SendString(ifMatchVersion(ctx)) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(fiber.HeaderIfMatch, tt.header)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != tt.want {
+			t.Errorf("ifMatchVersion(%q) = %q, want %q", tt.header, body, tt.want)
+		}
+	}
+}
+
+// newTestApp routes every handler of a controller over service, answering errors with
+// ExceptionHandler.
+func newTestApp(service *mockService, filter func(ctx *fiber.Ctx) (*roleFilter, error)) *fiber.App {
+	controller, _ := NewGormBaseControllerWithMapper[role, roleDto, roleDto](service, filter, roleMapper{})
+	controller.NotFoundMessageID = "role_not_found"
+
+	app := fiber.New(fiber.Config{ErrorHandler: middlewares.ExceptionHandler})
+	app.Post("/search", controller.Search)
+	app.Get("/trash", controller.FindTrashed)
+	app.Post("/", controller.Create)
+	app.Get("/", controller.FindAll)
+	app.Head("/:id", controller.Exists) // before Get, which answers HEAD too
+	app.Get("/:id", controller.FindOne)
+	app.Put("/:id", controller.Update)
+	app.Patch("/:id", controller.PatchColumns)
+	app.Delete("/:id", controller.Delete)
+	app.Post("/:id/restore", controller.Restore)
+	return app
+}
+
+func TestRoutes(t *testing.T) {
+	admin := &role{ID: 1, Name: "admin"}
+	listed := func(m *mockService) {
+		m.QueryBuilderFunc = func(context.Context, dto.FilterDto, *configs.GormConfig, ...any) (*repositories.Condition, error) {
+			return repositories.Eq("status", "active"), nil
+		}
+		m.FindAllWithPagingFunc = func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) (*models.ListResponse[role], error) {
+			return &models.ListResponse[role]{Total: 1, Data: []role{*admin}}, nil
+		}
+		m.FindAllFunc = func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) ([]role, error) {
+			return []role{*admin}, nil
+		}
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		headers map[string]string
+		mock    func(m *mockService)
+		status  int
+		calls   []string // service methods called, in order
+		check   func(t *testing.T, m *mockService, body string)
+	}{
+		{
+			name: "create", method: http.MethodPost, path: "/", body: `{"name":"admin"}`,
+			mock: func(m *mockService) {
+				m.CreateFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"Create"},
+			check: func(t *testing.T, m *mockService, body string) {
+				if entity := m.CallsTo("Create")[0].Args[0]; entity != (role{Name: "admin"}) {
+					t.Errorf("created %#v, want the mapped entity", entity)
+				}
+			},
+		},
+		{name: "create with a malformed body", method: http.MethodPost, path: "/", body: `{"name":`, status: http.StatusBadRequest},
+		{name: "create failing validation", method: http.MethodPost, path: "/", body: `{}`, status: http.StatusUnprocessableEntity},
+		{
+			name: "create conflict", method: http.MethodPost, path: "/", body: `{"name":"admin"}`,
+			mock: func(m *mockService) {
+				m.CreateFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) {
+					return nil, models.NewConflictError("duplicate_name", nil)
+				}
+			},
+			status: http.StatusConflict, calls: []string{"Create"},
+		},
+		{
+			name: "update", method: http.MethodPut, path: "/1", body: `{"name":"owner"}`,
+			mock: func(m *mockService) {
+				m.UpdateFunc = func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"Update"},
+		},
+		{
+			name: "update of a missing entity", method: http.MethodPut, path: "/9", body: `{"name":"owner"}`,
+			mock: func(m *mockService) {
+				m.UpdateFunc = func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) { return nil, nil }
+			},
+			status: http.StatusNotFound, calls: []string{"Update"},
+			check: func(t *testing.T, m *mockService, body string) {
+				if !strings.Contains(body, "role_not_found") {
+					t.Errorf("body = %s, want the controller's NotFoundMessageID", body)
+				}
+			},
+		},
+		{
+			name: "update with If-Match", method: http.MethodPut, path: "/1", body: `{"name":"owner"}`,
+			headers: map[string]string{fiber.HeaderIfMatch: `W/"3"`},
+			mock: func(m *mockService) {
+				m.UpdateVersionedFunc = func(context.Context, any, any, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"UpdateVersioned"},
+			check: func(t *testing.T, m *mockService, body string) {
+				if args := m.CallsTo("UpdateVersioned")[0].Args; args[0] != "1" || args[1] != "3" {
+					t.Errorf("UpdateVersioned(%v, %v), want id 1 at version 3", args[0], args[1])
+				}
+			},
+		},
+		{
+			name: "update with a stale If-Match", method: http.MethodPut, path: "/1", body: `{"name":"owner"}`,
+			headers: map[string]string{fiber.HeaderIfMatch: "2"},
+			mock: func(m *mockService) {
+				m.UpdateVersionedFunc = func(context.Context, any, any, any, *configs.GormConfig, ...any) (*role, error) {
+					return nil, repositories.ErrStaleVersion
+				}
+			},
+			status: http.StatusPreconditionFailed, calls: []string{"UpdateVersioned"},
+		},
+		{
+			name: "patch", method: http.MethodPatch, path: "/1", body: `{"name":null}`,
+			mock: func(m *mockService) {
+				m.PatchColumnsFunc = func(context.Context, any, map[string]any, *configs.GormConfig, ...any) (*role, error) {
+					return admin, nil
+				}
+			},
+			status: http.StatusOK, calls: []string{"PatchColumns"},
+			check: func(t *testing.T, m *mockService, body string) {
+				if columns := m.CallsTo("PatchColumns")[0].Args[1]; !reflect.DeepEqual(columns, map[string]any{"name": nil}) {
+					t.Errorf("patched %v, want the null kept", columns)
+				}
+			},
+		},
+		{
+			name: "patch of a column outside UpdatableColumns", method: http.MethodPatch, path: "/1", body: `{"password":"x"}`,
+			mock: func(m *mockService) {
+				m.PatchColumnsFunc = func(context.Context, any, map[string]any, *configs.GormConfig, ...any) (*role, error) {
+					return nil, fmt.Errorf("%w: password", repositories.ErrInvalidColumn)
+				}
+			},
+			status: http.StatusBadRequest, calls: []string{"PatchColumns"},
+		},
+		{
+			name: "find all", method: http.MethodGet, path: "/?status=active&page=2", mock: listed,
+			status: http.StatusOK, calls: []string{"QueryBuilder", "FindAllWithPaging"},
+			check: func(t *testing.T, m *mockService, body string) {
+				filter := m.CallsTo("FindAllWithPaging")[0].Args[1].(*roleFilter)
+				if filter.Status == nil || *filter.Status != "active" || filter.Page != 2 {
+					t.Errorf("filter = %+v, want status active on page 2", filter)
+				}
+				if !reflect.DeepEqual(filter.Params, []string{"page", "status"}) {
+					t.Errorf("Params = %v, want [page status]", filter.Params)
+				}
+			},
+		},
+		{
+			name: "find all without pagination", method: http.MethodGet, path: "/?pagination=false", mock: listed,
+			status: http.StatusOK, calls: []string{"QueryBuilder", "FindAll"},
+		},
+		{name: "find all with an invalid sort_dir", method: http.MethodGet, path: "/?sort_dir=UP", mock: listed, status: http.StatusBadRequest},
+		{name: "find all with an invalid include", method: http.MethodGet, path: "/?include=author;drop", mock: listed, status: http.StatusBadRequest},
+		{
+			name: "find all with an unknown filter", method: http.MethodGet, path: "/?colour=red",
+			mock: func(m *mockService) {
+				m.QueryBuilderFunc = func(context.Context, dto.FilterDto, *configs.GormConfig, ...any) (*repositories.Condition, error) {
+					return nil, fmt.Errorf("%w: colour", dto.ErrUnknownFilter)
+				}
+			},
+			status: http.StatusBadRequest, calls: []string{"QueryBuilder"},
+		},
+		{
+			name: "find all timing out", method: http.MethodGet, path: "/",
+			mock: func(m *mockService) {
+				listed(m)
+				m.FindAllWithPagingFunc = func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) (*models.ListResponse[role], error) {
+					return nil, context.DeadlineExceeded
+				}
+			},
+			status: http.StatusRequestTimeout, calls: []string{"QueryBuilder", "FindAllWithPaging"},
+		},
+		{
+			name: "search", method: http.MethodPost, path: "/search?status=draft", body: `{"status":"active","id":[1,2],"page":2}`, mock: listed,
+			status: http.StatusOK, calls: []string{"QueryBuilder", "FindAllWithPaging"},
+			check: func(t *testing.T, m *mockService, body string) {
+				filter := m.CallsTo("FindAllWithPaging")[0].Args[1].(*roleFilter)
+				if *filter.Status != "active" || filter.Page != 2 || !reflect.DeepEqual(filter.Params, []string{"id", "page", "status"}) {
+					t.Errorf("filter = %+v, want the body's params", filter)
+				}
+			},
+		},
+		{name: "search with an object value", method: http.MethodPost, path: "/search", body: `{"status":{"in":["a"]}}`, mock: listed, status: http.StatusBadRequest},
+		{name: "find trashed", method: http.MethodGet, path: "/trash", mock: listed, status: http.StatusOK, calls: []string{"QueryBuilder", "FindAllWithPaging"}},
+		{
+			name: "find one", method: http.MethodGet, path: "/1?fields=id,name",
+			mock: func(m *mockService) {
+				m.FindOneByPKOrErrorFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"FindOneByPKOrError"},
+			check: func(t *testing.T, m *mockService, body string) {
+				if id := m.CallsTo("FindOneByPKOrError")[0].Args[0]; id != "1" || !strings.Contains(body, `"name":"admin"`) {
+					t.Errorf("FindOneByPKOrError(%v) answered %s, want role 1", id, body)
+				}
+			},
+		},
+		{
+			name: "find one missing", method: http.MethodGet, path: "/9",
+			mock: func(m *mockService) {
+				m.FindOneByPKOrErrorFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return nil, models.ErrNotFound }
+			},
+			status: http.StatusNotFound, calls: []string{"FindOneByPKOrError"},
+		},
+		{
+			name: "exists", method: http.MethodHead, path: "/1",
+			mock: func(m *mockService) {
+				m.ExistsByPKFunc = func(context.Context, any, ...any) (bool, error) { return true, nil }
+			},
+			status: http.StatusNoContent, calls: []string{"ExistsByPK"},
+		},
+		{
+			name: "exists missing", method: http.MethodHead, path: "/9",
+			mock: func(m *mockService) {
+				m.ExistsByPKFunc = func(context.Context, any, ...any) (bool, error) { return false, nil }
+			},
+			status: http.StatusNotFound, calls: []string{"ExistsByPK"},
+		},
+		{
+			name: "delete", method: http.MethodDelete, path: "/1",
+			mock: func(m *mockService) {
+				m.DeleteOneByPKReturningFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"DeleteOneByPKReturning"},
+		},
+		{
+			name: "delete missing", method: http.MethodDelete, path: "/9",
+			mock: func(m *mockService) {
+				m.DeleteOneByPKReturningFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return nil, nil }
+			},
+			status: http.StatusNotFound, calls: []string{"DeleteOneByPKReturning"},
+		},
+		{
+			name: "restore", method: http.MethodPost, path: "/1/restore",
+			mock: func(m *mockService) {
+				m.RestoreFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return admin, nil }
+			},
+			status: http.StatusOK, calls: []string{"Restore"},
+		},
+		{
+			name: "restore missing", method: http.MethodPost, path: "/9/restore",
+			mock: func(m *mockService) {
+				m.RestoreFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return nil, nil }
+			},
+			status: http.StatusNotFound, calls: []string{"Restore"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockService{}
+			if tt.mock != nil {
+				tt.mock(service)
+			}
+			app := newTestApp(service, nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d (%s)", resp.StatusCode, tt.status, body)
+			}
+
+			var calls []string
+			for _, call := range service.Calls() {
+				calls = append(calls, call.Method)
+			}
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("calls = %v, want %v", calls, tt.calls)
+			}
+			if tt.check != nil && len(calls) == len(tt.calls) {
+				tt.check(t, service, string(body))
+			}
+		})
+	}
+}
+
+func TestFindAllRecordsParamsForCustomFilters(t *testing.T) {
+	service := &mockService{
+		QueryBuilderFunc: func(_ context.Context, filter dto.FilterDto, _ *configs.GormConfig, _ ...any) (*repositories.Condition, error) {
+			if params := filter.GetBase().Params; !reflect.DeepEqual(params, []string{"colour", "page"}) {
+				t.Errorf("Params = %v, want [colour page]", params)
+			}
+			return nil, nil
+		},
+		FindAllWithPagingFunc: func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) (*models.ListResponse[role], error) {
+			return &models.ListResponse[role]{}, nil
+		},
+	}
+	// a filter that skips BindQuery
+	filter := func(ctx *fiber.Ctx) (*roleFilter, error) { return &roleFilter{}, nil }
+
+	resp, err := newTestApp(service, filter).Test(httptest.NewRequest(http.MethodGet, "/?page=2&colour=red", nil))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET / = %v, %v", resp.StatusCode, err)
+	}
+	if len(service.CallsTo("QueryBuilder")) != 1 {
+		t.Error("QueryBuilder was not called")
+	}
+}
+
+func TestNewBaseCrudControllerWithMapper(t *testing.T) {
+	if _, err := NewGormBaseControllerWithMapper[role, roleDto, roleDto, *roleFilter](&mockService{}, nil, nil); !errors.Is(err, ErrNoMapper) {
+		t.Errorf("err = %v, want ErrNoMapper", err)
+	}
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
+)
+
+// serviceError maps an error returned by the service to the response error: a
+// models.CrudError keeps its status (ExceptionHandler translates its MessageID), a rejected
+// include, unknown or invalid filter, invalid column or If-Match on an unversioned entity is
+// a 400, a cancelled or timed-out request context a 408, a stale If-Match version a 412 and
+// anything else a 500.
+func serviceError(err error) error {
+	var crudErr *models.CrudError
+	if errors.As(err, &crudErr) {
+		return crudErr
+	}
+	if errors.Is(err, dto.ErrInvalidInclude) || errors.Is(err, dto.ErrUnknownFilter) || errors.Is(err, dto.ErrInvalidFilter) || errors.Is(err, repositories.ErrInvalidColumn) ||
+		errors.Is(err, repositories.ErrNoVersionColumn) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return fiber.ErrRequestTimeout
+	}
+	if errors.Is(err, repositories.ErrStaleVersion) {
+		return fiber.NewError(fiber.StatusPreconditionFailed, "stale_version")
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
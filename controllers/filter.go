@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/aghiadodeh/go-crud/dto"
+)
+
+// DefaultFilter parses the request's query into a new FilterDto: its `query` fields with
+// QueryParser, the BaseFilterDto fields with BindQuery, then runs its `validate` tags.
+// FilterDto must be a pointer to a struct (e.g. *RoleFilterDto). The constructors use it
+// when given a nil filter; pass your own func when parsing needs more than that.
+func DefaultFilter[FilterDto dto.FilterDto](ctx *fiber.Ctx) (FilterDto, error) {
+	var filter FilterDto
+	t := reflect.TypeFor[FilterDto]()
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return filter, fmt.Errorf("DefaultFilter: %s is not a pointer to a struct", t)
+	}
+
+	filter = reflect.New(t.Elem()).Interface().(FilterDto)
+	if err := ctx.QueryParser(filter); err != nil {
+		return filter, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := filter.GetBase().BindQuery(ctx); err != nil {
+		return filter, err
+	}
+	if err := validateFilter(ctx, filter); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// validateFilter runs the `validate` tags of a bound filter (e.g. BaseFilterDto's sort_dir),
+// whatever func produced it, answering a localized 400 on failure.
+func validateFilter(ctx *fiber.Ctx, filter any) error {
+	err := validator.New().Struct(filter)
+	var invalid *validator.InvalidValidationError
+	if err == nil || errors.As(err, &invalid) { // not a struct: nothing to validate
+		return nil
+	}
+	return validationError(ctx, err, fiber.StatusBadRequest)
+}
@@ -17,3 +17,13 @@ func NewGormBaseController[T any, CreateDto any, UpdateDto any, FilterDto dto.Fi
 		BaseCrudController: *NewBaseCrudController[T, configs.GormConfig, CreateDto, UpdateDto](service, filter),
 	}
 }
+
+// NewGormBaseControllerWithMapper is NewGormBaseController with the Mapper set; it fails with
+// ErrNoMapper when mapper is nil.
+func NewGormBaseControllerWithMapper[T any, CreateDto any, UpdateDto any, FilterDto dto.FilterDto](service services.IBaseCrudService[T, configs.GormConfig], filter func(ctx *fiber.Ctx) (FilterDto, error), mapper CreateDtoMapper[CreateDto, UpdateDto, T]) (*GormCrudController[T, CreateDto, UpdateDto, FilterDto], error) {
+	base, err := NewBaseCrudControllerWithMapper[T, configs.GormConfig](service, filter, mapper)
+	if err != nil {
+		return nil, err
+	}
+	return &GormCrudController[T, CreateDto, UpdateDto, FilterDto]{BaseCrudController: *base}, nil
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// ValidationMessages maps a validator tag to the i18n message ID of its error message.
+// Tags without an entry use "validation.<tag>". Messages can use the Field, Tag, Param and
+// Value template fields, e.g. in your translation file:
+//
+//	"validation.required": "{{.Field}} is required"
+//	"validation.email": "{{.Field}} must be a valid email address"
+//
+// When no translation is found, the default "<Field> must be <tag> <param>" message is used.
+var ValidationMessages = map[string]string{}
+
+// validationError turns a validation failure into an error with the given status (400 for
+// query params, 422 for well-formed bodies) listing a message per field.
+func validationError(ctx *fiber.Ctx, err error, status int) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return fiber.NewError(status, err.Error())
+	}
+
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, validationMessage(ctx, fieldError))
+	}
+	return fiber.NewError(status, strings.Join(messages, ", "))
+}
+
+func validationMessage(ctx *fiber.Ctx, err validator.FieldError) string {
+	messageID, ok := ValidationMessages[err.Tag()]
+	if !ok {
+		messageID = "validation." + err.Tag()
+	}
+
+	message, ok := middlewares.Localize(ctx, messageID, map[string]interface{}{
+		"Field": err.Field(),
+		"Tag":   err.Tag(),
+		"Param": err.Param(),
+		"Value": err.Value(),
+	})
+	if !ok {
+		return fmt.Sprintf("%s must be %s %s", err.Field(), err.Tag(), err.Param())
+	}
+	return message
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/language"
+
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// withTranslations loads files (name -> JSON content) as the i18n bundle for the test.
+// The bundle is emptied afterwards, so later tests get the default messages again.
+func withTranslations(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	var assets []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, path)
+	}
+	if err := middlewares.InitLocalization(language.English, assets); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = middlewares.InitLocalization(language.English, nil) })
+}
+
+func TestValidationMessages(t *testing.T) {
+	withTranslations(t, map[string]string{
+		"en.json": `{"validation.required": "{{.Field}} is required", "validation.email": "{{.Field}} must be a valid email address"}`,
+		"fr.json": `{"validation.required": "{{.Field}} est obligatoire", "validation.email": "{{.Field}} doit être une adresse e-mail valide"}`,
+	})
+	type signup struct {
+		Name  string `validate:"required"`
+		Email string `validate:"omitempty,email"`
+		Age   int    `validate:"omitempty,min=18"`
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		input signup
+		want  string
+	}{
+		{"required", "/", signup{Email: "ada@example.com"}, "Name is required"},
+		{"email", "/", signup{Name: "Ada", Email: "ada"}, "Email must be a valid email address"},
+		{"required in another language", "/?lang=fr", signup{}, "Name est obligatoire"},
+		{"email in another language", "/?lang=fr", signup{Name: "Ada", Email: "ada"}, "Email doit être une adresse e-mail valide"},
+		{"untranslated tag", "/", signup{Name: "Ada", Age: 9}, "Age must be min 18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(middlewares.I18nMiddleware("en"))
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.SendString(validationError(c, validator.New().Struct(tt.input), fiber.StatusUnprocessableEntity).Error())
+			})
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.want {
+				t.Errorf("message = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterValidationMessages(t *testing.T) {
+	withTranslations(t, map[string]string{
+		"en.json": `{"validation.oneof": "{{.Field}} must be one of {{.Param}}"}`,
+		"fr.json": `{"validation.oneof": "{{.Field}} doit valoir {{.Param}}"}`,
+	})
+	// a filter func that doesn't validate what it builds: FindAll still does
+	unchecked := func(ctx *fiber.Ctx) (*roleFilter, error) {
+		sortDir := ctx.Query("sort_dir")
+		return &roleFilter{BaseFilterDto: dto.BaseFilterDto{SortDir: &sortDir}}, nil
+	}
+
+	tests := []struct {
+		name   string
+		filter func(ctx *fiber.Ctx) (*roleFilter, error)
+		path   string
+		want   string
+	}{
+		{"default filter", nil, "/?sort_dir=UP", "SortDir must be one of ASC DESC"},
+		{"default filter in another language", nil, "/?sort_dir=UP&lang=fr", "SortDir doit valoir ASC DESC"},
+		{"custom filter", unchecked, "/?sort_dir=UP", "SortDir must be one of ASC DESC"},
+		{"custom filter in another language", unchecked, "/?sort_dir=UP&lang=fr", "SortDir doit valoir ASC DESC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockService{}
+			app := fiber.New(fiber.Config{ErrorHandler: middlewares.ExceptionHandler})
+			app.Use(middlewares.I18nMiddleware("en"))
+			app.Mount("/", newTestApp(service, tt.filter))
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body struct{ Message string }
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusBadRequest || body.Message != tt.want {
+				t.Errorf("response = %d %q, want 400 %q", resp.StatusCode, body.Message, tt.want)
+			}
+			if calls := service.Calls(); len(calls) != 0 {
+				t.Errorf("calls = %v, want none", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package crudtest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/services"
+)
+
+type role struct {
+	ID   uint
+	Name string
+}
+
+func TestMockRepository(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		mock *MockRepository[role, configs.GormConfig]
+		run  func(m *MockRepository[role, configs.GormConfig]) error
+		err  error
+		call Call
+	}{
+		{
+			name: "programmed",
+			mock: &MockRepository[role, configs.GormConfig]{
+				FindOneByPKFunc: func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return &role{ID: 7}, nil },
+			},
+			run: func(m *MockRepository[role, configs.GormConfig]) error {
+				item, err := m.FindOneByPK(ctx, 7, nil, "tenant-1")
+				if item == nil || item.ID != 7 {
+					t.Errorf("FindOneByPK() = %v, want role 7", item)
+				}
+				return err
+			},
+			call: Call{Method: "FindOneByPK", Args: []any{7, (*configs.GormConfig)(nil), "tenant-1"}},
+		},
+		{
+			name: "not mocked",
+			mock: &MockRepository[role, configs.GormConfig]{},
+			run: func(m *MockRepository[role, configs.GormConfig]) error {
+				_, err := m.DeleteRows(ctx, map[string]any{"id": 1})
+				return err
+			},
+			err:  ErrNotMocked,
+			call: Call{Method: "DeleteRows", Args: []any{map[string]any{"id": 1}}},
+		},
+		{
+			name: "programmed error",
+			mock: &MockRepository[role, configs.GormConfig]{
+				UpdateByPKFunc: func(context.Context, any, any, ...any) error { return context.DeadlineExceeded },
+			},
+			run: func(m *MockRepository[role, configs.GormConfig]) error {
+				return m.UpdateByPK(ctx, 1, role{Name: "x"})
+			},
+			err:  context.DeadlineExceeded,
+			call: Call{Method: "UpdateByPK", Args: []any{1, role{Name: "x"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(tt.mock); !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if calls := tt.mock.Calls(); len(calls) != 1 || !reflect.DeepEqual(calls[0], tt.call) {
+				t.Errorf("calls = %#v, want %#v", calls, tt.call)
+			}
+		})
+	}
+}
+
+func TestMockRepositoryTransaction(t *testing.T) {
+	ran := false
+	m := &MockRepository[role, configs.GormConfig]{}
+	if err := m.Transaction(context.Background(), func(context.Context) error { ran = true; return nil }); err != nil || !ran {
+		t.Errorf("Transaction() = %v, ran %v; want fn run", err, ran)
+	}
+
+	rollback := errors.New("rollback")
+	m.TransactionFunc = func(context.Context, func(context.Context) error) error { return rollback }
+	if err := m.Transaction(context.Background(), func(context.Context) error { return nil }); err != rollback {
+		t.Errorf("Transaction() = %v, want the programmed error", err)
+	}
+	if len(m.CallsTo("Transaction")) != 2 {
+		t.Errorf("recorded %d transactions, want 2", len(m.CallsTo("Transaction")))
+	}
+}
+
+func TestMockService(t *testing.T) {
+	m := &MockService[role, configs.GormConfig]{
+		CreateFunc: func(_ context.Context, createDto any, _ *configs.GormConfig, _ ...any) (*role, error) {
+			created := createDto.(role)
+			created.ID = 1
+			return &created, nil
+		},
+	}
+	var service services.IBaseCrudService[role, configs.GormConfig] = m
+
+	err := service.WithTx(context.Background(), func(ctx context.Context, tx services.IBaseCrudService[role, configs.GormConfig]) error {
+		if _, err := tx.Create(ctx, role{Name: "admin"}, nil); err != nil {
+			return err
+		}
+		_, err := tx.Restore(ctx, 1, nil)
+		return err
+	})
+	if !errors.Is(err, ErrNotMocked) {
+		t.Errorf("WithTx() = %v, want ErrNotMocked from Restore", err)
+	}
+
+	var methods []string
+	for _, call := range m.Calls() {
+		methods = append(methods, call.Method)
+	}
+	if want := []string{"WithTx", "Create", "Restore"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("calls = %v, want %v", methods, want)
+	}
+	if args := m.CallsTo("Create")[0].Args; args[0] != (role{Name: "admin"}) {
+		t.Errorf("Create args = %v, want the dto first", args)
+	}
+}
+
+func TestRecorderIsSafeForConcurrentUse(t *testing.T) {
+	m := &MockRepository[role, configs.GormConfig]{
+		CountFunc: func(context.Context, any, ...any) (int64, error) { return 1, nil },
+	}
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = m.Count(context.Background(), nil)
+		}()
+	}
+	wg.Wait()
+	if calls := len(m.CallsTo("Count")); calls != 50 {
+		t.Errorf("recorded %d calls, want 50", calls)
+	}
+}
@@ -0,0 +1,370 @@
+package crudtest
+
+import (
+	"context"
+
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
+)
+
+// MockRepository is a repositories.BaseRepository programmed through its Func fields, for
+// testing services without a database:
+//
+//	repository := &crudtest.MockRepository[Role, configs.GormConfig]{
+//		FindOneByPKFunc: func(ctx context.Context, id any, config *configs.GormConfig, args ...any) (*Role, error) {
+//			return &Role{ID: 7, Name: "admin"}, nil
+//		},
+//	}
+//	service := services.NewGormCrudService[Role](repository)
+//
+// Methods without a Func return ErrNotMocked (zero values for the other results), except
+// Transaction which runs fn with ctx. Every call is recorded.
+type MockRepository[T any, C any] struct {
+	Recorder
+
+	CreateFunc                 func(ctx context.Context, createDto any, args ...any) (any, error)
+	BulkCreateFunc             func(ctx context.Context, createDto []any, args ...any) ([]string, error)
+	BulkCreateReturningFunc    func(ctx context.Context, createDto []any, args ...any) ([]T, error)
+	BulkCreatePartialFunc      func(ctx context.Context, createDto []any, args ...any) ([]T, []repositories.BulkError, error)
+	BulkUpdateFunc             func(ctx context.Context, items []T, columns []string, args ...any) error
+	UpdateByPKFunc             func(ctx context.Context, id any, updateDto any, args ...any) error
+	UpdateByPKVersionedFunc    func(ctx context.Context, id any, version any, updateDto any, args ...any) error
+	UpdateFunc                 func(ctx context.Context, conditions any, updateDto any, args ...any) error
+	UpdateByPKReturningFunc    func(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error)
+	UpdateColumnsByPKFunc      func(ctx context.Context, id any, columns map[string]any, args ...any) error
+	PatchColumnsByPKFunc       func(ctx context.Context, id any, columns map[string]any, args ...any) error
+	FindAllFunc                func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error)
+	FindAllWithPagingFunc      func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error)
+	FindAllWithCursorFunc      func(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error)
+	FindOneFunc                func(ctx context.Context, conditions any, config *C, args ...any) (*T, error)
+	FindOneByPKFunc            func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	FindOneColumnsFunc         func(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error)
+	FindByIDsFunc              func(ctx context.Context, ids []any, config *C, args ...any) ([]T, error)
+	FindAllIDsFunc             func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error)
+	DeleteFunc                 func(ctx context.Context, conditions any, args ...any) error
+	DeleteRowsFunc             func(ctx context.Context, conditions any, args ...any) (int64, error)
+	DeleteOneByPKFunc          func(ctx context.Context, id any, args ...any) error
+	DeleteOneByPKReturningFunc func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	DeleteByIDsFunc            func(ctx context.Context, ids []any, args ...any) error
+	RestoreFunc                func(ctx context.Context, id any, args ...any) error
+	DeleteAllFunc              func(ctx context.Context) error
+	TruncateFunc               func(ctx context.Context) error
+	PreviewDeleteFunc          func(ctx context.Context, conditions any, args ...any) (*models.DryRun, error)
+	PreviewUpdateFunc          func(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error)
+	CountFunc                  func(ctx context.Context, conditions any, args ...any) (int64, error)
+	CountDistinctFunc          func(ctx context.Context, column string, conditions any, args ...any) (int64, error)
+	ExistsFunc                 func(ctx context.Context, conditions any, args ...any) (bool, error)
+	ExistsByPKFunc             func(ctx context.Context, id any, args ...any) (bool, error)
+	PluckFunc                  func(ctx context.Context, column string, conditions any, args ...any) ([]any, error)
+	GroupByScanFunc            func(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error)
+	FacetsFunc                 func(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error)
+	QueryBuilderFunc           func(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error)
+	TransactionFunc            func(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ repositories.BaseRepository[struct{}, struct{}] = (*MockRepository[struct{}, struct{}])(nil)
+
+func (m *MockRepository[T, C]) Create(ctx context.Context, createDto any, args ...any) (any, error) {
+	m.record("Create", append([]any{createDto}, args...)...)
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, createDto, args...)
+	}
+	return nil, notMocked("Create")
+}
+
+func (m *MockRepository[T, C]) BulkCreate(ctx context.Context, createDto []any, args ...any) ([]string, error) {
+	m.record("BulkCreate", append([]any{createDto}, args...)...)
+	if m.BulkCreateFunc != nil {
+		return m.BulkCreateFunc(ctx, createDto, args...)
+	}
+	return nil, notMocked("BulkCreate")
+}
+
+func (m *MockRepository[T, C]) BulkCreateReturning(ctx context.Context, createDto []any, args ...any) ([]T, error) {
+	m.record("BulkCreateReturning", append([]any{createDto}, args...)...)
+	if m.BulkCreateReturningFunc != nil {
+		return m.BulkCreateReturningFunc(ctx, createDto, args...)
+	}
+	return nil, notMocked("BulkCreateReturning")
+}
+
+func (m *MockRepository[T, C]) BulkCreatePartial(ctx context.Context, createDto []any, args ...any) ([]T, []repositories.BulkError, error) {
+	m.record("BulkCreatePartial", append([]any{createDto}, args...)...)
+	if m.BulkCreatePartialFunc != nil {
+		return m.BulkCreatePartialFunc(ctx, createDto, args...)
+	}
+	return nil, nil, notMocked("BulkCreatePartial")
+}
+
+func (m *MockRepository[T, C]) BulkUpdate(ctx context.Context, items []T, columns []string, args ...any) error {
+	m.record("BulkUpdate", append([]any{items, columns}, args...)...)
+	if m.BulkUpdateFunc != nil {
+		return m.BulkUpdateFunc(ctx, items, columns, args...)
+	}
+	return notMocked("BulkUpdate")
+}
+
+func (m *MockRepository[T, C]) UpdateByPK(ctx context.Context, id any, updateDto any, args ...any) error {
+	m.record("UpdateByPK", append([]any{id, updateDto}, args...)...)
+	if m.UpdateByPKFunc != nil {
+		return m.UpdateByPKFunc(ctx, id, updateDto, args...)
+	}
+	return notMocked("UpdateByPK")
+}
+
+func (m *MockRepository[T, C]) UpdateByPKVersioned(ctx context.Context, id any, version any, updateDto any, args ...any) error {
+	m.record("UpdateByPKVersioned", append([]any{id, version, updateDto}, args...)...)
+	if m.UpdateByPKVersionedFunc != nil {
+		return m.UpdateByPKVersionedFunc(ctx, id, version, updateDto, args...)
+	}
+	return notMocked("UpdateByPKVersioned")
+}
+
+func (m *MockRepository[T, C]) Update(ctx context.Context, conditions any, updateDto any, args ...any) error {
+	m.record("Update", append([]any{conditions, updateDto}, args...)...)
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, conditions, updateDto, args...)
+	}
+	return notMocked("Update")
+}
+
+func (m *MockRepository[T, C]) UpdateByPKReturning(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error) {
+	m.record("UpdateByPKReturning", append([]any{id, updateDto, config}, args...)...)
+	if m.UpdateByPKReturningFunc != nil {
+		return m.UpdateByPKReturningFunc(ctx, id, updateDto, config, args...)
+	}
+	return nil, notMocked("UpdateByPKReturning")
+}
+
+func (m *MockRepository[T, C]) UpdateColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
+	m.record("UpdateColumnsByPK", append([]any{id, columns}, args...)...)
+	if m.UpdateColumnsByPKFunc != nil {
+		return m.UpdateColumnsByPKFunc(ctx, id, columns, args...)
+	}
+	return notMocked("UpdateColumnsByPK")
+}
+
+func (m *MockRepository[T, C]) PatchColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
+	m.record("PatchColumnsByPK", append([]any{id, columns}, args...)...)
+	if m.PatchColumnsByPKFunc != nil {
+		return m.PatchColumnsByPKFunc(ctx, id, columns, args...)
+	}
+	return notMocked("PatchColumnsByPK")
+}
+
+func (m *MockRepository[T, C]) FindAll(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error) {
+	m.record("FindAll", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllFunc != nil {
+		return m.FindAllFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAll")
+}
+
+func (m *MockRepository[T, C]) FindAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error) {
+	m.record("FindAllWithPaging", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllWithPagingFunc != nil {
+		return m.FindAllWithPagingFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAllWithPaging")
+}
+
+func (m *MockRepository[T, C]) FindAllWithCursor(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error) {
+	m.record("FindAllWithCursor", append([]any{conditions, cursor, limit, config}, args...)...)
+	if m.FindAllWithCursorFunc != nil {
+		return m.FindAllWithCursorFunc(ctx, conditions, cursor, limit, config, args...)
+	}
+	return nil, notMocked("FindAllWithCursor")
+}
+
+func (m *MockRepository[T, C]) FindOne(ctx context.Context, conditions any, config *C, args ...any) (*T, error) {
+	m.record("FindOne", append([]any{conditions, config}, args...)...)
+	if m.FindOneFunc != nil {
+		return m.FindOneFunc(ctx, conditions, config, args...)
+	}
+	return nil, notMocked("FindOne")
+}
+
+func (m *MockRepository[T, C]) FindOneByPK(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("FindOneByPK", append([]any{id, config}, args...)...)
+	if m.FindOneByPKFunc != nil {
+		return m.FindOneByPKFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("FindOneByPK")
+}
+
+func (m *MockRepository[T, C]) FindOneColumns(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error) {
+	m.record("FindOneColumns", append([]any{conditions, columns, config}, args...)...)
+	if m.FindOneColumnsFunc != nil {
+		return m.FindOneColumnsFunc(ctx, conditions, columns, config, args...)
+	}
+	return nil, notMocked("FindOneColumns")
+}
+
+func (m *MockRepository[T, C]) FindByIDs(ctx context.Context, ids []any, config *C, args ...any) ([]T, error) {
+	m.record("FindByIDs", append([]any{ids, config}, args...)...)
+	if m.FindByIDsFunc != nil {
+		return m.FindByIDsFunc(ctx, ids, config, args...)
+	}
+	return nil, notMocked("FindByIDs")
+}
+
+func (m *MockRepository[T, C]) FindAllIDs(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error) {
+	m.record("FindAllIDs", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllIDsFunc != nil {
+		return m.FindAllIDsFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAllIDs")
+}
+
+func (m *MockRepository[T, C]) Delete(ctx context.Context, conditions any, args ...any) error {
+	m.record("Delete", append([]any{conditions}, args...)...)
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, conditions, args...)
+	}
+	return notMocked("Delete")
+}
+
+func (m *MockRepository[T, C]) DeleteRows(ctx context.Context, conditions any, args ...any) (int64, error) {
+	m.record("DeleteRows", append([]any{conditions}, args...)...)
+	if m.DeleteRowsFunc != nil {
+		return m.DeleteRowsFunc(ctx, conditions, args...)
+	}
+	return 0, notMocked("DeleteRows")
+}
+
+func (m *MockRepository[T, C]) DeleteOneByPK(ctx context.Context, id any, args ...any) error {
+	m.record("DeleteOneByPK", append([]any{id}, args...)...)
+	if m.DeleteOneByPKFunc != nil {
+		return m.DeleteOneByPKFunc(ctx, id, args...)
+	}
+	return notMocked("DeleteOneByPK")
+}
+
+func (m *MockRepository[T, C]) DeleteOneByPKReturning(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("DeleteOneByPKReturning", append([]any{id, config}, args...)...)
+	if m.DeleteOneByPKReturningFunc != nil {
+		return m.DeleteOneByPKReturningFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("DeleteOneByPKReturning")
+}
+
+func (m *MockRepository[T, C]) DeleteByIDs(ctx context.Context, ids []any, args ...any) error {
+	m.record("DeleteByIDs", append([]any{ids}, args...)...)
+	if m.DeleteByIDsFunc != nil {
+		return m.DeleteByIDsFunc(ctx, ids, args...)
+	}
+	return notMocked("DeleteByIDs")
+}
+
+func (m *MockRepository[T, C]) Restore(ctx context.Context, id any, args ...any) error {
+	m.record("Restore", append([]any{id}, args...)...)
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(ctx, id, args...)
+	}
+	return notMocked("Restore")
+}
+
+func (m *MockRepository[T, C]) DeleteAll(ctx context.Context) error {
+	m.record("DeleteAll")
+	if m.DeleteAllFunc != nil {
+		return m.DeleteAllFunc(ctx)
+	}
+	return notMocked("DeleteAll")
+}
+
+func (m *MockRepository[T, C]) Truncate(ctx context.Context) error {
+	m.record("Truncate")
+	if m.TruncateFunc != nil {
+		return m.TruncateFunc(ctx)
+	}
+	return notMocked("Truncate")
+}
+
+func (m *MockRepository[T, C]) PreviewDelete(ctx context.Context, conditions any, args ...any) (*models.DryRun, error) {
+	m.record("PreviewDelete", append([]any{conditions}, args...)...)
+	if m.PreviewDeleteFunc != nil {
+		return m.PreviewDeleteFunc(ctx, conditions, args...)
+	}
+	return nil, notMocked("PreviewDelete")
+}
+
+func (m *MockRepository[T, C]) PreviewUpdate(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error) {
+	m.record("PreviewUpdate", append([]any{conditions, updateDto}, args...)...)
+	if m.PreviewUpdateFunc != nil {
+		return m.PreviewUpdateFunc(ctx, conditions, updateDto, args...)
+	}
+	return nil, notMocked("PreviewUpdate")
+}
+
+func (m *MockRepository[T, C]) Count(ctx context.Context, conditions any, args ...any) (int64, error) {
+	m.record("Count", append([]any{conditions}, args...)...)
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx, conditions, args...)
+	}
+	return 0, notMocked("Count")
+}
+
+func (m *MockRepository[T, C]) CountDistinct(ctx context.Context, column string, conditions any, args ...any) (int64, error) {
+	m.record("CountDistinct", append([]any{column, conditions}, args...)...)
+	if m.CountDistinctFunc != nil {
+		return m.CountDistinctFunc(ctx, column, conditions, args...)
+	}
+	return 0, notMocked("CountDistinct")
+}
+
+func (m *MockRepository[T, C]) Exists(ctx context.Context, conditions any, args ...any) (bool, error) {
+	m.record("Exists", append([]any{conditions}, args...)...)
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(ctx, conditions, args...)
+	}
+	return false, notMocked("Exists")
+}
+
+func (m *MockRepository[T, C]) ExistsByPK(ctx context.Context, id any, args ...any) (bool, error) {
+	m.record("ExistsByPK", append([]any{id}, args...)...)
+	if m.ExistsByPKFunc != nil {
+		return m.ExistsByPKFunc(ctx, id, args...)
+	}
+	return false, notMocked("ExistsByPK")
+}
+
+func (m *MockRepository[T, C]) Pluck(ctx context.Context, column string, conditions any, args ...any) ([]any, error) {
+	m.record("Pluck", append([]any{column, conditions}, args...)...)
+	if m.PluckFunc != nil {
+		return m.PluckFunc(ctx, column, conditions, args...)
+	}
+	return nil, notMocked("Pluck")
+}
+
+func (m *MockRepository[T, C]) GroupByScan(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error) {
+	m.record("GroupByScan", append([]any{selects, groupBy, conditions}, args...)...)
+	if m.GroupByScanFunc != nil {
+		return m.GroupByScanFunc(ctx, selects, groupBy, conditions, args...)
+	}
+	return nil, notMocked("GroupByScan")
+}
+
+func (m *MockRepository[T, C]) Facets(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error) {
+	m.record("Facets", append([]any{conditions, facetColumns}, args...)...)
+	if m.FacetsFunc != nil {
+		return m.FacetsFunc(ctx, conditions, facetColumns, args...)
+	}
+	return nil, notMocked("Facets")
+}
+
+func (m *MockRepository[T, C]) QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error) {
+	m.record("QueryBuilder", append([]any{filter, config}, args...)...)
+	if m.QueryBuilderFunc != nil {
+		return m.QueryBuilderFunc(ctx, filter, config, args...)
+	}
+	return nil, notMocked("QueryBuilder")
+}
+
+func (m *MockRepository[T, C]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.record("Transaction", fn)
+	if m.TransactionFunc != nil {
+		return m.TransactionFunc(ctx, fn)
+	}
+	return fn(ctx)
+}
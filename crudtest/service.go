@@ -0,0 +1,345 @@
+package crudtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
+	"github.com/aghiadodeh/go-crud/services"
+)
+
+// ErrNotMocked is returned (wrapped) by the methods of a mock that were not programmed.
+var ErrNotMocked = errors.New("crudtest: method not mocked")
+
+// Call is a method call recorded by a mock.
+type Call struct {
+	Method string
+	Args   []any // arguments after the context, variadic args flattened last
+}
+
+// Recorder keeps the calls made to a mock. It is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns every recorded call, in order.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns the recorded calls to method.
+func (r *Recorder) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range r.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func notMocked(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotMocked, method)
+}
+
+// MockService is a services.IBaseCrudService programmed through its Func fields, for
+// testing controllers without a database:
+//
+//	service := &crudtest.MockService[Role, configs.GormConfig]{
+//		FindOneByPKOrErrorFunc: func(ctx context.Context, id any, config *configs.GormConfig, args ...any) (*Role, error) {
+//			return nil, models.ErrNotFound
+//		},
+//	}
+//	controller := controllers.NewGormBaseController[Role, RoleCreateDto, RoleUpdateDto](service, parseFilter)
+//
+// Methods without a Func return ErrNotMocked, except WithTx which runs fn with the mock.
+// Every call is recorded.
+type MockService[T any, C any] struct {
+	Recorder
+
+	CreateFunc                 func(ctx context.Context, createDto any, config *C, args ...any) (*T, error)
+	UpdateFunc                 func(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error)
+	UpdateVersionedFunc        func(ctx context.Context, id any, version any, updateDto any, config *C, args ...any) (*T, error)
+	PatchColumnsFunc           func(ctx context.Context, id any, columns map[string]any, config *C, args ...any) (*T, error)
+	UpdateColumnsByPKFunc      func(ctx context.Context, id any, columns map[string]any, args ...any) error
+	FindAllFunc                func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error)
+	FindAllWithPagingFunc      func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error)
+	FindAllWithCursorFunc      func(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error)
+	FindOneFunc                func(ctx context.Context, conditions any, config *C, args ...any) (*T, error)
+	FindOneByPKFunc            func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	FindOneByPKOrErrorFunc     func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	FindOneColumnsFunc         func(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error)
+	FindByIDsFunc              func(ctx context.Context, ids []any, config *C, args ...any) ([]T, error)
+	FindAllIDsFunc             func(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error)
+	DeleteFunc                 func(ctx context.Context, conditions any, args ...any) error
+	DeleteOneByPKFunc          func(ctx context.Context, id any, args ...any) error
+	DeleteOneByPKReturningFunc func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	DeleteByIDsFunc            func(ctx context.Context, ids []any, args ...any) error
+	RestoreFunc                func(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	PreviewDeleteFunc          func(ctx context.Context, conditions any, args ...any) (*models.DryRun, error)
+	PreviewUpdateFunc          func(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error)
+	CountFunc                  func(ctx context.Context, conditions any, args ...any) (int64, error)
+	CountDistinctFunc          func(ctx context.Context, column string, conditions any, args ...any) (int64, error)
+	ExistsFunc                 func(ctx context.Context, conditions any, args ...any) (bool, error)
+	ExistsByPKFunc             func(ctx context.Context, id any, args ...any) (bool, error)
+	PluckFunc                  func(ctx context.Context, column string, conditions any, args ...any) ([]any, error)
+	GroupByScanFunc            func(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error)
+	FacetsFunc                 func(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error)
+	QueryBuilderFunc           func(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error)
+	WithTxFunc                 func(ctx context.Context, fn func(ctx context.Context, tx services.IBaseCrudService[T, C]) error) error
+}
+
+var _ services.IBaseCrudService[struct{}, struct{}] = (*MockService[struct{}, struct{}])(nil)
+
+func (m *MockService[T, C]) Create(ctx context.Context, createDto any, config *C, args ...any) (*T, error) {
+	m.record("Create", append([]any{createDto, config}, args...)...)
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, createDto, config, args...)
+	}
+	return nil, notMocked("Create")
+}
+
+func (m *MockService[T, C]) Update(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error) {
+	m.record("Update", append([]any{id, updateDto, config}, args...)...)
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, id, updateDto, config, args...)
+	}
+	return nil, notMocked("Update")
+}
+
+func (m *MockService[T, C]) UpdateVersioned(ctx context.Context, id any, version any, updateDto any, config *C, args ...any) (*T, error) {
+	m.record("UpdateVersioned", append([]any{id, version, updateDto, config}, args...)...)
+	if m.UpdateVersionedFunc != nil {
+		return m.UpdateVersionedFunc(ctx, id, version, updateDto, config, args...)
+	}
+	return nil, notMocked("UpdateVersioned")
+}
+
+func (m *MockService[T, C]) UpdateColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
+	m.record("UpdateColumnsByPK", append([]any{id, columns}, args...)...)
+	if m.UpdateColumnsByPKFunc != nil {
+		return m.UpdateColumnsByPKFunc(ctx, id, columns, args...)
+	}
+	return notMocked("UpdateColumnsByPK")
+}
+
+func (m *MockService[T, C]) PatchColumns(ctx context.Context, id any, columns map[string]any, config *C, args ...any) (*T, error) {
+	m.record("PatchColumns", append([]any{id, columns, config}, args...)...)
+	if m.PatchColumnsFunc != nil {
+		return m.PatchColumnsFunc(ctx, id, columns, config, args...)
+	}
+	return nil, notMocked("PatchColumns")
+}
+
+func (m *MockService[T, C]) FindAll(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error) {
+	m.record("FindAll", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllFunc != nil {
+		return m.FindAllFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAll")
+}
+
+func (m *MockService[T, C]) FindAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error) {
+	m.record("FindAllWithPaging", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllWithPagingFunc != nil {
+		return m.FindAllWithPagingFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAllWithPaging")
+}
+
+func (m *MockService[T, C]) FindAllWithCursor(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error) {
+	m.record("FindAllWithCursor", append([]any{conditions, cursor, limit, config}, args...)...)
+	if m.FindAllWithCursorFunc != nil {
+		return m.FindAllWithCursorFunc(ctx, conditions, cursor, limit, config, args...)
+	}
+	return nil, notMocked("FindAllWithCursor")
+}
+
+func (m *MockService[T, C]) FindOne(ctx context.Context, conditions any, config *C, args ...any) (*T, error) {
+	m.record("FindOne", append([]any{conditions, config}, args...)...)
+	if m.FindOneFunc != nil {
+		return m.FindOneFunc(ctx, conditions, config, args...)
+	}
+	return nil, notMocked("FindOne")
+}
+
+func (m *MockService[T, C]) FindOneByPK(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("FindOneByPK", append([]any{id, config}, args...)...)
+	if m.FindOneByPKFunc != nil {
+		return m.FindOneByPKFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("FindOneByPK")
+}
+
+func (m *MockService[T, C]) FindOneByPKOrError(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("FindOneByPKOrError", append([]any{id, config}, args...)...)
+	if m.FindOneByPKOrErrorFunc != nil {
+		return m.FindOneByPKOrErrorFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("FindOneByPKOrError")
+}
+
+func (m *MockService[T, C]) FindOneColumns(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error) {
+	m.record("FindOneColumns", append([]any{conditions, columns, config}, args...)...)
+	if m.FindOneColumnsFunc != nil {
+		return m.FindOneColumnsFunc(ctx, conditions, columns, config, args...)
+	}
+	return nil, notMocked("FindOneColumns")
+}
+
+func (m *MockService[T, C]) FindByIDs(ctx context.Context, ids []any, config *C, args ...any) ([]T, error) {
+	m.record("FindByIDs", append([]any{ids, config}, args...)...)
+	if m.FindByIDsFunc != nil {
+		return m.FindByIDsFunc(ctx, ids, config, args...)
+	}
+	return nil, notMocked("FindByIDs")
+}
+
+func (m *MockService[T, C]) FindAllIDs(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error) {
+	m.record("FindAllIDs", append([]any{conditions, filter, config}, args...)...)
+	if m.FindAllIDsFunc != nil {
+		return m.FindAllIDsFunc(ctx, conditions, filter, config, args...)
+	}
+	return nil, notMocked("FindAllIDs")
+}
+
+func (m *MockService[T, C]) Delete(ctx context.Context, conditions any, args ...any) error {
+	m.record("Delete", append([]any{conditions}, args...)...)
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, conditions, args...)
+	}
+	return notMocked("Delete")
+}
+
+func (m *MockService[T, C]) DeleteOneByPK(ctx context.Context, id any, args ...any) error {
+	m.record("DeleteOneByPK", append([]any{id}, args...)...)
+	if m.DeleteOneByPKFunc != nil {
+		return m.DeleteOneByPKFunc(ctx, id, args...)
+	}
+	return notMocked("DeleteOneByPK")
+}
+
+func (m *MockService[T, C]) DeleteOneByPKReturning(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("DeleteOneByPKReturning", append([]any{id, config}, args...)...)
+	if m.DeleteOneByPKReturningFunc != nil {
+		return m.DeleteOneByPKReturningFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("DeleteOneByPKReturning")
+}
+
+func (m *MockService[T, C]) DeleteByIDs(ctx context.Context, ids []any, args ...any) error {
+	m.record("DeleteByIDs", append([]any{ids}, args...)...)
+	if m.DeleteByIDsFunc != nil {
+		return m.DeleteByIDsFunc(ctx, ids, args...)
+	}
+	return notMocked("DeleteByIDs")
+}
+
+func (m *MockService[T, C]) Restore(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	m.record("Restore", append([]any{id, config}, args...)...)
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(ctx, id, config, args...)
+	}
+	return nil, notMocked("Restore")
+}
+
+func (m *MockService[T, C]) PreviewDelete(ctx context.Context, conditions any, args ...any) (*models.DryRun, error) {
+	m.record("PreviewDelete", append([]any{conditions}, args...)...)
+	if m.PreviewDeleteFunc != nil {
+		return m.PreviewDeleteFunc(ctx, conditions, args...)
+	}
+	return nil, notMocked("PreviewDelete")
+}
+
+func (m *MockService[T, C]) PreviewUpdate(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error) {
+	m.record("PreviewUpdate", append([]any{conditions, updateDto}, args...)...)
+	if m.PreviewUpdateFunc != nil {
+		return m.PreviewUpdateFunc(ctx, conditions, updateDto, args...)
+	}
+	return nil, notMocked("PreviewUpdate")
+}
+
+func (m *MockService[T, C]) Count(ctx context.Context, conditions any, args ...any) (int64, error) {
+	m.record("Count", append([]any{conditions}, args...)...)
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx, conditions, args...)
+	}
+	return 0, notMocked("Count")
+}
+
+func (m *MockService[T, C]) CountDistinct(ctx context.Context, column string, conditions any, args ...any) (int64, error) {
+	m.record("CountDistinct", append([]any{column, conditions}, args...)...)
+	if m.CountDistinctFunc != nil {
+		return m.CountDistinctFunc(ctx, column, conditions, args...)
+	}
+	return 0, notMocked("CountDistinct")
+}
+
+func (m *MockService[T, C]) Exists(ctx context.Context, conditions any, args ...any) (bool, error) {
+	m.record("Exists", append([]any{conditions}, args...)...)
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(ctx, conditions, args...)
+	}
+	return false, notMocked("Exists")
+}
+
+func (m *MockService[T, C]) ExistsByPK(ctx context.Context, id any, args ...any) (bool, error) {
+	m.record("ExistsByPK", append([]any{id}, args...)...)
+	if m.ExistsByPKFunc != nil {
+		return m.ExistsByPKFunc(ctx, id, args...)
+	}
+	return false, notMocked("ExistsByPK")
+}
+
+func (m *MockService[T, C]) Pluck(ctx context.Context, column string, conditions any, args ...any) ([]any, error) {
+	m.record("Pluck", append([]any{column, conditions}, args...)...)
+	if m.PluckFunc != nil {
+		return m.PluckFunc(ctx, column, conditions, args...)
+	}
+	return nil, notMocked("Pluck")
+}
+
+func (m *MockService[T, C]) GroupByScan(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error) {
+	m.record("GroupByScan", append([]any{selects, groupBy, conditions}, args...)...)
+	if m.GroupByScanFunc != nil {
+		return m.GroupByScanFunc(ctx, selects, groupBy, conditions, args...)
+	}
+	return nil, notMocked("GroupByScan")
+}
+
+func (m *MockService[T, C]) Facets(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error) {
+	m.record("Facets", append([]any{conditions, facetColumns}, args...)...)
+	if m.FacetsFunc != nil {
+		return m.FacetsFunc(ctx, conditions, facetColumns, args...)
+	}
+	return nil, notMocked("Facets")
+}
+
+func (m *MockService[T, C]) QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error) {
+	m.record("QueryBuilder", append([]any{filter, config}, args...)...)
+	if m.QueryBuilderFunc != nil {
+		return m.QueryBuilderFunc(ctx, filter, config, args...)
+	}
+	return nil, notMocked("QueryBuilder")
+}
+
+func (m *MockService[T, C]) WithTx(ctx context.Context, fn func(ctx context.Context, tx services.IBaseCrudService[T, C]) error) error {
+	m.record("WithTx", fn)
+	if m.WithTxFunc != nil {
+		return m.WithTxFunc(ctx, fn)
+	}
+	return fn(ctx, m)
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"errors"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -8,14 +11,30 @@ import (
 )
 
 type BaseFilterDto struct {
-	Page       int     `query:"page"`
-	PerPage    int     `query:"per_page"`
-	Pagination *bool   `query:"pagination"`
-	Search     *string `query:"search"`
-	SortKey    *string `query:"sort_key"`
-	SortDir    *string `query:"sort_dir" validate:"omitempty,oneof=ASC DESC"`
+	Page       int      `query:"page"`
+	PerPage    int      `query:"per_page"`
+	Pagination *bool    `query:"pagination"`
+	Search     *string  `query:"search"`
+	SortKey    *string  `query:"sort_key"`
+	SortDir    *string  `query:"sort_dir" validate:"omitempty,oneof=ASC DESC"`
+	Include    *string  `query:"include"`
+	AfterID    *string  `query:"after_id"` // seek pagination: rows with a greater primary key, by primary key
+	Includes   []string `query:"-"`        // Include parsed by BindQuery (see ParseIncludes)
+	Params     []string `query:"-"`        // names of the request's query params, recorded by BindQuery
 }
 
+// ErrUnknownFilter is returned (wrapped) by QueryBuilder with GormConfig.StrictFilters for
+// query params that match no filter.
+var ErrUnknownFilter = errors.New("unknown filter")
+
+// ErrInvalidFilter is returned (wrapped) by QueryBuilder for a filter value it can't use,
+// such as a date range bound that isn't a date, and by the list reads for an after_id or a
+// sort_key they can't use.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// BaseParams are the query params BindQuery reads itself.
+var BaseParams = []string{"page", "per_page", "pagination", "search", "sort_key", "sort_dir", "include", "embed", "after_id"}
+
 type FilterDto interface {
 	GetBase() *BaseFilterDto
 	ToMap() (map[string]interface{}, error)
@@ -46,9 +65,12 @@ func (f *BaseFilterDto) ToMapNoError() map[string]interface{} {
 }
 
 func (f *BaseFilterDto) BindQuery(c *fiber.Ctx) error {
+	f.Params = slices.Sorted(maps.Keys(c.Queries()))
+
 	// Parse primitive values
-	f.Page, _ = strconv.Atoi(c.Query("page", "1"))
-	f.PerPage, _ = strconv.Atoi(c.Query("per_page", "10"))
+	f.Page, _ = strconv.Atoi(c.Query("page"))
+	f.PerPage, _ = strconv.Atoi(c.Query("per_page"))
+	f.Page, f.PerPage = NormalizePagination(f.Page, f.PerPage)
 
 	// Optional booleans
 	if val := c.Query("pagination"); val != "" {
@@ -65,5 +87,20 @@ func (f *BaseFilterDto) BindQuery(c *fiber.Ctx) error {
 	if sortDir := c.Query("sort_dir"); sortDir != "" {
 		f.SortDir = &sortDir
 	}
+	if afterID := c.Query("after_id"); afterID != "" {
+		f.AfterID = &afterID
+	}
+	include := c.Query("include")
+	if include == "" {
+		include = c.Query("embed")
+	}
+	if include != "" {
+		includes, err := ParseIncludes(include)
+		if err != nil {
+			return err
+		}
+		f.Include = &include
+		f.Includes = includes
+	}
 	return nil
 }
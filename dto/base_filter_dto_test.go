@@ -0,0 +1,141 @@
+package dto
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNormalizePagination(t *testing.T) {
+	tests := []struct {
+		name                  string
+		page, perPage         int
+		wantPage, wantPerPage int
+	}{
+		{"unset", 0, 0, 1, DefaultPerPage},
+		{"negative", -3, -1, 1, DefaultPerPage},
+		{"in range", 4, 25, 4, 25},
+		{"too large", 2, MaxPerPage + 1, 2, MaxPerPage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, perPage := NormalizePagination(tt.page, tt.perPage)
+			if page != tt.wantPage || perPage != tt.wantPerPage {
+				t.Errorf("NormalizePagination(%d, %d) = %d, %d; want %d, %d", tt.page, tt.perPage, page, perPage, tt.wantPage, tt.wantPerPage)
+			}
+		})
+	}
+}
+
+func TestParseIncludes(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		invalid bool
+	}{
+		{"", nil, false},
+		{"author", []string{"author"}, false},
+		{" author , comments.user ,author,, ", []string{"author", "comments.user"}, false},
+		{"Author_2.Profile", []string{"Author_2.Profile"}, false},
+		{"comments..user", nil, true},
+		{"author;drop", nil, true},
+		{"2fa", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseIncludes(tt.raw)
+			if errors.Is(err, ErrInvalidInclude) != tt.invalid {
+				t.Fatalf("ParseIncludes(%q) err = %v, want invalid %v", tt.raw, err, tt.invalid)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIncludes(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeList(t *testing.T) {
+	raw := "author,comments"
+	tests := []struct {
+		name   string
+		filter BaseFilterDto
+		want   []string
+	}{
+		{"none", BaseFilterDto{}, nil},
+		{"bound by BindQuery", BaseFilterDto{Include: &raw, Includes: []string{"author"}}, []string{"author"}},
+		{"set by hand", BaseFilterDto{Include: &raw}, []string{"author", "comments"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.filter.IncludeList(); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IncludeList() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		check   func(t *testing.T, f *BaseFilterDto)
+		invalid bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			check: func(t *testing.T, f *BaseFilterDto) {
+				if f.Page != 1 || f.PerPage != DefaultPerPage || f.Pagination != nil || f.Search != nil || len(f.Params) != 0 {
+					t.Errorf("filter = %+v, want the defaults", f)
+				}
+			},
+		},
+		{
+			name:  "all params",
+			query: "page=3&per_page=500&pagination=FALSE&search=jo&sort_key=name&sort_dir=DESC&after_id=7&include=author&status=active",
+			check: func(t *testing.T, f *BaseFilterDto) {
+				if f.Page != 3 || f.PerPage != MaxPerPage || *f.Pagination || *f.Search != "jo" || *f.SortKey != "name" ||
+					*f.SortDir != "DESC" || *f.AfterID != "7" || *f.Include != "author" || !reflect.DeepEqual(f.Includes, []string{"author"}) {
+					t.Errorf("filter = %+v", f)
+				}
+				want := []string{"after_id", "include", "page", "pagination", "per_page", "search", "sort_dir", "sort_key", "status"}
+				if !reflect.DeepEqual(f.Params, want) {
+					t.Errorf("Params = %v, want %v", f.Params, want)
+				}
+			},
+		},
+		{
+			name:  "embed alias",
+			query: "embed=comments.user",
+			check: func(t *testing.T, f *BaseFilterDto) {
+				if !reflect.DeepEqual(f.Includes, []string{"comments.user"}) {
+					t.Errorf("Includes = %q, want [comments.user]", f.Includes)
+				}
+			},
+		},
+		{name: "invalid include", query: "include=a%20b", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter BaseFilterDto
+			var err error
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				err = filter.BindQuery(c)
+				return nil
+			})
+			if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+tt.query, nil)); testErr != nil {
+				t.Fatal(testErr)
+			}
+			if errors.Is(err, ErrInvalidInclude) != tt.invalid {
+				t.Fatalf("BindQuery() = %v, want invalid %v", err, tt.invalid)
+			}
+			if tt.check != nil {
+				tt.check(t, &filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidInclude is returned (wrapped) for a malformed include list or a relation that
+// isn't allowed by the repository's Includable.
+var ErrInvalidInclude = errors.New("invalid include")
+
+var includePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ParseIncludes parses a comma-separated relation list such as "author,comments.user",
+// where dots separate nested relations. Blank and duplicate entries are dropped.
+func ParseIncludes(raw string) ([]string, error) {
+	var includes []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(includes, name) {
+			continue
+		}
+		if !includePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidInclude, name)
+		}
+		includes = append(includes, name)
+	}
+	return includes, nil
+}
+
+// IncludeList returns the requested relations: Includes when BindQuery filled it, otherwise
+// Include parsed with ParseIncludes.
+func (f *BaseFilterDto) IncludeList() ([]string, error) {
+	if f.Includes != nil || f.Include == nil {
+		return f.Includes, nil
+	}
+	return ParseIncludes(*f.Include)
+}
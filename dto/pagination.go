@@ -0,0 +1,23 @@
+package dto
+
+// Pagination defaults shared by BindQuery and the repositories' Paginate scope.
+// They can be changed at startup.
+var (
+	DefaultPerPage = 10
+	MaxPerPage     = 100
+)
+
+// NormalizePagination clamps page to at least 1 and perPage to [1, MaxPerPage], using
+// DefaultPerPage when perPage is not set (zero or negative).
+func NormalizePagination(page, perPage int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	return page, perPage
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type actorKey struct{}
+
+// SetActor returns a copy of ctx carrying the acting user (an id, a claims struct...).
+func SetActor(ctx context.Context, actor any) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// GetActor returns the acting user stored with SetActor, or nil when there is none.
+func GetActor(ctx context.Context) any {
+	return ctx.Value(actorKey{})
+}
+
+// ActorMiddleware stores the acting user in the request's user context. extract reads it
+// from whatever the auth middleware left behind (Locals, JWT claims...); a nil result
+// leaves the request anonymous.
+func ActorMiddleware(extract func(c *fiber.Ctx) any) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if actor := extract(c); actor != nil {
+			c.SetUserContext(SetActor(c.UserContext(), actor))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SQLRecorder collects the SQL statements executed for one request (see DebugSQLMiddleware).
+type SQLRecorder struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (r *SQLRecorder) Record(sql string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, sql)
+}
+
+// Queries returns the statements recorded so far, in execution order.
+func (r *SQLRecorder) Queries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+type sqlRecorderKey struct{}
+
+// WithSQLRecorder returns a copy of ctx whose queries are recorded by recorder.
+func WithSQLRecorder(ctx context.Context, recorder *SQLRecorder) context.Context {
+	return context.WithValue(ctx, sqlRecorderKey{}, recorder)
+}
+
+// GetSQLRecorder returns the recorder stored with WithSQLRecorder, or nil.
+func GetSQLRecorder(ctx context.Context) *SQLRecorder {
+	recorder, _ := ctx.Value(sqlRecorderKey{}).(*SQLRecorder)
+	return recorder
+}
+
+// DebugSQLConfig configures DebugSQLMiddleware.
+type DebugSQLConfig struct {
+	// Allow decides whether the request may turn on SQL debugging, e.g. only outside
+	// production or for admins. A nil Allow disables the middleware.
+	Allow func(c *fiber.Ctx) bool
+
+	// QueryParam and Header turn debugging on when set to "1" or "true".
+	// They default to "debug_sql" and "X-Debug-SQL".
+	QueryParam string
+	Header     string
+}
+
+// DebugSQLMiddleware records the SQL run by the repositories for requests that ask for it
+// (?debug_sql=1) and are allowed to. ResponseTransformer returns the statements in the
+// "sql" field of the response; GetSQLRecorder exposes them to your own logging.
+func DebugSQLMiddleware(config DebugSQLConfig) fiber.Handler {
+	if config.QueryParam == "" {
+		config.QueryParam = "debug_sql"
+	}
+	if config.Header == "" {
+		config.Header = "X-Debug-SQL"
+	}
+
+	return func(c *fiber.Ctx) error {
+		if config.Allow == nil || !(isTrue(c.Query(config.QueryParam)) || isTrue(c.Get(config.Header))) || !config.Allow(c) {
+			return c.Next()
+		}
+		c.SetUserContext(WithSQLRecorder(c.UserContext(), &SQLRecorder{}))
+		return c.Next()
+	}
+}
+
+func isTrue(value string) bool {
+	return value == "1" || value == "true"
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"cmp"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// EnvelopeKeys names the fields of the response envelope (models.BaseResponse) written by
+// ResponseTransformer and ExceptionHandler. Empty names keep their default.
+type EnvelopeKeys struct {
+	Success    string
+	Data       string
+	Message    string
+	StatusCode string
+	Error      string
+	SQL        string
+}
+
+// DefaultEnvelopeKeys are the JSON names of models.BaseResponse.
+var DefaultEnvelopeKeys = EnvelopeKeys{
+	Success:    "success",
+	Data:       "data",
+	Message:    "message",
+	StatusCode: "statusCode",
+	Error:      "error",
+	SQL:        "sql",
+}
+
+// ResponseEnvelopeKeys renames the envelope fields for frontends that expect other names.
+// Set it once at startup:
+//
+//	middlewares.ResponseEnvelopeKeys = middlewares.EnvelopeKeys{Data: "payload", Message: "msg"}
+var ResponseEnvelopeKeys = DefaultEnvelopeKeys
+
+func (k EnvelopeKeys) withDefaults() EnvelopeKeys {
+	return EnvelopeKeys{
+		Success:    cmp.Or(k.Success, DefaultEnvelopeKeys.Success),
+		Data:       cmp.Or(k.Data, DefaultEnvelopeKeys.Data),
+		Message:    cmp.Or(k.Message, DefaultEnvelopeKeys.Message),
+		StatusCode: cmp.Or(k.StatusCode, DefaultEnvelopeKeys.StatusCode),
+		Error:      cmp.Or(k.Error, DefaultEnvelopeKeys.Error),
+		SQL:        cmp.Or(k.SQL, DefaultEnvelopeKeys.SQL),
+	}
+}
+
+// envelope returns response as sent to the client: as is with the default keys, as a map
+// using ResponseEnvelopeKeys otherwise.
+func envelope(response models.BaseResponse[any]) any {
+	keys := ResponseEnvelopeKeys.withDefaults()
+	if keys == DefaultEnvelopeKeys {
+		return response
+	}
+
+	body := fiber.Map{
+		keys.Success:    response.Success,
+		keys.Data:       response.Data,
+		keys.Message:    response.Message,
+		keys.StatusCode: response.StatusCode,
+	}
+	if response.Error != "" {
+		body[keys.Error] = response.Error
+	}
+	if len(response.SQL) > 0 {
+		body[keys.SQL] = response.SQL
+	}
+	return body
+}
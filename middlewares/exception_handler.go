@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/aghiadodeh/go-crud/models"
@@ -10,17 +12,21 @@ func ExceptionHandler(ctx *fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
 	message := "Internal Server Error"
 
+	var crudErr *models.CrudError
 	if e, ok := err.(*fiber.Error); ok {
 		code = e.Code
 		message = e.Message
+	} else if errors.As(err, &crudErr) {
+		code = crudErr.Code
+		message = crudErr.MessageID
 	}
 
 	message = Translate(ctx, message, nil)
 
-	return ctx.Status(code).JSON(models.BaseResponse[any]{
+	return ctx.Status(code).JSON(envelope(models.BaseResponse[any]{
 		Success:    false,
 		Data:       nil,
 		Message:    message,
 		StatusCode: code,
-	})
+	}))
 }
@@ -45,17 +45,28 @@ func InitLocalization(defaultLanguage language.Tag, assets []string) error {
 	return nil
 }
 
+// I18nConfig configures I18nMiddlewareWithConfig.
+type I18nConfig struct {
+	// DefaultLanguage is used when the request carries no language at all.
+	DefaultLanguage string
+
+	// LanguageExtractor reads the language from state set by earlier middlewares,
+	// e.g. a "lang" claim stored by the auth middleware. Return "" to fall through.
+	LanguageExtractor func(c *fiber.Ctx) string
+}
+
 // I18nMiddleware sets up the i18n localizer for each request
 func I18nMiddleware(defaultLanguage string) fiber.Handler {
+	return I18nMiddlewareWithConfig(I18nConfig{DefaultLanguage: defaultLanguage})
+}
+
+// I18nMiddlewareWithConfig sets up the i18n localizer for each request.
+//
+// The language is resolved in this order: the `lang` query param, the `lang` cookie,
+// config.LanguageExtractor, the Accept-Language header, then config.DefaultLanguage.
+func I18nMiddlewareWithConfig(config I18nConfig) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Get language from header, query, cookie, or default
-		lang := c.Get("Accept-Language", defaultLanguage)
-		if l := c.Query("lang"); l != "" {
-			lang = l
-		}
-		if l := c.Cookies("lang"); l != "" {
-			lang = l
-		}
+		lang := resolveLanguage(c, config)
 
 		// You can parse lang here more robustly if needed
 		ctx := context.WithValue(c.UserContext(), LangContextKey, lang)
@@ -69,6 +80,21 @@ func I18nMiddleware(defaultLanguage string) fiber.Handler {
 	}
 }
 
+func resolveLanguage(c *fiber.Ctx, config I18nConfig) string {
+	if l := c.Query("lang"); l != "" {
+		return l
+	}
+	if l := c.Cookies("lang"); l != "" {
+		return l
+	}
+	if config.LanguageExtractor != nil {
+		if l := config.LanguageExtractor(c); l != "" {
+			return l
+		}
+	}
+	return c.Get("Accept-Language", config.DefaultLanguage)
+}
+
 func GetLangFromContext(ctx context.Context) string {
 	if l, ok := ctx.Value(LangContextKey).(string); ok {
 		return l
@@ -86,6 +112,18 @@ func GetLocalizer(c *fiber.Ctx) *i18n.Localizer {
 
 // Translate translates a message using the context's localizer
 func Translate(c *fiber.Ctx, messageID string, templateData map[string]interface{}) string {
+	if translated, ok := Localize(c, messageID, templateData); ok {
+		return translated
+	}
+	return messageID
+}
+
+// Localize is Translate reporting whether a translation was found (in the i18n namespace
+// first, then as a regular message). It reports false when localization isn't initialized.
+func Localize(c *fiber.Ctx, messageID string, templateData map[string]interface{}) (string, bool) {
+	if bundle == nil {
+		return "", false
+	}
 	localizer := GetLocalizer(c)
 
 	// Try to find message in i18n namespace first
@@ -93,7 +131,7 @@ func Translate(c *fiber.Ctx, messageID string, templateData map[string]interface
 		MessageID:    "i18n." + messageID,
 		TemplateData: templateData,
 	}); err == nil {
-		return translated
+		return translated, true
 	}
 
 	// Fallback to regular message
@@ -102,7 +140,7 @@ func Translate(c *fiber.Ctx, messageID string, templateData map[string]interface
 		TemplateData: templateData,
 	})
 	if err != nil {
-		return messageID
+		return "", false
 	}
-	return translated
+	return translated, true
 }
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LoggerMiddleware writes one structured log record per request to logger (JSON on stdout
+// when nil) with its method, path, status, duration, request id and error. Register it
+// first: an error returned by the handlers is answered by the app's ErrorHandler
+// (ExceptionHandler) here, so the status logged is the one the client receives, after
+// ResponseTransformer and ExceptionHandler. Requests answered 5xx are logged at error level.
+func LoggerMiddleware(logger *slog.Logger) fiber.Handler {
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		chainErr := c.Next()
+		if chainErr != nil {
+			if err := c.App().ErrorHandler(c, chainErr); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		requestID := GetRequestID(c.UserContext())
+		if requestID == "" {
+			requestID = c.GetRespHeader(fiber.HeaderXRequestID)
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("request_id", requestID),
+		}
+		if chainErr != nil {
+			attrs = append(attrs, slog.String("error", chainErr.Error()))
+		}
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.UserContext(), level, "request", attrs...)
+		return nil
+	}
+}
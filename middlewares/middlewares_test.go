@@ -0,0 +1,343 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/language"
+
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// serve runs one request through handlers (the last being the route) and returns the
+// response status and body.
+func serve(t *testing.T, req *http.Request, handlers ...fiber.Handler) (int, string) {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: ExceptionHandler})
+	for _, handler := range handlers[:len(handlers)-1] {
+		app.Use(handler)
+	}
+	app.All("/*", handlers[len(handlers)-1])
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		sent     string
+		assigned string // by a previous middleware, like Fiber's requestid
+		want     string
+	}{
+		{"none", "", "", ""},
+		{"sent by the client", "client-1", "", "client-1"},
+		{"assigned by requestid", "client-1", "server-1", "server-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assign := func(c *fiber.Ctx) error {
+				if tt.assigned != "" {
+					c.Set(fiber.HeaderXRequestID, tt.assigned)
+				}
+				return c.Next()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.sent != "" {
+				req.Header.Set(fiber.HeaderXRequestID, tt.sent)
+			}
+			_, body := serve(t, req, assign, RequestIDMiddleware(), func(c *fiber.Ctx) error {
+				return c.SendString(GetRequestID(c.UserContext()))
+			})
+			if body != tt.want {
+				t.Errorf("request id = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestActorMiddleware(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor any
+	}{
+		{"anonymous", nil},
+		{"user", "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any = "unset"
+			extract := func(c *fiber.Ctx) any { return tt.actor }
+			serve(t, httptest.NewRequest(http.MethodGet, "/", nil), ActorMiddleware(extract), func(c *fiber.Ctx) error {
+				got = GetActor(c.UserContext())
+				return nil
+			})
+			if got != tt.actor {
+				t.Errorf("actor = %v, want %v", got, tt.actor)
+			}
+		})
+	}
+}
+
+func TestDebugSQLMiddleware(t *testing.T) {
+	allow := func(c *fiber.Ctx) bool { return true }
+	deny := func(c *fiber.Ctx) bool { return false }
+	tests := []struct {
+		name   string
+		config DebugSQLConfig
+		path   string
+		header map[string]string
+		record bool
+	}{
+		{"not asked", DebugSQLConfig{Allow: allow}, "/", nil, false},
+		{"query param", DebugSQLConfig{Allow: allow}, "/?debug_sql=1", nil, true},
+		{"header", DebugSQLConfig{Allow: allow}, "/", map[string]string{"X-Debug-SQL": "true"}, true},
+		{"other value", DebugSQLConfig{Allow: allow}, "/?debug_sql=yes", nil, false},
+		{"custom param", DebugSQLConfig{Allow: allow, QueryParam: "sql"}, "/?sql=1", nil, true},
+		{"denied", DebugSQLConfig{Allow: deny}, "/?debug_sql=1", nil, false},
+		{"no Allow", DebugSQLConfig{}, "/?debug_sql=1", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			var recorder *SQLRecorder
+			serve(t, req, DebugSQLMiddleware(tt.config), func(c *fiber.Ctx) error {
+				recorder = GetSQLRecorder(c.UserContext())
+				return nil
+			})
+			if (recorder != nil) != tt.record {
+				t.Errorf("recorder = %v, want recording %v", recorder, tt.record)
+			}
+		})
+	}
+}
+
+func TestResponseTransformer(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    EnvelopeKeys
+		disable bool
+		handler fiber.Handler
+		status  int
+		want    map[string]any
+	}{
+		{
+			name:    "wraps data",
+			handler: func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"id": 1}) },
+			status:  http.StatusOK,
+			want:    map[string]any{"success": true, "data": map[string]any{"id": 1.0}, "message": "operation_done_successfully", "statusCode": 200.0},
+		},
+		{
+			name:    "renamed keys",
+			keys:    EnvelopeKeys{Data: "payload", Message: "msg"},
+			handler: func(c *fiber.Ctx) error { return c.JSON([]int{1}) },
+			status:  http.StatusOK,
+			want:    map[string]any{"success": true, "payload": []any{1.0}, "msg": "operation_done_successfully", "statusCode": 200.0},
+		},
+		{
+			name:    "skipped",
+			handler: RawResponse(func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"id": 1}) }),
+			status:  http.StatusOK,
+			want:    map[string]any{"id": 1.0},
+		},
+		{
+			name:    "envelope disabled",
+			disable: true,
+			handler: func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"id": 1}) },
+			status:  http.StatusOK,
+			want:    map[string]any{"id": 1.0},
+		},
+		{
+			name: "already an envelope",
+			handler: func(c *fiber.Ctx) error {
+				return c.JSON(fiber.Map{"success": true, "data": nil, "message": "custom"})
+			},
+			status: http.StatusOK,
+			want:   map[string]any{"success": true, "data": nil, "message": "custom"},
+		},
+		{
+			name:    "fiber error",
+			handler: func(c *fiber.Ctx) error { return fiber.NewError(http.StatusBadRequest, "bad_sort") },
+			status:  http.StatusBadRequest,
+			want:    map[string]any{"success": false, "data": nil, "message": "bad_sort", "statusCode": 400.0},
+		},
+		{
+			name:    "crud error",
+			handler: func(c *fiber.Ctx) error { return models.NewConflictError("duplicate_name", errors.New("unique")) },
+			status:  http.StatusConflict,
+			want:    map[string]any{"success": false, "data": nil, "message": "duplicate_name", "statusCode": 409.0},
+		},
+		{
+			name: "recorded SQL",
+			handler: func(c *fiber.Ctx) error {
+				recorder := &SQLRecorder{}
+				recorder.Record("SELECT 1")
+				c.SetUserContext(WithSQLRecorder(c.UserContext(), recorder))
+				return c.JSON(true)
+			},
+			status: http.StatusOK,
+			want:   map[string]any{"success": true, "data": true, "message": "operation_done_successfully", "statusCode": 200.0, "sql": []any{"SELECT 1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ResponseEnvelopeKeys, DisableResponseEnvelope = tt.keys, tt.disable
+			t.Cleanup(func() { ResponseEnvelopeKeys, DisableResponseEnvelope = DefaultEnvelopeKeys, false })
+
+			status, body := serve(t, httptest.NewRequest(http.MethodGet, "/", nil), ResponseTransformer, tt.handler)
+			var got map[string]any
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("body %s: %v", body, err)
+			}
+			if status != tt.status || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("response = %d %v, want %d %v", status, got, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceptionHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"fiber error", fiber.ErrRequestTimeout, http.StatusRequestTimeout, "Request Timeout"},
+		{"crud error", models.NewNotFoundError("role_not_found"), http.StatusNotFound, "role_not_found"},
+		{"wrapped crud error", errors.Join(errors.New("lookup"), models.ErrNotFound), http.StatusNotFound, "item_not_found"},
+		{"other", errors.New("boom"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, httptest.NewRequest(http.MethodGet, "/", nil), func(c *fiber.Ctx) error { return tt.err })
+			var got models.BaseResponse[any]
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.status || got.StatusCode != tt.status || got.Message != tt.message || got.Success {
+				t.Errorf("response = %d %s, want %d %q", status, body, tt.status, tt.message)
+			}
+		})
+	}
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler fiber.Handler
+		status  float64
+		level   string
+		err     string
+	}{
+		{"success", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusCreated) }, 201, "INFO", ""},
+		{"crud error", func(c *fiber.Ctx) error { return models.ErrNotFound }, 404, "INFO", "item_not_found"},
+		{"failure", func(c *fiber.Ctx) error { return errors.New("boom") }, 500, "ERROR", "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&out, nil))
+			req := httptest.NewRequest(http.MethodPost, "/roles", nil)
+			req.Header.Set(fiber.HeaderXRequestID, "req-1")
+			status, _ := serve(t, req, LoggerMiddleware(logger), RequestIDMiddleware(), tt.handler)
+
+			var record map[string]any
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("log %q: %v", out.String(), err)
+			}
+			if float64(status) != tt.status || record["status"] != tt.status || record["level"] != tt.level {
+				t.Errorf("logged %v for a %d response, want status %v at %s", record, status, tt.status, tt.level)
+			}
+			if record["method"] != "POST" || record["path"] != "/roles" || record["request_id"] != "req-1" {
+				t.Errorf("logged %v, want POST /roles of req-1", record)
+			}
+			if err, _ := record["error"].(string); err != tt.err {
+				t.Errorf("logged error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestI18n(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"en.json":   `{"i18n.item_not_found": "Item not found", "greeting": "Hello {{.Name}}"}`,
+		"fr.json":   `{"i18n.item_not_found": "Élément introuvable", "greeting": "Bonjour {{.Name}}"}`,
+		"notes.txt": "skipped",
+	}
+	var assets []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, path)
+	}
+	if err := InitLocalization(language.English, assets); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bundle = nil })
+
+	config := I18nConfig{
+		DefaultLanguage:   "en",
+		LanguageExtractor: func(c *fiber.Ctx) string { return c.Get("X-User-Lang") },
+	}
+	tests := []struct {
+		name      string
+		path      string
+		headers   map[string]string
+		messageID string
+		want      string
+	}{
+		{"default language", "/", nil, "item_not_found", "Item not found"},
+		{"accept-language", "/", map[string]string{"Accept-Language": "fr"}, "item_not_found", "Élément introuvable"},
+		{"extractor before accept-language", "/", map[string]string{"X-User-Lang": "en", "Accept-Language": "fr"}, "item_not_found", "Item not found"},
+		{"cookie before extractor", "/", map[string]string{"Cookie": "lang=fr", "X-User-Lang": "en"}, "item_not_found", "Élément introuvable"},
+		{"query first", "/?lang=en", map[string]string{"Cookie": "lang=fr"}, "item_not_found", "Item not found"},
+		{"regular message with data", "/?lang=fr", nil, "greeting", "Bonjour Ada"},
+		{"untranslated", "/", nil, "missing_key", "missing_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			_, body := serve(t, req, I18nMiddlewareWithConfig(config), func(c *fiber.Ctx) error {
+				return c.SendString(Translate(c, tt.messageID, map[string]any{"Name": "Ada"}))
+			})
+			if body != tt.want {
+				t.Errorf("Translate(%q) = %q, want %q", tt.messageID, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeWithoutBundle(t *testing.T) {
+	_, body := serve(t, httptest.NewRequest(http.MethodGet, "/", nil), func(c *fiber.Ctx) error {
+		if _, ok := Localize(c, "item_not_found", nil); ok {
+			t.Error("Localize() found a translation without InitLocalization")
+		}
+		return c.SendString(Translate(c, "item_not_found", nil))
+	})
+	if body != "item_not_found" {
+		t.Errorf("Translate() = %q, want the message ID", body)
+	}
+}
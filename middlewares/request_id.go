@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type requestIDKey struct{}
+
+// SetRequestID returns a copy of ctx carrying the request id.
+func SetRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// GetRequestID returns the request id stored with SetRequestID, or "" when there is none.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware stores the request id in the request's user context, where the
+// repositories pick it up (see GormConfig.SQLComment). The id is the X-Request-ID set by
+// Fiber's requestid middleware (register it first), or the one sent by the client.
+func RequestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.GetRespHeader(fiber.HeaderXRequestID)
+		if id == "" {
+			id = c.Get(fiber.HeaderXRequestID)
+		}
+		if id != "" {
+			c.SetUserContext(SetRequestID(c.UserContext(), id))
+		}
+		return c.Next()
+	}
+}
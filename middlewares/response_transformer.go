@@ -8,6 +8,31 @@ import (
 	"github.com/aghiadodeh/go-crud/models"
 )
 
+// SkipResponseTransformKey is the ctx.Locals key that makes ResponseTransformer send the
+// response as the handler wrote it (set it to true).
+const SkipResponseTransformKey = "skipResponseTransform"
+
+// SkipResponseTransform makes ResponseTransformer leave the current response untouched.
+// Use it for file downloads, streams (SSE) and other non-JSON bodies.
+func SkipResponseTransform(ctx *fiber.Ctx) {
+	ctx.Locals(SkipResponseTransformKey, true)
+}
+
+// DisableResponseEnvelope makes ResponseTransformer send every successful response as the
+// handler wrote it, as if each route were wrapped in RawResponse, for clients that want plain
+// JSON. Errors are still translated and answered with the envelope. Set it once at startup.
+var DisableResponseEnvelope = false
+
+// RawResponse wraps a handler so its response bypasses ResponseTransformer:
+//
+//	router.Get("/export", middlewares.RawResponse(controller.Export))
+func RawResponse(handler fiber.Handler) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		SkipResponseTransform(ctx)
+		return handler(ctx)
+	}
+}
+
 func ResponseTransformer(ctx *fiber.Ctx) error {
 	// Call next middleware/handler
 	err := ctx.Next()
@@ -16,17 +41,18 @@ func ResponseTransformer(ctx *fiber.Ctx) error {
 		if ok {
 			statusCode := fiberError.Code
 			message := Translate(ctx, fiberError.Message, nil)
-			return ctx.Status(statusCode).JSON(models.BaseResponse[any]{
+			return ctx.Status(statusCode).JSON(envelope(models.BaseResponse[any]{
 				Success:    false,
 				Message:    message,
 				Data:       nil,
 				StatusCode: statusCode,
-			})
+			}))
 		}
+		return err // e.g. models.CrudError, answered by the app's ErrorHandler (ExceptionHandler)
 	}
 
 	// Skip Transform
-	if skip, ok := ctx.Locals("skipResponseTransform").(bool); ok && skip {
+	if skip, ok := ctx.Locals(SkipResponseTransformKey).(bool); DisableResponseEnvelope || (ok && skip) {
 		return nil
 	}
 
@@ -56,9 +82,10 @@ func ResponseTransformer(ctx *fiber.Ctx) error {
 	// Check if response is already a BaseResponse
 	var maybeMap map[string]interface{}
 	if err := json.Unmarshal(originalBody, &maybeMap); err == nil {
-		_, hasSuccess := maybeMap["success"]
-		_, hasData := maybeMap["data"]
-		_, hasMessage := maybeMap["message"]
+		keys := ResponseEnvelopeKeys.withDefaults()
+		_, hasSuccess := maybeMap[keys.Success]
+		_, hasData := maybeMap[keys.Data]
+		_, hasMessage := maybeMap[keys.Message]
 		if hasSuccess && hasData && hasMessage {
 			// Already in base response format
 			return nil
@@ -72,6 +99,9 @@ func ResponseTransformer(ctx *fiber.Ctx) error {
 		Message:    message,
 		StatusCode: statusCode,
 	}
+	if recorder := GetSQLRecorder(ctx.UserContext()); recorder != nil {
+		response.SQL = recorder.Queries()
+	}
 
-	return ctx.JSON(response)
+	return ctx.JSON(envelope(response))
 }
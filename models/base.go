@@ -0,0 +1,56 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Base is the auto-increment ID, timestamps and soft-delete column the repositories expect.
+// Embed it instead of declaring them on every model:
+//
+//	type Role struct {
+//		models.Base
+//		Name string `json:"name"`
+//	}
+type Base struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// BaseUUID is Base with a UUID primary key, generated on create when left empty (a
+// repository's IDGenerator runs first). A model declaring its own BeforeCreate hook must call
+// BaseUUID.BeforeCreate itself.
+type BaseUUID struct {
+	ID        string         `gorm:"primaryKey;size:36" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (b *BaseUUID) BeforeCreate(tx *gorm.DB) error {
+	if b.ID != "" {
+		return nil
+	}
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+	b.ID = id
+	return nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -1,9 +1,10 @@
 package models
 
 type BaseResponse[T any] struct {
-	Success    bool   `json:"success"`
-	Data       T      `json:"data"`
-	Message    string `json:"message"`
-	StatusCode int    `json:"statusCode"`
-	Error      string `json:"error,omitempty"`
+	Success    bool     `json:"success"`
+	Data       T        `json:"data"`
+	Message    string   `json:"message"`
+	StatusCode int      `json:"statusCode"`
+	Error      string   `json:"error,omitempty"`
+	SQL        []string `json:"sql,omitempty"` // statements run, when DebugSQLMiddleware is on
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestBaseUUIDBeforeCreate(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{"generated when empty", "", false},
+		{"kept when set", "custom-id", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &BaseUUID{ID: tt.id}
+			if err := base.BeforeCreate(nil); err != nil {
+				t.Fatal(err)
+			}
+			if tt.keep && base.ID != tt.id {
+				t.Errorf("ID = %q, want %q kept", base.ID, tt.id)
+			}
+			if !tt.keep && !uuidV4.MatchString(base.ID) {
+				t.Errorf("ID = %q, want a version 4 UUID", base.ID)
+			}
+		})
+	}
+}
+
+func TestNewUUIDIsRandom(t *testing.T) {
+	seen := map[string]bool{}
+	for range 100 {
+		id, err := newUUID()
+		if err != nil || seen[id] {
+			t.Fatalf("newUUID() = %q, %v; want a fresh id", id, err)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "net/http"
+
+// CrudError is a domain failure (not found, conflict, forbidden...) carrying the HTTP status
+// to answer with. Services return it; the controllers and ExceptionHandler turn it into a
+// response with Code and the translation of MessageID.
+type CrudError struct {
+	Code      int    // HTTP status code
+	MessageID string // i18n message ID, also the message when no translation exists
+	Err       error  // underlying cause, if any
+}
+
+// ErrNotFound is the 404 returned by the *OrError lookups; match it with errors.Is.
+var ErrNotFound = NewNotFoundError("item_not_found")
+
+func NewCrudError(code int, messageID string, err error) *CrudError {
+	return &CrudError{Code: code, MessageID: messageID, Err: err}
+}
+
+func NewNotFoundError(messageID string) *CrudError {
+	return NewCrudError(http.StatusNotFound, messageID, nil)
+}
+
+func NewConflictError(messageID string, err error) *CrudError {
+	return NewCrudError(http.StatusConflict, messageID, err)
+}
+
+func NewForbiddenError(messageID string) *CrudError {
+	return NewCrudError(http.StatusForbidden, messageID, nil)
+}
+
+func (e *CrudError) Error() string {
+	if e.Err != nil {
+		return e.MessageID + ": " + e.Err.Error()
+	}
+	return e.MessageID
+}
+
+func (e *CrudError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,10 @@
+package models
+
+// CursorResponse is a page of a keyset (cursor) paginated list. Cursors are opaque,
+// URL-safe strings: pass NextCursor (or PrevCursor) back to get the adjacent page.
+type CursorResponse[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"nextCursor"` // nil on the last page
+	PrevCursor *string `json:"prevCursor"` // nil on the first page
+	HasMore    bool    `json:"hasMore"`    // whether a next page exists
+}
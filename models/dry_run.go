@@ -0,0 +1,8 @@
+package models
+
+// DryRun previews a write without running it: how many rows it would affect and the SQL it
+// would execute.
+type DryRun struct {
+	Affected int64    `json:"affected"`
+	SQL      []string `json:"sql"`
+}
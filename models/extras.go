@@ -0,0 +1,12 @@
+package models
+
+// Extras holds the computed columns of a row (aliases from a SelectHandler such as
+// "COUNT(children.id) AS children_count") that have no field on the model. Add one to a
+// model to get them back from FindAll, FindAllWithPaging, FindOne and FindOneByPK:
+//
+//	type Category struct {
+//		ID     uint          `json:"id"`
+//		Name   string        `json:"name"`
+//		Extras models.Extras `json:"extras,omitempty" gorm:"-"`
+//	}
+type Extras map[string]any
@@ -1,7 +1,13 @@
 package models
 
 type ListResponse[T any] struct {
-	Total    int64 `json:"total"`
-	Data     []T   `json:"data"`
-	Metadata any   `json:"metadata,omitempty"`
+	Total    int64         `json:"total"`
+	Data     []T           `json:"data"`
+	Metadata *ListMetadata `json:"metadata,omitempty"` // nil (and omitted) unless computed
+}
+
+// ListMetadata carries both totals when GormConfig.CountUnfiltered is enabled.
+type ListMetadata struct {
+	TotalFiltered   int64 `json:"totalFiltered"`
+	TotalUnfiltered int64 `json:"totalUnfiltered"`
 }
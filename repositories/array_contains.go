@@ -0,0 +1,42 @@
+package repositories
+
+import "fmt"
+
+// ArrayContains creates a condition matching rows whose array column holds value, or every
+// element of value when it is a slice, e.g. posts tagged "go" without a join table:
+//
+//	Postgres:   column @> ?                       -- ? = '{go}', a native array column (text[], int[]...)
+//	MySQL:      JSON_CONTAINS(column, ?)          -- ? = '["go"]', a JSON array column
+//	SQLite:     every element of ? is in json_each(column)
+//	SQL Server: every element of ? is in OPENJSON(column)
+//
+// Values must be strings or numbers. Other databases get the MySQL form.
+func ArrayContains(column string, value any) *Condition {
+	return &Condition{
+		parts: []conditionPart{
+			{
+				build: func(dialect Dialect) (string, []any) {
+					return arrayContains(dialect, column, value)
+				},
+			},
+		},
+	}
+}
+
+// arrayContains returns the predicate of ArrayContains for dialect, and its argument.
+func arrayContains(dialect Dialect, column string, value any) (string, []any) {
+	items, ok := normalizeSlice(value).([]any)
+	if !ok {
+		items = []any{normalizeArg(value)}
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return fmt.Sprintf("%s @> ?", column), []any{postgresArray(items)}
+	case DialectSQLite:
+		return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM json_each(?) AS wanted WHERE wanted.value NOT IN (SELECT value FROM json_each(%s)))", column), []any{jsonArray(items)}
+	case DialectSQLServer:
+		return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM OPENJSON(?) AS wanted WHERE wanted.value NOT IN (SELECT value FROM OPENJSON(%s)))", column), []any{jsonArray(items)}
+	}
+	return fmt.Sprintf("JSON_CONTAINS(%s, ?)", column), []any{jsonArray(items)}
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+type account struct {
+	models.BaseUUID
+	Email string
+}
+
+// baseModelSteps checks that a model embedding models.Base or models.BaseUUID works with the
+// repository: its promoted ID is the schema's primary key, Create returns it, extractID
+// reads it and DeleteOneByPK soft-deletes the row.
+func baseModelSteps[T any](t *testing.T, db *gorm.DB, table string, createDto T, validID func(id any) bool) {
+	ctx := context.Background()
+	r := NewGormRepository[T](db, nil, table)
+
+	s, err := r.schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PrioritizedPrimaryField == nil || s.PrioritizedPrimaryField.DBName != "id" || s.LookUpField("deleted_at") == nil {
+		t.Fatalf("schema of %s: primary key %v, want id and a deleted_at column", s.Name, s.PrioritizedPrimaryField)
+	}
+
+	id, err := r.Create(ctx, createDto)
+	if err != nil || !validID(id) {
+		t.Fatalf("Create() = %v, %v; want a generated id", id, err)
+	}
+	item, err := r.FindOneByPK(ctx, id, nil)
+	if err != nil || item == nil {
+		t.Fatalf("FindOneByPK(%v) = %v, %v", id, item, err)
+	}
+	if extracted, err := extractID(item); err != nil || fmt.Sprint(extracted) != fmt.Sprint(id) {
+		t.Errorf("extractID() = %v, %v; want %v", extracted, err, id)
+	}
+
+	if err := r.DeleteOneByPK(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := r.FindOneByPK(ctx, id, nil); err != nil || item != nil {
+		t.Errorf("FindOneByPK() after delete = %v, %v; want nil", item, err)
+	}
+	var rows int64
+	if err := db.Table(table).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&rows).Error; err != nil || rows != 1 {
+		t.Errorf("soft-deleted rows = %d, %v; want 1", rows, err)
+	}
+}
+
+func TestEmbeddedBaseModels(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, db *gorm.DB)
+	}{
+		{"Base", func(t *testing.T, db *gorm.DB) {
+			baseModelSteps(t, db, "products", product{Name: "p1"}, func(id any) bool { return fmt.Sprint(id) == "1" })
+		}},
+		{"BaseUUID", func(t *testing.T, db *gorm.DB) {
+			baseModelSteps(t, db, "accounts", account{Email: "a@example.com"}, func(id any) bool {
+				s, ok := id.(string)
+				return ok && len(s) == 36
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if err := db.AutoMigrate(&account{}); err != nil {
+				t.Fatal(err)
+			}
+			tt.run(t, db)
+		})
+	}
+}
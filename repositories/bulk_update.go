@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// BulkUpdate writes the given columns of every item, each row getting its own values (e.g.
+// new positions after a reorder), matching rows by primary key. Each chunk of IDChunkSize
+// items is a single statement setting every column to a CASE on the primary key:
+//
+//	UPDATE items SET position = CASE id WHEN 1 THEN 3 WHEN 2 THEN 1 ... ELSE position END WHERE id IN (1, 2, ...)
+//
+// All chunks run in one transaction. Columns are checked like PatchColumnsByPK's, and as
+// with UpdateColumnsByPK no hooks run and UpdatedAt is not touched.
+func (r *GormRepository[T]) BulkUpdate(ctx context.Context, items []T, columns []string, args ...any) error {
+	if len(items) == 0 {
+		return nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("%s has no primary key to update by", s.Name)
+	}
+	fields, err := r.bulkUpdateFields(s, columns)
+	if err != nil {
+		return err
+	}
+
+	return r.retryWrite(ctx, func() error {
+		return r.db(ctx).Transaction(func(tx *gorm.DB) error {
+			offset := 0
+			for chunk := range slices.Chunk(items, r.config().ChunkSize()) {
+				ids := make([]any, len(chunk))
+				for i := range chunk {
+					id, zero := pk.ValueOf(ctx, reflect.ValueOf(&chunk[i]).Elem())
+					if zero {
+						return fmt.Errorf("BulkUpdate: item %d has no primary key", offset+i)
+					}
+					ids[i] = id
+				}
+
+				updates := make(map[string]any, len(fields))
+				for _, field := range fields {
+					var sql strings.Builder
+					sql.WriteString("CASE ?")
+					vars := []any{clause.Column{Name: pk.DBName}}
+					for i := range chunk {
+						value, _ := field.ValueOf(ctx, reflect.ValueOf(&chunk[i]).Elem())
+						sql.WriteString(" WHEN ? THEN ?")
+						vars = append(vars, ids[i], value)
+					}
+					// the ELSE branch gives the CASE the column's type (Postgres reads the bare
+					// THEN parameters as text otherwise)
+					sql.WriteString(" ELSE ? END")
+					vars = append(vars, clause.Column{Name: field.DBName})
+					updates[field.DBName] = gorm.Expr(sql.String(), vars...)
+				}
+
+				query := r.excludeDeleted(tx.Model(new(T))).
+					Where(clause.IN{Column: clause.Column{Name: pk.DBName}, Values: ids})
+				if err := query.UpdateColumns(updates).Error; err != nil {
+					return err
+				}
+				offset += len(chunk)
+			}
+			return nil
+		})
+	})
+}
+
+// bulkUpdateFields resolves BulkUpdate's columns, which must be updatable, non-key columns
+// (within UpdatableColumns when set).
+func (r *GormRepository[T]) bulkUpdateFields(s *schema.Schema, columns []string) ([]*schema.Field, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: no columns to update", ErrInvalidColumn)
+	}
+	allowed := r.config().UpdatableColumns
+	fields := make([]*schema.Field, 0, len(columns))
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" || field.PrimaryKey || !field.Updatable {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidColumn, column)
+		}
+		if len(allowed) > 0 && !slices.Contains(allowed, field.DBName) && !slices.Contains(allowed, field.Name) {
+			return nil, fmt.Errorf("%w: %s is not updatable", ErrInvalidColumn, column)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// scope narrows a query on T down to the rows an operation applies to.
+type scope func(db *gorm.DB) *gorm.DB
+
+// deleteWhere deletes the rows of T matched by where. When CascadeSoftDelete is configured,
+// the listed relations are soft-deleted in the same transaction. It returns the number of rows
+// of T deleted.
+func (r *GormRepository[T]) deleteWhere(db *gorm.DB, where scope) (int64, error) {
+	if len(r.config().CascadeSoftDelete) == 0 {
+		return r.softDelete(where(db.Model(new(T))))
+	}
+
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// collect the children before the parents disappear from the default scope
+		children := make([]*gorm.DB, 0, len(r.config().CascadeSoftDelete))
+		for _, name := range r.config().CascadeSoftDelete {
+			child, err := r.childQuery(tx, name, where, false)
+			if err != nil {
+				return err
+			}
+			if child != nil {
+				children = append(children, child)
+			}
+		}
+
+		var err error
+		if deleted, err = r.softDelete(where(tx.Model(new(T)))); err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := child.Delete(child.Statement.Model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// restoreWhere clears the soft-delete column on the rows of T matched by where. When
+// CascadeRestore is set, the CascadeSoftDelete relations deleted at or after their parent
+// are restored too.
+func (r *GormRepository[T]) restoreWhere(db *gorm.DB, where scope) error {
+	if !r.config().CascadeRestore || len(r.config().CascadeSoftDelete) == 0 {
+		return r.restore(where(db.Model(new(T))))
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// children go first: the comparison needs the parent's deletion time
+		for _, name := range r.config().CascadeSoftDelete {
+			child, err := r.childQuery(tx, name, where, true)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue
+			}
+			if err := child.UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return r.restore(where(tx.Model(new(T))))
+	})
+}
+
+// childQuery returns a query on the rows of relation name that belong to the parents
+// matched by where, or nil when there are none. For restores the query is unscoped and,
+// unless the parent uses a boolean soft delete, limited to children deleted at or after
+// their parent.
+func (r *GormRepository[T]) childQuery(tx *gorm.DB, name string, where scope, restore bool) (*gorm.DB, error) {
+	relation, err := r.cascadeRelation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	model := reflect.New(relation.FieldSchema.ModelType).Interface()
+	query := tx.Session(&gorm.Session{NewDB: true}).Model(model)
+	if restore {
+		query = query.Unscoped()
+	}
+
+	for _, ref := range relation.References {
+		if !ref.OwnPrimaryKey {
+			if ref.PrimaryValue != "" { // polymorphic type column
+				query = query.Where(clause.Eq{Column: clause.Column{Name: ref.ForeignKey.DBName}, Value: ref.PrimaryValue})
+			}
+			continue
+		}
+
+		parents := where(tx.Model(new(T)))
+		if restore {
+			parents = parents.Unscoped()
+		} else {
+			parents = r.excludeDeleted(parents)
+		}
+		var keys []any
+		if err := parents.Pluck(ref.PrimaryKey.DBName, &keys).Error; err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, nil
+		}
+		query = query.Where(clause.IN{Column: clause.Column{Name: ref.ForeignKey.DBName}, Values: keys})
+
+		if restore && !r.booleanSoftDelete() {
+			parent, child := relation.Schema.Table, relation.FieldSchema.Table
+			query = query.Where(fmt.Sprintf(
+				"%s >= (SELECT %s FROM %s WHERE %s = %s)",
+				tx.Statement.Quote(clause.Column{Table: child, Name: "deleted_at"}),
+				tx.Statement.Quote(clause.Column{Table: parent, Name: r.softDeleteColumn()}),
+				tx.Statement.Quote(clause.Table{Name: parent}),
+				tx.Statement.Quote(clause.Column{Table: parent, Name: ref.PrimaryKey.DBName}),
+				tx.Statement.Quote(clause.Column{Table: child, Name: ref.ForeignKey.DBName}),
+			))
+		}
+	}
+	return query, nil
+}
+
+// cascadeRelation looks up a CascadeSoftDelete entry on T's schema.
+func (r *GormRepository[T]) cascadeRelation(name string) (*schema.Relationship, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	relation, ok := s.Relationships.Relations[name]
+	if !ok {
+		return nil, fmt.Errorf("cascade: unknown relation %s on %s", name, s.Name)
+	}
+	if relation.Type != schema.HasOne && relation.Type != schema.HasMany {
+		return nil, fmt.Errorf("cascade: relation %s on %s must be has-one or has-many", name, s.Name)
+	}
+	return relation, nil
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+)
+
+// runConcurrently runs tasks in their own goroutines and returns the first error. The tasks'
+// queries must be built on a context cancelled by cancel: the first failure calls it, so the
+// queries still in flight are aborted instead of completing. Inside a transaction the tasks
+// run one after another, since its connection runs one statement at a time.
+func runConcurrently(ctx context.Context, cancel context.CancelFunc, tasks ...func() error) error {
+	if _, inTx := TxFromContext(ctx); inTx || len(tasks) < 2 {
+		for _, task := range tasks {
+			if err := task(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for _, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := task(); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return first
+}
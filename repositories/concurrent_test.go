@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	failure := errors.New("count failed")
+	tests := []struct {
+		name    string
+		inTx    bool
+		fail    bool
+		wantErr error
+	}{
+		{"all succeed", false, false, nil},
+		{"a failure cancels the others", false, true, failure},
+		{"sequential inside a transaction", true, false, nil},
+		{"sequential failure inside a transaction", true, true, failure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.inTx {
+				ctx = ContextWithTx(ctx, &gorm.DB{})
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			var running, peak atomic.Int32
+			track := func() func() {
+				if n := running.Add(1); n > peak.Load() {
+					peak.Store(n)
+				}
+				return func() { running.Add(-1) }
+			}
+			slow := func() error { // an in-flight query: returns when ctx is cancelled
+				defer track()()
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+					return nil
+				}
+			}
+			failing := func() error {
+				defer track()()
+				time.Sleep(5 * time.Millisecond)
+				if tt.fail {
+					return failure
+				}
+				return nil
+			}
+
+			start := time.Now()
+			err := runConcurrently(ctx, cancel, failing, slow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.inTx && peak.Load() != 1 {
+				t.Errorf("peak concurrency = %d, want 1 inside a transaction", peak.Load())
+			}
+			if !tt.inTx && !tt.fail && peak.Load() != 2 {
+				t.Errorf("peak concurrency = %d, want 2", peak.Load())
+			}
+			if !tt.inTx && tt.fail && time.Since(start) >= 50*time.Millisecond {
+				t.Errorf("took %v: the failure didn't cancel the slow task", time.Since(start))
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package repositories
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Condition represents a composable query condition with a fluent builder API.
@@ -42,13 +46,17 @@ import (
 //	repo.Delete(ctx, repositories.In("id", expiredIDs))
 type Condition struct {
 	parts []conditionPart
+	joins []string   // joins the conditions need (QueryBuilder's SearchJoins)
+	rank  *Condition // search relevance, used to sort when no sort_key is given
 }
 
 type conditionPart struct {
-	connector string     // "" for the first part, "AND" or "OR" for subsequent parts
-	fragment  string     // SQL fragment like "status = ?"
-	args      []any      // bind values for this fragment
-	group     *Condition // nested group (if set, fragment/args are ignored)
+	connector string                                // "" for the first part, "AND" or "OR" for subsequent parts
+	fragment  string                                // SQL fragment like "status = ?"
+	render    func(dialect Dialect) string          // dialect-specific fragment (if set, fragment is ignored)
+	build     func(dialect Dialect) (string, []any) // dialect-specific fragment and args (if set, overrides render)
+	args      []any                                 // bind values for this fragment
+	group     *Condition                            // nested group (if set, fragment/args are ignored)
 }
 
 // --- Constructor functions (start a new condition) ---
@@ -84,13 +92,17 @@ func Lte(column string, value any) *Condition {
 }
 
 // In creates a condition: column IN (values)
+//
+// values may be any slice, including slices of custom types implementing driver.Valuer:
+//
+//	In("status", []OrderStatus{StatusPaid, StatusShipped})
 func In(column string, values any) *Condition {
-	return newLeaf(fmt.Sprintf("%s IN (?)", column), values)
+	return newLeaf(fmt.Sprintf("%s IN (?)", column), normalizeSlice(values))
 }
 
 // NotIn creates a condition: column NOT IN (values)
 func NotIn(column string, values any) *Condition {
-	return newLeaf(fmt.Sprintf("%s NOT IN (?)", column), values)
+	return newLeaf(fmt.Sprintf("%s NOT IN (?)", column), normalizeSlice(values))
 }
 
 // Like creates a condition: column LIKE pattern
@@ -104,12 +116,13 @@ func Like(column string, pattern string) *Condition {
 }
 
 // ILike creates a case-insensitive LIKE: LOWER(column) LIKE pattern
+// (column ILIKE pattern on Postgres, so case-insensitive indexes can be used).
 //
 // The pattern is automatically lowercased:
 //
 //	ILike("name", "%John%")  // matches "john", "JOHN", "John", etc.
 func ILike(column string, pattern string) *Condition {
-	return newLeaf(fmt.Sprintf("LOWER(%s) LIKE ?", column), strings.ToLower(pattern))
+	return newCaseInsensitiveLike(column, false, pattern, false)
 }
 
 // Contains creates a case-insensitive substring search.
@@ -118,30 +131,51 @@ func ILike(column string, pattern string) *Condition {
 //
 //	Contains("name", "john")  // matches "John Doe", "JOHNNY", etc.
 func Contains(column string, value string) *Condition {
-	return newLeaf(
-		fmt.Sprintf("LOWER(%s) LIKE ?", column),
-		fmt.Sprintf("%%%s%%", strings.ToLower(value)),
-	)
+	return newCaseInsensitiveLike(column, false, fmt.Sprintf("%%%s%%", value), false)
 }
 
 // StartsWith creates a case-insensitive prefix search.
 //
 // Equivalent to: LOWER(column) LIKE 'value%'
 func StartsWith(column string, value string) *Condition {
-	return newLeaf(
-		fmt.Sprintf("LOWER(%s) LIKE ?", column),
-		fmt.Sprintf("%s%%", strings.ToLower(value)),
-	)
+	return newCaseInsensitiveLike(column, false, fmt.Sprintf("%s%%", value), false)
 }
 
 // EndsWith creates a case-insensitive suffix search.
 //
 // Equivalent to: LOWER(column) LIKE '%value'
 func EndsWith(column string, value string) *Condition {
-	return newLeaf(
-		fmt.Sprintf("LOWER(%s) LIKE ?", column),
-		fmt.Sprintf("%%%s", strings.ToLower(value)),
-	)
+	return newCaseInsensitiveLike(column, false, fmt.Sprintf("%%%s", value), false)
+}
+
+// NotLike creates a condition: column NOT LIKE pattern ESCAPE '\'
+//
+// You provide the full pattern including wildcards; escape literal %, _ and \ in it with a
+// backslash (see EscapeLike):
+//
+//	NotLike("email", "%@example.com")          // excludes example.com addresses
+//	NotLike("code", EscapeLike(prefix)+"%")    // excludes codes starting with prefix as typed
+func NotLike(column string, pattern string) *Condition {
+	return &Condition{
+		parts: []conditionPart{
+			{
+				render: func(dialect Dialect) string {
+					return fmt.Sprintf("%s NOT LIKE ?%s", column, likeEscape(dialect))
+				},
+				args: []any{pattern},
+			},
+		},
+	}
+}
+
+// NotContains creates a case-insensitive negated substring search.
+//
+// Equivalent to: LOWER(column) NOT LIKE '%value%' ESCAPE '\'. % and _ in value match
+// themselves, not any characters.
+//
+//	NotContains("name", "test")  // excludes "Test User", "TESTING", etc.
+func NotContains(column string, value string) *Condition {
+	return newCaseInsensitiveLike(column, true, fmt.Sprintf("%%%s%%", EscapeLike(value)), true)
 }
 
 // IsNull creates a condition: column IS NULL
@@ -163,10 +197,12 @@ func IsNotNull(column string) *Condition {
 }
 
 // Between creates a condition: column BETWEEN low AND high
+//
+//	Between("created_at", time.Now().AddDate(0, -1, 0), time.Now())
 func Between(column string, low, high any) *Condition {
 	return &Condition{
 		parts: []conditionPart{
-			{fragment: fmt.Sprintf("%s BETWEEN ? AND ?", column), args: []any{low, high}},
+			{fragment: fmt.Sprintf("%s BETWEEN ? AND ?", column), args: []any{normalizeArg(low), normalizeArg(high)}},
 		},
 	}
 }
@@ -175,7 +211,7 @@ func Between(column string, low, high any) *Condition {
 func NotBetween(column string, low, high any) *Condition {
 	return &Condition{
 		parts: []conditionPart{
-			{fragment: fmt.Sprintf("%s NOT BETWEEN ? AND ?", column), args: []any{low, high}},
+			{fragment: fmt.Sprintf("%s NOT BETWEEN ? AND ?", column), args: []any{normalizeArg(low), normalizeArg(high)}},
 		},
 	}
 }
@@ -207,6 +243,7 @@ func (c *Condition) And(other *Condition) *Condition {
 		connector: "AND",
 		group:     other,
 	})
+	c.merge(other)
 	return c
 }
 
@@ -222,6 +259,37 @@ func (c *Condition) Or(other *Condition) *Condition {
 		connector: "OR",
 		group:     other,
 	})
+	c.merge(other)
+	return c
+}
+
+// merge carries the joins and search rank of other over to c.
+func (c *Condition) merge(other *Condition) {
+	for _, join := range other.joins {
+		if !slices.Contains(c.joins, join) {
+			c.joins = append(c.joins, join)
+		}
+	}
+	if c.rank == nil {
+		c.rank = other.rank
+	}
+}
+
+// IsEmpty reports whether c compiles to no SQL at all (nil, no parts, or only empty groups),
+// i.e. would match every row.
+func (c *Condition) IsEmpty() bool {
+	query, _ := c.compile(DefaultDialect)
+	return strings.TrimSpace(query) == ""
+}
+
+// MustCondition returns c, panicking when it is empty. Wrap conditions that must never match
+// every row, e.g. ones built from optional inputs before a bulk Update or Delete:
+//
+//	repo.Update(ctx, repositories.MustCondition(cond), values)
+func MustCondition(c *Condition) *Condition {
+	if c.IsEmpty() {
+		panic(ErrEmptyCondition)
+	}
 	return c
 }
 
@@ -235,7 +303,14 @@ func (c *Condition) Or(other *Condition) *Condition {
 // You typically don't need to call Build() directly -- pass the *Condition
 // straight to repository methods. This method is available for manual use
 // or interoperability with code that expects the raw map format.
+//
+// Build compiles for DefaultDialect; use BuildFor to target a specific database.
 func (c *Condition) Build() map[string]any {
+	return c.BuildFor(DefaultDialect)
+}
+
+// BuildFor compiles the condition tree like Build, for the given dialect.
+func (c *Condition) BuildFor(dialect Dialect) map[string]any {
 	if c == nil || len(c.parts) == 0 {
 		return map[string]any{
 			"query": "",
@@ -243,15 +318,43 @@ func (c *Condition) Build() map[string]any {
 		}
 	}
 
-	query, args := c.compile()
-	return map[string]any{
+	query, args := c.compile(dialect)
+	built := map[string]any{
 		"query": query,
 		"args":  args,
 	}
+	if len(c.joins) > 0 {
+		built["joins"] = c.joins
+	}
+	if c.rank != nil {
+		built["rank"] = c.rank.BuildFor(dialect)
+	}
+	return built
+}
+
+// String renders the compiled condition with its args interpolated, e.g.
+//
+//	status = 'active' AND role IN ('admin', 'editor')
+//
+// The output is meant for logs and debugging only. It is NOT escaped for execution;
+// always pass the *Condition itself (or Build()) to the repository.
+func (c *Condition) String() string {
+	query, args := c.compile(DefaultDialect)
+
+	var b strings.Builder
+	for _, ch := range query {
+		if ch == '?' && len(args) > 0 {
+			b.WriteString(formatArg(args[0]))
+			args = args[1:]
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
 }
 
 // compile recursively builds the SQL string and args from the condition tree.
-func (c *Condition) compile() (string, []any) {
+func (c *Condition) compile(dialect Dialect) (string, []any) {
 	if c == nil || len(c.parts) == 0 {
 		return "", nil
 	}
@@ -259,12 +362,12 @@ func (c *Condition) compile() (string, []any) {
 	var segments []string
 	var allArgs []any
 
-	for i, part := range c.parts {
+	for _, part := range c.parts {
 		var fragment string
 		var args []any
 
 		if part.group != nil {
-			fragment, args = part.group.compile()
+			fragment, args = part.group.compile(dialect)
 			if fragment == "" {
 				continue
 			}
@@ -272,12 +375,17 @@ func (c *Condition) compile() (string, []any) {
 			if len(part.group.parts) > 1 {
 				fragment = "(" + fragment + ")"
 			}
+		} else if part.build != nil {
+			fragment, args = part.build(dialect)
+		} else if part.render != nil {
+			fragment = part.render(dialect)
+			args = part.args
 		} else {
 			fragment = part.fragment
 			args = part.args
 		}
 
-		if i > 0 && part.connector != "" {
+		if len(segments) > 0 && part.connector != "" { // skipped empty groups don't count
 			segments = append(segments, part.connector)
 		}
 		segments = append(segments, fragment)
@@ -296,3 +404,141 @@ func newLeaf(fragment string, args ...any) *Condition {
 		},
 	}
 }
+
+// newCaseInsensitiveLike matches column against the lowercased pattern, using native
+// ILIKE on Postgres and LOWER(column) LIKE elsewhere. escaped adds the ESCAPE clause for
+// patterns built with EscapeLike.
+func newCaseInsensitiveLike(column string, negate bool, pattern string, escaped bool) *Condition {
+	not := ""
+	if negate {
+		not = "NOT "
+	}
+	return &Condition{
+		parts: []conditionPart{
+			{
+				render: func(dialect Dialect) string {
+					escape := ""
+					if escaped {
+						escape = likeEscape(dialect)
+					}
+					if dialect == DialectPostgres {
+						return fmt.Sprintf("%s %sILIKE ?%s", column, not, escape)
+					}
+					return fmt.Sprintf("LOWER(%s) %sLIKE ?%s", column, not, escape)
+				},
+				args: []any{strings.ToLower(pattern)},
+			},
+		},
+	}
+}
+
+// EscapeLike escapes \, % and _ in value with a backslash, so it matches literally inside a
+// LIKE pattern compiled with ESCAPE '\' (NotLike).
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeEscape is the ESCAPE clause declaring the backslash used by EscapeLike. MySQL string
+// literals treat the backslash as an escape character too, so it is doubled there.
+func likeEscape(dialect Dialect) string {
+	if dialect == DialectMySQL {
+		return ` ESCAPE '\\'`
+	}
+	return ` ESCAPE '\'`
+}
+
+// normalizeArg resolves pointers and driver.Valuer implementations (including pointer-receiver
+// ones) to the plain value the driver binds, so every dialect receives the same thing.
+// time.Time is left as-is: all supported drivers bind it natively.
+func normalizeArg(arg any) any {
+	if arg == nil {
+		return nil
+	}
+	if _, ok := arg.(time.Time); ok {
+		return arg
+	}
+	if valuer, ok := arg.(driver.Valuer); ok {
+		if val := reflect.ValueOf(arg); val.Kind() == reflect.Ptr && val.IsNil() {
+			return nil
+		}
+		if value, err := valuer.Value(); err == nil {
+			return value
+		}
+		return arg
+	}
+
+	val := reflect.ValueOf(arg)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		return normalizeArg(val.Elem().Interface())
+	}
+
+	// Value types whose Value() method has a pointer receiver
+	ptr := reflect.New(val.Type())
+	ptr.Elem().Set(val)
+	if valuer, ok := ptr.Interface().(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil {
+			return value
+		}
+	}
+	return arg
+}
+
+// normalizeSlice converts any slice or array into []any of normalized elements,
+// leaving []byte and non-slice values untouched.
+func normalizeSlice(values any) any {
+	if _, ok := values.(driver.Valuer); ok {
+		return values
+	}
+	val := reflect.ValueOf(values)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return values
+	}
+	if val.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+
+	items := make([]any, val.Len())
+	for i := range items {
+		items[i] = normalizeArg(val.Index(i).Interface())
+	}
+	return items
+}
+
+// formatArg renders a bind value the way it would read in SQL, for String().
+func formatArg(arg any) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []byte:
+		return "'" + strings.ReplaceAll(string(v), "'", "''") + "'"
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	case fmt.Stringer:
+		return "'" + strings.ReplaceAll(v.String(), "'", "''") + "'"
+	}
+
+	val := reflect.ValueOf(arg)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, val.Len())
+		for i := range items {
+			items[i] = formatArg(val.Index(i).Interface())
+		}
+		return strings.Join(items, ", ")
+	case reflect.Ptr:
+		if val.IsNil() {
+			return "NULL"
+		}
+		return formatArg(val.Elem().Interface())
+	case reflect.String:
+		return formatArg(val.String())
+	}
+	return fmt.Sprintf("%v", arg)
+}
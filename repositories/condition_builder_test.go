@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+)
+
+type orderStatus string
+
+func (s orderStatus) Value() (driver.Value, error) { return "status:" + string(s), nil }
+
+type priority int
+
+func (p *priority) Value() (driver.Value, error) { return int64(*p) * 10, nil }
+
+func TestGormConditionBuilder(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []configs.GormQueryField
+		query  string
+		args   []any
+	}{
+		{"binary", []configs.GormQueryField{{Operation: ">", Column: "age", Value: 18}}, "age > ?", []any{18}},
+		{"default operation", []configs.GormQueryField{{Column: "name", Value: "a"}}, "name = ?", []any{"a"}},
+		{"in", []configs.GormQueryField{{Operation: "in", Column: "id", Value: []int{1, 2}}}, "id IN (?)", []any{[]int{1, 2}}},
+		{"not in", []configs.GormQueryField{{Operation: "NOT IN", Column: "id", Value: []int{3}}}, "id NOT IN (?)", []any{[]int{3}}},
+		{"between", []configs.GormQueryField{{Operation: "BETWEEN", Column: "age", Values: []any{18, 65}}}, "age BETWEEN ? AND ?", []any{18, 65}},
+		{"not between", []configs.GormQueryField{{Operation: "not between", Column: "age", Values: []any{1, 2}}}, "age NOT BETWEEN ? AND ?", []any{1, 2}},
+		{"is null", []configs.GormQueryField{{Operation: "IS NULL", Column: "deleted_at"}}, "deleted_at IS NULL", []any{}},
+		{"is not null", []configs.GormQueryField{{Operation: " is not null ", Column: "email"}}, "email IS NOT NULL", []any{}},
+		{
+			"mixed",
+			[]configs.GormQueryField{
+				{Operation: "IS NULL", Column: "deleted_at"},
+				{Operation: "IN", Column: "role", Value: []string{"admin"}},
+				{Operation: "BETWEEN", Column: "age", Values: []any{18, 65}},
+			},
+			"deleted_at IS NULL AND role IN (?) AND age BETWEEN ? AND ?",
+			[]any{[]string{"admin"}, 18, 65},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built := GormConditionBuilder(tt.fields)
+			if built["query"] != tt.query {
+				t.Errorf("query = %q, want %q", built["query"], tt.query)
+			}
+			if !reflect.DeepEqual(built["args"], tt.args) {
+				t.Errorf("args = %#v, want %#v", built["args"], tt.args)
+			}
+		})
+	}
+}
+
+func TestValidateQueryFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []configs.GormQueryField
+		invalid bool
+	}{
+		{"between with two values", []configs.GormQueryField{{Operation: "BETWEEN", Column: "age", Values: []any{1, 2}}}, false},
+		{"between with one value", []configs.GormQueryField{{Operation: "BETWEEN", Column: "age", Values: []any{1}}}, true},
+		{"not between with three values", []configs.GormQueryField{{Operation: "NOT BETWEEN", Column: "age", Values: []any{1, 2, 3}}}, true},
+		{"between with Value only", []configs.GormQueryField{{Operation: "between", Column: "age", Value: []any{1, 2}}}, true},
+		{"is null", []configs.GormQueryField{{Operation: "IS NULL", Column: "deleted_at"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateQueryFields(tt.fields)
+			if got := errors.Is(err, dto.ErrInvalidFilter); got != tt.invalid {
+				t.Errorf("ValidateQueryFields() = %v, want invalid %v", err, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestConditionBuildFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    *Condition
+		dialect Dialect
+		query   string
+		args    []any
+	}{
+		{"eq", Eq("status", "active"), DialectDefault, "status = ?", []any{"active"}},
+		{"and", Eq("a", 1).And(Gte("b", 2)), DialectDefault, "a = ? AND b >= ?", []any{1, 2}},
+		{
+			"nested or",
+			Eq("status", "active").And(Eq("role", "admin").Or(Eq("role", "editor"))),
+			DialectDefault,
+			"status = ? AND (role = ? OR role = ?)",
+			[]any{"active", "admin", "editor"},
+		},
+		{"in", In("id", []int{1, 2}), DialectDefault, "id IN (?)", []any{[]any{1, 2}}},
+		{"is null", IsNull("deleted_at"), DialectDefault, "deleted_at IS NULL", nil},
+		{"between", Between("age", 18, 65), DialectDefault, "age BETWEEN ? AND ?", []any{18, 65}},
+		{"ilike", ILike("name", "%John%"), DialectDefault, "LOWER(name) LIKE ?", []any{"%john%"}},
+		{"ilike on postgres", ILike("name", "%John%"), DialectPostgres, "name ILIKE ?", []any{"%john%"}},
+		{"contains on postgres", Contains("name", "Jo"), DialectPostgres, "name ILIKE ?", []any{"%jo%"}},
+		{"starts with on mysql", StartsWith("name", "Jo"), DialectMySQL, "LOWER(name) LIKE ?", []any{"jo%"}},
+		{"not like", NotLike("email", "%@example.com"), DialectDefault, `email NOT LIKE ? ESCAPE '\'`, []any{"%@example.com"}},
+		{"not like on mysql", NotLike("email", "%@x.io"), DialectMySQL, `email NOT LIKE ? ESCAPE '\\'`, []any{"%@x.io"}},
+		{"not contains", NotContains("name", "Test"), DialectDefault, `LOWER(name) NOT LIKE ? ESCAPE '\'`, []any{"%test%"}},
+		{"not contains escapes wildcards", NotContains("code", `50%_a\b`), DialectDefault, `LOWER(code) NOT LIKE ? ESCAPE '\'`, []any{`%50\%\_a\\b%`}},
+		{"not contains on postgres", NotContains("name", "x"), DialectPostgres, `name NOT ILIKE ? ESCAPE '\'`, []any{"%x%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built := tt.cond.BuildFor(tt.dialect)
+			if built["query"] != tt.query {
+				t.Errorf("query = %q, want %q", built["query"], tt.query)
+			}
+			args, _ := built["args"].([]any)
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	day := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		cond *Condition
+		want string
+	}{
+		{
+			"strings numbers and a slice",
+			Eq("status", "active").And(Gte("age", 18)).And(In("role", []string{"admin", "editor"})),
+			"status = 'active' AND age >= 18 AND role IN ('admin', 'editor')",
+		},
+		{"quote", Eq("name", "O'Brien"), "name = 'O''Brien'"},
+		{"null", Eq("owner_id", nil), "owner_id = NULL"},
+		{"time", Gt("created_at", day), "created_at > '2024-01-02T03:04:05Z'"},
+		{"no args", IsNotNull("email"), "email IS NOT NULL"},
+		{"empty", &Condition{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionNormalizesArgs(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	high := priority(3)
+	tests := []struct {
+		name string
+		cond *Condition
+		args []any
+	}{
+		{"time bounds", Between("created_at", from, to), []any{from, to}},
+		{"time pointer", Between("created_at", &from, &to), []any{from, to}},
+		{"valuer enum slice", In("status", []orderStatus{"paid", "shipped"}), []any{[]any{"status:paid", "status:shipped"}}},
+		{"pointer receiver valuer", Between("priority", priority(1), &high), []any{int64(10), int64(30)}},
+		{"nil pointer", NotBetween("priority", (*priority)(nil), 5), []any{nil, 5}},
+		{"bytes are not a list", In("hash", []byte("ab")), []any{[]byte("ab")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.Build()["args"]; !reflect.DeepEqual(got, tt.args) {
+				t.Errorf("args = %#v, want %#v", got, tt.args)
+			}
+		})
+	}
+}
+
+func TestConditionIsEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		cond  *Condition
+		empty bool
+	}{
+		{"nil", nil, true},
+		{"no parts", &Condition{}, true},
+		{"empty groups", (&Condition{}).And(&Condition{}).Or(&Condition{}), true},
+		{"leaf", Eq("id", 1), false},
+		{"empty group and leaf", (&Condition{}).And(Eq("id", 1)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.IsEmpty(); got != tt.empty {
+				t.Errorf("IsEmpty() = %v, want %v", got, tt.empty)
+			}
+		})
+	}
+}
+
+func TestMustCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		cond   *Condition
+		panics bool
+	}{
+		{"empty", &Condition{}, true},
+		{"nil", nil, true},
+		{"leaf", Eq("id", 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				recovered := recover()
+				if (recovered != nil) != tt.panics {
+					t.Fatalf("panic = %v, want panic %v", recovered, tt.panics)
+				}
+				if err, ok := recovered.(error); ok && !errors.Is(err, ErrEmptyCondition) {
+					t.Errorf("panic = %v, want ErrEmptyCondition", err)
+				}
+			}()
+			if got := MustCondition(tt.cond); got != tt.cond {
+				t.Errorf("MustCondition() = %v, want its argument", got)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"plain", "plain"},
+		{"100%", `100\%`},
+		{"a_b", `a\_b`},
+		{`c:\dir`, `c:\\dir`},
+	}
+	for _, tt := range tests {
+		if got := EscapeLike(tt.value); got != tt.want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// ErrInvalidCursor is returned when a cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorToken is the payload behind an opaque cursor: the primary key of the row the page
+// starts after, and the direction to read in.
+type cursorToken struct {
+	Key      any  `json:"k"`
+	Backward bool `json:"b,omitempty"`
+}
+
+func encodeCursor(key any, backward bool) (*string, error) {
+	payload, err := json.Marshal(cursorToken{Key: key, Backward: backward})
+	if err != nil {
+		return nil, err
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(payload)
+	return &cursor, nil
+}
+
+func decodeCursor(cursor string) (*cursorToken, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var token cursorToken
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&token); err != nil || token.Key == nil {
+		return nil, ErrInvalidCursor
+	}
+	// keep integer keys exact instead of going through float64
+	if number, ok := token.Key.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			token.Key = i
+		} else if f, err := number.Float64(); err == nil {
+			token.Key = f
+		}
+	}
+	return &token, nil
+}
+
+// FindAllWithCursor returns a page of at most limit entities ordered by primary key, newest
+// first, starting after cursor (an empty cursor starts at the beginning). Unlike offset
+// pagination, pages stay stable while rows are inserted and deep pages cost the same as
+// the first one. limit is normalized with dto.NormalizePagination.
+func (r *GormRepository[T]) FindAllWithCursor(ctx context.Context, conditions any, cursor string, limit int, config *configs.GormConfig, args ...any) (*models.CursorResponse[T], error) {
+	var token *cursorToken
+	if cursor != "" {
+		var err error
+		if token, err = decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, errors.New("cursor pagination requires a primary key")
+	}
+	primaryKey := s.PrioritizedPrimaryField
+
+	_, limit = dto.NormalizePagination(1, limit)
+	backward := token != nil && token.Backward
+	column := clause.Column{Table: clause.CurrentTable, Name: primaryKey.DBName}
+
+	query := r.BuildQueryConfig(ctx, conditions, r.ResolveListConfig(config))
+	if token != nil {
+		if backward {
+			query = query.Where(clause.Gt{Column: column, Value: token.Key})
+		} else {
+			query = query.Where(clause.Lt{Column: column, Value: token.Key})
+		}
+	}
+	// fetch one extra row to learn whether another page follows
+	query = query.Order(clause.OrderByColumn{Column: column, Desc: !backward}).Limit(limit + 1)
+
+	var entities []T
+	if err := query.Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	more := len(entities) > limit
+	if more {
+		entities = entities[:limit]
+	}
+	if backward {
+		slices.Reverse(entities)
+	}
+
+	response := &models.CursorResponse[T]{Data: entities}
+	if len(entities) == 0 {
+		return response, nil
+	}
+	keyOf := func(entity *T) any {
+		key, _ := primaryKey.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		return key
+	}
+
+	// forward: more rows after the page; backward: the page we came from is always next
+	if more || backward {
+		if response.NextCursor, err = encodeCursor(keyOf(&entities[len(entities)-1]), false); err != nil {
+			return nil, err
+		}
+	}
+	// backward: more rows before the page; forward: anything but the first page has a previous one
+	if (backward && more) || (!backward && token != nil) {
+		if response.PrevCursor, err = encodeCursor(keyOf(&entities[0]), true); err != nil {
+			return nil, err
+		}
+	}
+	response.HasMore = response.NextCursor != nil
+	return response, nil
+}
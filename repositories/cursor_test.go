@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      any
+		backward bool
+		want     any
+	}{
+		{"integer key stays exact", int64(9007199254740993), false, int64(9007199254740993)},
+		{"uint key", uint(42), true, int64(42)},
+		{"string key", "0190f0c2-7c1e-7d2e-a3c5-3f1e2d4c5b6a", false, "0190f0c2-7c1e-7d2e-a3c5-3f1e2d4c5b6a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := encodeCursor(tt.key, tt.backward)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := decodeCursor(*cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(token.Key, tt.want) || token.Backward != tt.backward {
+				t.Errorf("decoded %#v, %v; want %#v, %v", token.Key, token.Backward, tt.want, tt.backward)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "e30", "bnVsbA"} { // "{}", "null"
+		if _, err := decodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestFindAllWithCursor(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 7)
+	ctx := context.Background()
+
+	ids := func(page *models.CursorResponse[product]) []uint {
+		var ids []uint
+		for _, p := range page.Data {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	// forward to the end, then back to the start
+	steps := []struct {
+		name    string
+		next    bool // follow NextCursor (PrevCursor otherwise)
+		ids     []uint
+		hasNext bool
+		hasPrev bool
+	}{
+		{"first page", true, []uint{7, 6, 5}, true, false},
+		{"second page", true, []uint{4, 3, 2}, true, true},
+		{"last page", true, []uint{1}, false, true},
+		{"back to the second page", false, []uint{4, 3, 2}, true, true},
+		{"back to the first page", false, []uint{7, 6, 5}, true, false},
+	}
+	var page *models.CursorResponse[product]
+	for i, step := range steps {
+		cursor := ""
+		if i > 0 {
+			if step.next {
+				cursor = *page.NextCursor
+			} else {
+				cursor = *page.PrevCursor
+			}
+		}
+		var err error
+		if page, err = r.FindAllWithCursor(ctx, nil, cursor, 3, nil); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := ids(page); !reflect.DeepEqual(got, step.ids) {
+			t.Errorf("%s: ids = %v, want %v", step.name, got, step.ids)
+		}
+		if (page.NextCursor != nil) != step.hasNext || page.HasMore != step.hasNext || (page.PrevCursor != nil) != step.hasPrev {
+			t.Errorf("%s: next %v, prev %v; want %v, %v", step.name, page.NextCursor != nil, page.PrevCursor != nil, step.hasNext, step.hasPrev)
+		}
+	}
+
+	if _, err := r.FindAllWithCursor(ctx, nil, "garbage!", 3, nil); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("FindAllWithCursor() error = %v, want ErrInvalidCursor", err)
+	}
+}
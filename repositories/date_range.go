@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aghiadodeh/go-crud/dto"
+)
+
+// dateRangeCondition returns the predicate of a GormFilterTypeDateRange column for the given
+// bounds, either of which may be missing (nil or ""). A date-only `to` includes that whole
+// day, so the upper bound becomes the start of the next day.
+func dateRangeCondition(column string, from, to any) (string, []any, error) {
+	start, _, hasStart, err := parseDateBound(from)
+	if err != nil {
+		return "", nil, err
+	}
+	end, dateOnly, hasEnd, err := parseDateBound(to)
+	if err != nil {
+		return "", nil, err
+	}
+
+	upper := fmt.Sprintf("%s <= ?", column)
+	if dateOnly {
+		end = end.AddDate(0, 0, 1)
+		upper = fmt.Sprintf("%s < ?", column)
+	}
+
+	switch {
+	case hasStart && hasEnd:
+		return fmt.Sprintf("(%s >= ? AND %s)", column, upper), []any{start, end}, nil
+	case hasStart:
+		return fmt.Sprintf("%s >= ?", column), []any{start}, nil
+	case hasEnd:
+		return upper, []any{end}, nil
+	}
+	return "", nil, nil
+}
+
+// parseDateBound reads a date range bound: a time.Time, or a string in RFC 3339 or
+// YYYY-MM-DD (UTC midnight) format.
+func parseDateBound(value any) (bound time.Time, dateOnly bool, ok bool, err error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, false, false, nil
+	case *string:
+		if v == nil {
+			return time.Time{}, false, false, nil
+		}
+		return parseDateBound(*v)
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false, false, nil
+		}
+		return *v, false, true, nil
+	case time.Time:
+		return v, false, true, nil
+	case string:
+		if v == "" {
+			return time.Time{}, false, false, nil
+		}
+		if t, err := time.Parse(time.DateOnly, v); err == nil {
+			return t, true, true, nil
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, false, true, nil
+		}
+	}
+	return time.Time{}, false, false, fmt.Errorf("%w: %v is not a date", dto.ErrInvalidFilter, value)
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// recordingLogger passes every statement to a request's SQLRecorder, then to the
+// database's own logger.
+type recordingLogger struct {
+	logger.Interface
+	recorder *middlewares.SQLRecorder
+}
+
+func (l recordingLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return recordingLogger{Interface: l.Interface.LogMode(level), recorder: l.recorder}
+}
+
+func (l recordingLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, rows := fc()
+	l.recorder.Record(sql)
+	l.Interface.Trace(ctx, begin, func() (string, int64) { return sql, rows }, err)
+}
+
+// withSQLRecorder makes db record its statements when ctx carries a SQLRecorder.
+func withSQLRecorder(ctx context.Context, db *gorm.DB) *gorm.DB {
+	recorder := middlewares.GetSQLRecorder(ctx)
+	if recorder == nil {
+		return db
+	}
+	return db.Session(&gorm.Session{Logger: recordingLogger{Interface: db.Logger, recorder: recorder}})
+}
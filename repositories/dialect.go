@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+// Dialect identifies the SQL flavor conditions are compiled for.
+// Values match the names reported by GORM dialectors.
+type Dialect string
+
+const (
+	DialectDefault   Dialect = ""
+	DialectPostgres  Dialect = "postgres"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// DefaultDialect is used when a Condition is compiled outside a repository (Build, String).
+// Repositories always compile with their own database's dialect.
+var DefaultDialect = DialectDefault
+
+// Dialect reports the dialect of the repository's database.
+func (r *GormRepository[T]) Dialect() Dialect {
+	if r.DB == nil || r.DB.Dialector == nil {
+		return DefaultDialect
+	}
+	return Dialect(r.DB.Dialector.Name())
+}
+
+// orderBy returns the ORDER BY item sorting column in dir with NULLs placed as requested.
+// Postgres and SQLite support NULLS FIRST/LAST; MySQL sorts on `column IS NULL` first and
+// other databases on an equivalent CASE.
+func (d Dialect) orderBy(column, dir string, nulls configs.GormSortNulls) string {
+	order := fmt.Sprintf("%s %s", column, dir)
+	if nulls != configs.GormSortNullsFirst && nulls != configs.GormSortNullsLast {
+		return order
+	}
+
+	switch d {
+	case DialectPostgres, DialectSQLite:
+		if nulls == configs.GormSortNullsFirst {
+			return order + " NULLS FIRST"
+		}
+		return order + " NULLS LAST"
+	case DialectMySQL:
+		if nulls == configs.GormSortNullsFirst {
+			return fmt.Sprintf("%s IS NULL DESC, %s", column, order)
+		}
+		return fmt.Sprintf("%s IS NULL, %s", column, order)
+	}
+	if nulls == configs.GormSortNullsFirst {
+		return fmt.Sprintf("CASE WHEN %s IS NULL THEN 0 ELSE 1 END, %s", column, order)
+	}
+	return fmt.Sprintf("CASE WHEN %s IS NULL THEN 1 ELSE 0 END, %s", column, order)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// renamedDialector is SQLite reporting itself as another database, to see the SQL the
+// repository builds for it.
+type renamedDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d renamedDialector) Name() string { return d.name }
+
+func TestDialectOrderBy(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		nulls   configs.GormSortNulls
+		want    string
+	}{
+		{DialectPostgres, "", "name asc"},
+		{DialectPostgres, configs.GormSortNullsFirst, "name asc NULLS FIRST"},
+		{DialectSQLite, configs.GormSortNullsLast, "name asc NULLS LAST"},
+		{DialectMySQL, configs.GormSortNullsFirst, "name IS NULL DESC, name asc"},
+		{DialectMySQL, configs.GormSortNullsLast, "name IS NULL, name asc"},
+		{DialectSQLServer, configs.GormSortNullsFirst, "CASE WHEN name IS NULL THEN 0 ELSE 1 END, name asc"},
+		{DialectDefault, configs.GormSortNullsLast, "CASE WHEN name IS NULL THEN 1 ELSE 0 END, name asc"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dialect)+"/"+string(tt.nulls), func(t *testing.T) {
+			if got := tt.dialect.orderBy("name", "asc", tt.nulls); got != tt.want {
+				t.Errorf("orderBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFullTextSearchDialects(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		strategy configs.GormSearchStrategy
+		want     string
+	}{
+		{"mysql", DialectMySQL, configs.GormSearchStrategyFullText, `WHERE (MATCH(name) AGAINST("go" IN BOOLEAN MODE))`},
+		{"postgres", DialectPostgres, configs.GormSearchStrategyFullText, `WHERE (to_tsvector(name) @@ plainto_tsquery("go"))`},
+		{"other databases keep like", DialectSQLite, configs.GormSearchStrategyFullText, `WHERE (lower(name) LIKE "%go%")`},
+		{"like by default", DialectPostgres, "", `WHERE (lower(name) LIKE "%go%")`},
+		{"like", DialectMySQL, configs.GormSearchStrategyLike, `WHERE (lower(name) LIKE "%go%")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialector := renamedDialector{Dialector: sqlite.Open(":memory:"), name: string(tt.dialect)}
+			db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard, DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewGormRepository[product](db, &configs.GormConfig{
+				Searchable:     configs.SearchColumns("name"),
+				SearchStrategy: tt.strategy,
+			}, "products")
+			recorder := &middlewares.SQLRecorder{}
+			ctx := middlewares.WithSQLRecorder(context.Background(), recorder)
+
+			conditions, err := r.QueryBuilder(ctx, &dto.BaseFilterDto{Search: ptr("Go")}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.FindAll(ctx, conditions, &dto.BaseFilterDto{}, nil); err != nil {
+				t.Fatal(err)
+			}
+			queries := recorder.Queries()
+			if len(queries) != 1 || !strings.Contains(queries[0], tt.want) {
+				t.Errorf("queries = %q, want one containing %q", queries, tt.want)
+			}
+		})
+	}
+}
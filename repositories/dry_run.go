@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/middlewares"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// PreviewDelete reports what Delete(ctx, conditions) would do -- the rows it would delete and
+// its SQL -- without deleting anything. CascadeSoftDelete statements are not part of the SQL,
+// and model hooks (BeforeDelete...) don't run.
+func (r *GormRepository[T]) PreviewDelete(ctx context.Context, conditions any, args ...any) (*models.DryRun, error) {
+	if emptyConditions(conditions) && !r.config().AllowFullTableDelete {
+		return nil, ErrFullTableDelete
+	}
+	where := func(db *gorm.DB) *gorm.DB {
+		if emptyConditions(conditions) {
+			return db.Session(&gorm.Session{AllowGlobalUpdate: true})
+		}
+		return r.where(db, conditions)
+	}
+
+	var affected int64
+	if err := r.excludeDeleted(where(r.db(ctx).Model(new(T)))).Count(&affected).Error; err != nil {
+		return nil, err
+	}
+
+	recorder := &middlewares.SQLRecorder{}
+	dryRun := r.db(middlewares.WithSQLRecorder(ctx, recorder)).Session(&gorm.Session{DryRun: true, SkipHooks: true})
+	if _, err := r.softDelete(where(dryRun.Model(new(T)))); err != nil {
+		return nil, err
+	}
+	return &models.DryRun{Affected: affected, SQL: recorder.Queries()}, nil
+}
+
+// PreviewUpdate reports what Update(ctx, conditions, updateDto) would do -- the rows it would
+// update and its SQL -- without updating anything or running model hooks. Empty conditions
+// fail with ErrFullTableUpdate, as they would in Update.
+func (r *GormRepository[T]) PreviewUpdate(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error) {
+	if emptyConditions(conditions) && !r.config().AllowFullTableUpdate {
+		return nil, ErrFullTableUpdate
+	}
+	var affected int64
+	if err := r.BuildQueryConditions(ctx, conditions, nil).Count(&affected).Error; err != nil {
+		return nil, err
+	}
+
+	recorder := &middlewares.SQLRecorder{}
+	query := r.BuildQueryConfig(middlewares.WithSQLRecorder(ctx, recorder), conditions, nil)
+	query = r.omitNotAllowed(query.Session(&gorm.Session{DryRun: true, SkipHooks: true}), r.config().UpdatableColumns)
+	if err := query.Updates(updateDto).Error; err != nil {
+		return nil, err
+	}
+	return &models.DryRun{Affected: affected, SQL: recorder.Queries()}, nil
+}
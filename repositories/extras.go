@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+var extrasType = reflect.TypeOf(models.Extras(nil))
+
+// extrasIndex returns the index of T's models.Extras field, or nil when it has none.
+func extrasIndex[T any]() []int {
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(new(T)).Elem()) {
+		if field.IsExported() && field.Type == extrasType {
+			return field.Index
+		}
+	}
+	return nil
+}
+
+// customSelect reports whether query selects its own columns (a SelectHandler or Select).
+func customSelect(query *gorm.DB) bool {
+	// the clause may only hold a SQL comment (SQLComment)
+	selectClause, hasSelect := query.Statement.Clauses["SELECT"]
+	return (hasSelect && selectClause.Expression != nil) || len(query.Statement.Selects) > 0
+}
+
+// find runs query into dest. When T has a models.Extras field and the query selects its own
+// columns, the rows are scanned by hand so the computed columns are kept.
+func (r *GormRepository[T]) find(ctx context.Context, query *gorm.DB, dest *[]T) error {
+	if extrasIndex[T]() == nil || !customSelect(query) {
+		return query.Find(dest).Error
+	}
+
+	// relations can't be preloaded into maps: they are loaded once the entities exist
+	preloads := query.Statement.Preloads
+	query.Statement.Preloads = nil
+
+	var rows []map[string]any
+	if err := query.Find(&rows).Error; err != nil {
+		return err
+	}
+	entities, err := r.decodeRows(ctx, rows)
+	if err != nil {
+		return err
+	}
+	if len(preloads) > 0 && len(entities) > 0 {
+		if err := r.preloadInto(ctx, query, preloads, entities); err != nil {
+			return err
+		}
+	}
+	*dest = entities
+	return nil
+}
+
+// first is find for a single row, ordered by primary key like gorm's First.
+func (r *GormRepository[T]) first(ctx context.Context, query *gorm.DB, dest *T) error {
+	if extrasIndex[T]() == nil || !customSelect(query) {
+		return query.First(dest).Error
+	}
+
+	var entities []T
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}}).Limit(1)
+	if err := r.find(ctx, query, &entities); err != nil {
+		return err
+	}
+	if len(entities) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	*dest = entities[0]
+	return nil
+}
+
+// decodeRows builds entities from rows scanned into maps. Columns without a field on T go to
+// its models.Extras field when it has one and are dropped otherwise.
+func (r *GormRepository[T]) decodeRows(ctx context.Context, rows []map[string]any) ([]T, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	index := extrasIndex[T]()
+	entities := make([]T, len(rows))
+	for i, row := range rows {
+		entity := reflect.ValueOf(&entities[i]).Elem()
+		for column, value := range row {
+			if field := s.LookUpField(column); field != nil && field.Readable {
+				if err := field.Set(ctx, entity, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if index == nil {
+				continue
+			}
+			target, err := entity.FieldByIndexErr(index)
+			if err != nil { // Extras sits behind a nil embedded pointer
+				continue
+			}
+			extras := target.Addr().Interface().(*models.Extras)
+			if *extras == nil {
+				*extras = models.Extras{}
+			}
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			(*extras)[column] = value
+		}
+	}
+	return entities, nil
+}
+
+// preloadInto loads the preloaded relations of entities with a query by primary key and
+// copies them over.
+func (r *GormRepository[T]) preloadInto(ctx context.Context, query *gorm.DB, preloads map[string][]any, entities []T) error {
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("%s has no primary key to load relations by", s.Name)
+	}
+
+	relations := map[string]*schema.Relationship{}
+	for name := range preloads {
+		name = strings.SplitN(name, ".", 2)[0]
+		if name == clause.Associations {
+			for relationName, relation := range s.Relationships.Relations {
+				relations[relationName] = relation
+			}
+		} else if relation, ok := s.Relationships.Relations[name]; ok {
+			relations[name] = relation
+		}
+	}
+
+	ids := make([]any, len(entities))
+	for i := range entities {
+		ids[i], _ = pk.ValueOf(ctx, reflect.ValueOf(&entities[i]).Elem())
+	}
+
+	tx := r.db(ctx).Model(new(T))
+	if query.Statement.Unscoped {
+		tx = tx.Unscoped()
+	}
+	tx.Statement.Preloads = preloads
+
+	var loaded []T
+	column := clause.Column{Table: clause.CurrentTable, Name: pk.DBName}
+	if err := tx.Where(clause.IN{Column: column, Values: ids}).Find(&loaded).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[string]reflect.Value, len(loaded))
+	for i := range loaded {
+		value := reflect.ValueOf(&loaded[i]).Elem()
+		id, _ := pk.ValueOf(ctx, value)
+		byID[fmt.Sprint(id)] = value
+	}
+	for i, id := range ids {
+		source, ok := byID[fmt.Sprint(id)]
+		if !ok {
+			continue
+		}
+		entity := reflect.ValueOf(&entities[i]).Elem()
+		for _, relation := range relations {
+			relation.Field.ReflectValueOf(ctx, entity).Set(relation.Field.ReflectValueOf(ctx, source))
+		}
+	}
+	return nil
+}
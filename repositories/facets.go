@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+)
+
+// Facets counts the entities matching conditions per value of each facet column, e.g. how
+// many of the filtered products are in each category:
+//
+//	facets, err := repo.Facets(ctx, conditions, []string{"category", "brand"})
+//	// {"category": {"books": 12, "games": 3}, "brand": {...}}
+//
+// Values are keyed by their text form; NULLs are not counted. Columns are validated against
+// Selectable. Each facet is one grouped count query.
+func (r *GormRepository[T]) Facets(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error) {
+	if err := validateColumns(facetColumns, r.config().Selectable); err != nil {
+		return nil, err
+	}
+
+	facets := make(map[string]map[string]int64, len(facetColumns))
+	for _, column := range facetColumns {
+		var rows []struct {
+			Value string `gorm:"column:facet_value"`
+			Count int64  `gorm:"column:facet_count"`
+		}
+		err := r.BuildQueryConditions(ctx, conditions, r.config()).
+			Model(new(T)).
+			Select(fmt.Sprintf("%s AS facet_value, COUNT(*) AS facet_count", column)).
+			Where(fmt.Sprintf("%s IS NOT NULL", column)).
+			Group(column).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		counts := make(map[string]int64, len(rows))
+		for _, row := range rows {
+			counts[row.Value] = row.Count
+		}
+		facets[column] = counts
+	}
+	return facets, nil
+}
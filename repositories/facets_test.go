@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+func TestFacets(t *testing.T) {
+	tests := []struct {
+		name       string
+		selectable []string
+		conditions any
+		columns    []string
+		want       map[string]map[string]int64
+		wantErr    bool
+	}{
+		{
+			name:    "one facet",
+			columns: []string{"status"},
+			want:    map[string]map[string]int64{"status": {"active": 3, "draft": 2}},
+		},
+		{
+			name:       "filtered",
+			conditions: Gt("price", 2),
+			columns:    []string{"status", "category_id"},
+			want:       map[string]map[string]int64{"status": {"active": 2, "draft": 1}, "category_id": {"1": 2}},
+		},
+		{name: "not selectable", selectable: []string{"status"}, columns: []string{"name"}, wantErr: true},
+		{name: "expression", columns: []string{"status, (SELECT 1)"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{Selectable: tt.selectable})
+			seedProducts(t, r, 5)
+			r.DB.Model(&product{}).Where("id IN ?", []int{4, 5}).Update("category_id", 1) // the others stay NULL
+
+			facets, err := r.Facets(context.Background(), tt.conditions, tt.columns)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Facets() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(facets, tt.want) {
+				t.Errorf("Facets() = %v, want %v", facets, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx whose FindOne and FindOneByPK load only fields
+// (e.g. from ?fields=id,name), like GormConfig.Fields. Config Fields take precedence.
+func ContextWithFields(ctx context.Context, fields []string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func contextFields(ctx context.Context) []string {
+	fields, _ := ctx.Value(fieldsKey{}).([]string)
+	return fields
+}
+
+// selectFields restricts a single-entity read to the Fields of config (or of ctx), validated
+// against Selectable. Without fields the query is returned unchanged.
+func (r *GormRepository[T]) selectFields(ctx context.Context, query *gorm.DB, config *configs.GormConfig) *gorm.DB {
+	if config == nil {
+		config = r.config()
+	}
+	fields := config.Fields
+	if len(fields) == 0 {
+		fields = contextFields(ctx)
+	}
+	if len(fields) == 0 {
+		return query
+	}
+	if err := validateColumns(fields, config.Selectable); err != nil {
+		query.AddError(fmt.Errorf("%w: %v", ErrInvalidColumn, err))
+		return query
+	}
+	return query.Select(fields)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFullTableDelete is returned by Delete when the conditions would match every row and
+// GormConfig.AllowFullTableDelete is not set. Use DeleteAll or Truncate when that is intended.
+var ErrFullTableDelete = errors.New("refusing to delete without conditions")
+
+// ErrFullTableUpdate is returned by Update when the conditions would match every row and
+// GormConfig.AllowFullTableUpdate is not set.
+var ErrFullTableUpdate = errors.New("refusing to update without conditions")
+
+// ErrEmptyCondition is the panic value of MustCondition.
+var ErrEmptyCondition = errors.New("empty condition")
+
+// DeleteAll deletes every row of T, honoring soft delete and CascadeSoftDelete like Delete.
+func (r *GormRepository[T]) DeleteAll(ctx context.Context) error {
+	_, err := r.deleteAll(ctx)
+	return err
+}
+
+func (r *GormRepository[T]) deleteAll(ctx context.Context) (int64, error) {
+	return r.deleteWhere(r.db(ctx), func(db *gorm.DB) *gorm.DB {
+		return db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	})
+}
+
+// Truncate permanently removes every row of the table, bypassing soft delete and hooks.
+// SQLite has no TRUNCATE, so it runs an unconditional DELETE there.
+func (r *GormRepository[T]) Truncate(ctx context.Context) error {
+	table := clause.Table{Name: r.TableName}
+	if r.Dialect() == DialectSQLite {
+		return r.db(ctx).Exec("DELETE FROM ?", table).Error
+	}
+	return r.db(ctx).Exec("TRUNCATE TABLE ?", table).Error
+}
+
+// emptyConditions reports whether conditions would not narrow a query: nil, an empty
+// Condition, map, slice or string, a condition map with an empty query, or a zero struct.
+// The filter added by excludeDeleted is a WHERE clause of its own, which defeats GORM's
+// ErrMissingWhereClause guard, so Update and Delete check their conditions with this first.
+func emptyConditions(conditions any) bool {
+	switch c := conditions.(type) {
+	case nil:
+		return true
+	case *Condition:
+		return c.IsEmpty()
+	case map[string]any:
+		if query, ok := c["query"]; ok {
+			return emptyConditions(query)
+		}
+		return len(c) == 0
+	}
+
+	value := reflect.ValueOf(conditions)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return true
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Struct:
+		return value.IsZero()
+	}
+	return false
+}
@@ -1,13 +1,22 @@
 package repositories
 
 import (
+	"cmp"
 	"context"
+	"encoding"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 
 	"github.com/aghiadodeh/go-crud/configs"
 	"github.com/aghiadodeh/go-crud/dto"
@@ -19,80 +28,405 @@ type GormRepository[T any] struct {
 	DB        *gorm.DB
 	Config    *configs.GormConfig
 	TableName string
+
+	scopes []func(*gorm.DB) *gorm.DB
 }
 
 func NewGormRepository[T any](db *gorm.DB, config *configs.GormConfig, tableName string) *GormRepository[T] {
 	return &GormRepository[T]{DB: db, Config: config, TableName: tableName}
 }
 
+// config returns the repository configuration; a repository built without one behaves as
+// if given an empty GormConfig.
+func (r *GormRepository[T]) config() *configs.GormConfig {
+	if r.Config == nil {
+		return &configs.GormConfig{}
+	}
+	return r.Config
+}
+
+// WithScopes returns a copy of the repository whose queries built from conditions (reads,
+// counts, Update) also apply scopes, after the config-driven clauses:
+//
+//	active := repo.WithScopes(func(db *gorm.DB) *gorm.DB { return db.Where("active = ?", true) })
+//	users, err := active.FindAll(ctx, nil, filter, nil)
+func (r *GormRepository[T]) WithScopes(scopes ...func(*gorm.DB) *gorm.DB) *GormRepository[T] {
+	scoped := *r
+	scoped.scopes = append(slices.Clip(r.scopes), scopes...)
+	return &scoped
+}
+
+// NewGormRepositoryFromModel is NewGormRepository with the table name GORM derives for T:
+// its TableName() method when it has one, the naming strategy otherwise.
+func NewGormRepositoryFromModel[T any](db *gorm.DB, config *configs.GormConfig) (*GormRepository[T], error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	if stmt.Table == "" {
+		return nil, fmt.Errorf("could not derive a table name for %T", new(T))
+	}
+	return NewGormRepository[T](db, config, stmt.Table), nil
+}
+
 func (r *GormRepository[T]) Create(ctx context.Context, createDto any, args ...any) (any, error) {
 	entity, ok := createDto.(T)
 	if !ok {
 		return "", fmt.Errorf("invalid type passed to Create: expected %T", entity)
 	}
+	if err := r.assignID(ctx, &entity); err != nil {
+		return "", err
+	}
+	if err := r.checkReferences(ctx, entity); err != nil {
+		return "", err
+	}
+	if err := r.resolveTrashedDuplicates(ctx, entity); err != nil {
+		return "", err
+	}
 
-	err := r.DB.WithContext(ctx).Model(new(T)).Create(&entity).Error
+	err := r.retryWrite(ctx, func() error {
+		return r.omitNotAllowed(r.db(ctx).Model(new(T)), r.config().CreatableColumns).Create(&entity).Error
+	})
 	if err != nil {
 		return "", err
 	}
 
-	// Assuming ID is a string field
-	val := reflect.ValueOf(entity)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+	return extractID(entity)
+}
+
+// BulkCreate inserts all entities in batches of CreateBatchSize rows, in a single
+// transaction, and returns their ids in insertion order.
+func (r *GormRepository[T]) BulkCreate(ctx context.Context, createDto []any, args ...any) ([]string, error) {
+	entities, err := r.bulkEntities(ctx, createDto)
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return []string{}, nil
 	}
 
-	idField := val.FieldByName("ID")
-	if !idField.IsValid() {
-		return "", fmt.Errorf("ID field not found on entity")
+	if err := r.createInBatches(ctx, &entities); err != nil {
+		return nil, err
 	}
-	switch idField.Kind() {
-	case reflect.String:
-		return idField.String(), nil
-	case reflect.Int, reflect.Int64:
-		return idField.Int(), nil
-	case reflect.Uint, reflect.Uint64:
-		return idField.Uint(), nil
-	default:
-		return "", fmt.Errorf("unsupported ID type: %s", idField.Kind())
+
+	ids := make([]string, len(entities))
+	for i, entity := range entities {
+		id, err := extractID(entity)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = fmt.Sprint(id)
 	}
+	return ids, nil
 }
 
-func (r *GormRepository[T]) BulkCreate(ctx context.Context, createDto []any, args ...any) ([]string, error) {
-	var entities []T
-	if err := r.DB.WithContext(ctx).Model(&entities).Create(createDto).Error; err != nil {
+// BulkError reports why the item at Index of a BulkCreatePartial payload wasn't created.
+type BulkError struct {
+	Index int
+	Err   error
+}
+
+func (e BulkError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e BulkError) Unwrap() error {
+	return e.Err
+}
+
+// BulkCreatePartial inserts the entities one by one, each under its own savepoint, so
+// invalid rows (constraint violations, wrong types...) are skipped instead of aborting the
+// import. It returns the created entities and a BulkError per skipped item; err is only
+// set when the surrounding transaction itself fails, or ctx is cancelled mid-import (nothing
+// is kept then). Use BulkCreate for all-or-nothing.
+func (r *GormRepository[T]) BulkCreatePartial(ctx context.Context, createDto []any, args ...any) ([]T, []BulkError, error) {
+	created := make([]T, 0, len(createDto))
+	var failures []BulkError
+
+	err := r.db(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, item := range createDto {
+			// a cancelled context would otherwise be reported as a failure of every remaining item
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			entity, ok := item.(T)
+			if !ok {
+				failures = append(failures, BulkError{Index: i, Err: fmt.Errorf("invalid type: expected %T", entity)})
+				continue
+			}
+			if err := r.assignID(ctx, &entity); err != nil {
+				failures = append(failures, BulkError{Index: i, Err: err})
+				continue
+			}
+			txCtx := ContextWithTx(ctx, tx)
+			if err := r.checkReferences(txCtx, entity); err != nil {
+				failures = append(failures, BulkError{Index: i, Err: err})
+				continue
+			}
+			if err := r.resolveTrashedDuplicates(txCtx, entity); err != nil {
+				failures = append(failures, BulkError{Index: i, Err: err})
+				continue
+			}
+
+			err := tx.Transaction(func(savepoint *gorm.DB) error {
+				return r.omitNotAllowed(savepoint.Model(new(T)), r.config().CreatableColumns).Create(&entity).Error
+			})
+			if err != nil {
+				failures = append(failures, BulkError{Index: i, Err: err})
+				continue
+			}
+			created = append(created, entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, failures, nil
+}
+
+// bulkEntities converts a bulk payload to entities, assigning generated ids, checking their
+// references and applying TrashedUniqueStrategy.
+func (r *GormRepository[T]) bulkEntities(ctx context.Context, createDto []any) ([]T, error) {
+	entities := make([]T, 0, len(createDto))
+	for _, item := range createDto {
+		entity, ok := item.(T)
+		if !ok {
+			return nil, fmt.Errorf("invalid type passed to bulk create: expected %T", entity)
+		}
+		if err := r.assignID(ctx, &entity); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	if err := r.checkReferences(ctx, entities...); err != nil {
+		return nil, err
+	}
+	if err := r.resolveTrashedDuplicates(ctx, entities...); err != nil {
 		return nil, err
 	}
+	return entities, nil
+}
+
+// createInBatches inserts entities CreateBatchSize rows per statement, all-or-nothing.
+func (r *GormRepository[T]) createInBatches(ctx context.Context, entities *[]T, clauses ...clause.Expression) error {
+	return r.db(ctx).Transaction(func(tx *gorm.DB) error {
+		query := r.omitNotAllowed(tx.Model(new(T)), r.config().CreatableColumns)
+		return query.Clauses(clauses...).CreateInBatches(entities, r.config().BatchSize()).Error
+	})
+}
 
-	var ids []string
-	if err := r.DB.WithContext(ctx).Model(&entities).Select("id").Find(&ids).Error; err != nil {
+// createReturning inserts entities CreateBatchSize rows per INSERT ... RETURNING, all-or-nothing,
+// and overwrites them with the returned rows. Each batch is copied into its own slice:
+// GORM scans RETURNING * by resetting the destination slice, which the sub-slices
+// CreateInBatches hands to it can't do.
+func (r *GormRepository[T]) createReturning(ctx context.Context, entities []T) error {
+	return r.db(ctx).Transaction(func(tx *gorm.DB) error {
+		offset := 0
+		for chunk := range slices.Chunk(entities, r.config().BatchSize()) {
+			batch := slices.Clone(chunk)
+			query := r.omitNotAllowed(tx.Model(new(T)), r.config().CreatableColumns)
+			if err := query.Clauses(clause.Returning{}).Create(&batch).Error; err != nil {
+				return err
+			}
+			offset += copy(entities[offset:], batch)
+		}
+		return nil
+	})
+}
+
+// BulkCreateReturning inserts all entities and returns them with database-populated fields
+// (generated IDs, defaults, timestamps). Postgres fills them with INSERT ... RETURNING;
+// other databases re-read the inserted rows by id (IN queries of IDChunkSize ids).
+func (r *GormRepository[T]) BulkCreateReturning(ctx context.Context, createDto []any, args ...any) ([]T, error) {
+	entities, err := r.bulkEntities(ctx, createDto)
+	if err != nil {
 		return nil, err
 	}
+	if len(entities) == 0 {
+		return entities, nil
+	}
 
-	return ids, nil
+	if r.Dialect() == DialectPostgres {
+		if err := r.createReturning(ctx, entities); err != nil {
+			return nil, err
+		}
+		return entities, nil
+	}
+
+	if err := r.createInBatches(ctx, &entities); err != nil {
+		return nil, err
+	}
+
+	ids := make([]any, len(entities))
+	for i, entity := range entities {
+		id, err := extractID(entity)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	var created []T
+	for chunk := range slices.Chunk(ids, r.config().ChunkSize()) {
+		var batch []T
+		if err := r.db(ctx).Model(new(T)).Where("id IN ?", chunk).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		created = append(created, batch...)
+	}
+
+	// Return the rows in insertion order
+	byID := make(map[string]T, len(created))
+	for _, entity := range created {
+		id, err := extractID(entity)
+		if err != nil {
+			return nil, err
+		}
+		byID[fmt.Sprint(id)] = entity
+	}
+	for i, id := range ids {
+		if entity, ok := byID[fmt.Sprint(id)]; ok {
+			entities[i] = entity
+		}
+	}
+	return entities, nil
 }
 
 func (r *GormRepository[T]) UpdateByPK(ctx context.Context, id any, updateDto any, args ...any) error {
-	return r.DB.WithContext(ctx).Model(new(T)).Where("id = ?", id).Updates(updateDto).Error
+	model, values := updateModel[T](updateDto)
+	defer r.clearPK(ctx, model)()
+	return r.retryWrite(ctx, func() error {
+		query := r.omitNotAllowed(r.updateScope(ctx, r.db(ctx).Model(model)), r.config().UpdatableColumns)
+		return query.Where("id = ?", id).Updates(values).Error
+	})
 }
 
 func (r *GormRepository[T]) Update(ctx context.Context, conditions any, updateDto any, args ...any) error {
-	query := r.BuildQueryConfig(ctx, conditions, nil)
-	return query.Updates(updateDto).Error
+	if emptyConditions(conditions) && !r.config().AllowFullTableUpdate {
+		return ErrFullTableUpdate
+	}
+	model, values := updateModel[T](updateDto)
+	return r.retryWrite(ctx, func() error {
+		query := r.omitNotAllowed(r.BuildQueryConfig(ctx, conditions, nil).Model(model), r.config().UpdatableColumns)
+		return query.Updates(values).Error
+	})
+}
+
+// UpdateByPKReturning updates the entity like UpdateByPK and returns it loaded with config,
+// or nil when it doesn't exist. On Postgres the row comes back from the UPDATE itself
+// (RETURNING *) when config loads nothing beyond the row (no Joins, SelectHandler or
+// Preloads); otherwise it is re-read in the same transaction.
+func (r *GormRepository[T]) UpdateByPKReturning(ctx context.Context, id any, updateDto any, config *configs.GormConfig, args ...any) (*T, error) {
+	if r.Dialect() == DialectPostgres && r.rowOnly(config) {
+		if entity, ok := updateDto.(*T); ok && entity != nil {
+			updateDto = *entity // RETURNING scans into the model: keep the caller's value intact
+		}
+		model, values := updateModel[T](updateDto)
+		r.clearPK(ctx, model) // a copy: RETURNING fills the key back in
+		var result *gorm.DB
+		err := r.retryWrite(ctx, func() error {
+			query := r.omitNotAllowed(r.updateScope(ctx, r.db(ctx).Model(model)), r.config().UpdatableColumns)
+			result = query.Clauses(clause.Returning{}).Where("id = ?", id).Updates(values)
+			return result.Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected == 0 { // a missing row, or nothing to set and GORM skipped the statement
+			return r.FindOneByPK(ctx, id, config, args...)
+		}
+		return model.(*T), nil
+	}
+
+	var updated *T
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.UpdateByPK(ctx, id, updateDto, args...); err != nil {
+			return err
+		}
+		item, err := r.FindOneByPK(ctx, id, config, args...)
+		updated = item
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// rowOnly reports whether loading an entity with config (nil for the repository's) reads
+// nothing but its own row, so the row returned by a write can stand in for the read.
+func (r *GormRepository[T]) rowOnly(config *configs.GormConfig) bool {
+	if config == nil {
+		config = r.config()
+	}
+	return config.Joins == "" && config.SelectHandler == nil && len(config.Preloads) == 0 &&
+		len(config.AdditionalPreloads) == 0 && len(r.scopes) == 0 && extrasIndex[T]() == nil
+}
+
+// updateModel returns the model and values of an update. When updateDto is a T, the model is
+// updateDto itself, so T's BeforeUpdate/AfterUpdate hooks see (and may change) the new values
+// instead of a zero T. A non-zero primary key on it also narrows the update to that row;
+// the by-PK updates clear it with clearPK.
+func updateModel[T any](updateDto any) (model any, values any) {
+	switch entity := updateDto.(type) {
+	case T:
+		return &entity, &entity
+	case *T:
+		return entity, entity
+	}
+	return new(T), updateDto
+}
+
+// clearPK zeroes the primary key of model while it is a *T, so GORM doesn't add it to an
+// update by id as a second (possibly different) WHERE. The returned func puts it back.
+func (r *GormRepository[T]) clearPK(ctx context.Context, model any) (restore func()) {
+	restore = func() {}
+	entity, ok := model.(*T)
+	if !ok || entity == nil {
+		return restore
+	}
+	s, err := r.schema()
+	if err != nil || s.PrioritizedPrimaryField == nil {
+		return restore
+	}
+	field := s.PrioritizedPrimaryField.ReflectValueOf(ctx, reflect.ValueOf(entity).Elem())
+	if field.IsZero() {
+		return restore
+	}
+	old := reflect.New(field.Type()).Elem()
+	old.Set(field)
+	field.SetZero()
+	return func() { field.Set(old) }
 }
 
 func (r *GormRepository[T]) FindAll(ctx context.Context, conditions any, filter dto.FilterDto, config *configs.GormConfig, args ...any) ([]T, error) {
 	var models []T
 	listConfig := r.ResolveListConfig(config)
-	query := r.BuildBaseQuery(ctx, conditions, filter, listConfig)
-	err := query.Find(&models).Error
+	err := r.retry(ctx, func() error {
+		return r.find(ctx, r.BuildBaseQuery(ctx, conditions, filter, listConfig), &models)
+	})
 	return models, err
 }
 
 func (r *GormRepository[T]) FindAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *configs.GormConfig, args ...any) (*models.ListResponse[T], error) {
+	var response *models.ListResponse[T]
+	err := r.retry(ctx, func() (err error) {
+		response, err = r.findAllWithPaging(ctx, conditions, filter, config)
+		return err
+	})
+	return response, err
+}
+
+func (r *GormRepository[T]) findAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *configs.GormConfig) (*models.ListResponse[T], error) {
 	var entities []T
 	var total int64
 
+	// every query below runs on ctx: cancelling it (the client went away, or one of the
+	// concurrent queries failed) aborts the others in flight
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	listConfig := r.ResolveListConfig(config)
 	query := r.BuildBaseQuery(ctx, conditions, filter, listConfig)
 	countQuery := r.BuildQueryConditions(ctx, conditions, listConfig)
@@ -102,38 +436,178 @@ func (r *GormRepository[T]) FindAllWithPaging(ctx context.Context, conditions an
 		countQuery = countQuery.Group(listConfig.Group)
 	}
 
-	if err := countQuery.Model(new(T)).Count(&total).Error; err != nil {
-		return nil, err
-	}
-
 	filterDto := filter.GetBase()
 	if filterDto.Pagination == nil || *filterDto.Pagination {
-		query = query.Scopes(Paginate(filterDto.Page, filterDto.PerPage))
+		paginate := Paginate
+		if listConfig.Paginator != nil {
+			paginate = listConfig.Paginator
+		}
+		page, perPage := dto.NormalizePagination(filterDto.Page, filterDto.PerPage)
+		if filterDto.AfterID != nil {
+			query = query.Limit(perPage) // the seek replaces the offset
+		} else {
+			query = query.Scopes(paginate(page, perPage))
+		}
 	}
 
-	if err := query.Find(&entities).Error; err != nil {
+	// Single round trip: the total comes back with the page (see GormConfig.WindowCount).
+	// Not with after_id: the window would only count the rows after it, while total counts
+	// every matching row.
+	fetched, counted := false, false
+	if listConfig.WindowCount && filterDto.AfterID == nil && r.windowCountable(query, listConfig) {
+		rows, windowTotal, err := r.findWithWindowCount(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		entities, fetched = rows, true
+		if len(rows) > 0 {
+			total, counted = windowTotal, true
+		}
+	}
+
+	// Otherwise the counts and the page are read concurrently
+	var tasks []func() error
+	if !counted {
+		tasks = append(tasks, func() error {
+			return countQuery.Model(new(T)).Count(&total).Error
+		})
+	}
+	var unfiltered int64
+	if listConfig.CountUnfiltered {
+		unfilteredQuery := r.BuildQueryConditions(ctx, nil, listConfig)
+		if listConfig.Group != "" {
+			unfilteredQuery = unfilteredQuery.Group(listConfig.Group)
+		}
+		tasks = append(tasks, func() error {
+			return unfilteredQuery.Model(new(T)).Count(&unfiltered).Error
+		})
+	}
+	if !fetched {
+		tasks = append(tasks, func() error {
+			return r.find(ctx, query, &entities)
+		})
+	}
+	if err := runConcurrently(ctx, cancel, tasks...); err != nil {
 		return nil, err
 	}
 
+	var metadata *models.ListMetadata
+	if listConfig.CountUnfiltered {
+		metadata = &models.ListMetadata{TotalFiltered: total, TotalUnfiltered: unfiltered}
+	}
+
 	return &models.ListResponse[T]{
-		Total: total,
-		Data:  entities,
+		Total:    total,
+		Data:     entities,
+		Metadata: metadata,
 	}, nil
 }
 
+// ErrTooManyIDs is returned (wrapped) by FindAllIDs when more than MaxIDList rows match.
+var ErrTooManyIDs = errors.New("too many ids")
+
+// FindAllIDs returns the primary keys of all the entities matching conditions and filter
+// (applied with QueryBuilder; nil for none), ignoring pagination -- e.g. for a "select all
+// 10,000 matching rows" action. More than config.MaxIDList matches fail with ErrTooManyIDs
+// rather than returning a partial list.
+func (r *GormRepository[T]) FindAllIDs(ctx context.Context, conditions any, filter dto.FilterDto, config *configs.GormConfig, args ...any) ([]any, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%s has no primary key", s.Name)
+	}
+
+	listConfig := r.ResolveListConfig(config)
+	column := s.PrioritizedPrimaryField.DBName
+	if r.TableName != "" {
+		column = r.TableName + "." + column
+	}
+
+	limit := listConfig.IDListLimit()
+	query := r.BuildQueryConditions(ctx, conditions, listConfig).Order(column).Limit(limit + 1)
+	if filter != nil {
+		filtered, err := r.QueryBuilder(ctx, filter, listConfig, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, join := range conditionJoins(filtered) {
+			if !slices.Contains(conditionJoins(conditions), join) {
+				query = query.Joins(join)
+			}
+		}
+		query = r.where(query, filtered)
+	}
+	if listConfig.Group != "" {
+		query = query.Group(listConfig.Group)
+	}
+
+	var ids []any
+	if err := query.Pluck(column, &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) > limit {
+		return nil, fmt.Errorf("%w: more than %d match", ErrTooManyIDs, limit)
+	}
+	return ids, nil
+}
+
 func (r *GormRepository[T]) FindOne(ctx context.Context, conditions any, config *configs.GormConfig, args ...any) (*T, error) {
 	var model T
-	query := r.BuildQueryConfig(ctx, conditions, config)
-	err := query.First(&model).Error
+	err := r.retry(ctx, func() error {
+		query := r.selectFields(ctx, r.BuildQueryConfig(ctx, conditions, config), config)
+		if order := r.findOneOrder(config); order != "" {
+			query = query.Order(order)
+		}
+		return r.first(ctx, query, &model)
+	})
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
 	return &model, err
 }
 
+// findOneOrder returns the FindOneOrder of config, or of the repository's config when nil.
+func (r *GormRepository[T]) findOneOrder(config *configs.GormConfig) string {
+	if config == nil {
+		config = r.config()
+	}
+	return config.FindOneOrder
+}
+
 func (r *GormRepository[T]) FindOneByPK(ctx context.Context, id any, config *configs.GormConfig, args ...any) (*T, error) {
 	var model T
-	query := r.BuildQueryConfig(ctx, Eq("id", id), config)
+	err := r.retry(ctx, func() error {
+		return r.first(ctx, r.selectFields(ctx, r.BuildQueryConfig(ctx, Eq("id", id), config), config), &model)
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &model, err
+}
+
+// FindOneColumns finds a single entity by conditions, loading only the given columns.
+// Fields that are not selected are left zero-valued. Columns are validated against config.Selectable.
+func (r *GormRepository[T]) FindOneColumns(ctx context.Context, conditions any, columns []string, config *configs.GormConfig, args ...any) (*T, error) {
+	var gormConfig configs.GormConfig
+	if config == nil {
+		gormConfig = *r.config()
+	} else {
+		gormConfig = *config
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to select")
+	}
+	if err := validateColumns(columns, gormConfig.Selectable); err != nil {
+		return nil, err
+	}
+
+	var model T
+	query := r.BuildQueryConfig(ctx, conditions, &gormConfig).Select(columns)
+	if gormConfig.FindOneOrder != "" {
+		query = query.Order(gormConfig.FindOneOrder)
+	}
 	err := query.First(&model).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
@@ -141,29 +615,122 @@ func (r *GormRepository[T]) FindOneByPK(ctx context.Context, id any, config *con
 	return &model, err
 }
 
+// FindByIDs finds the entities with the given ids, querying at most config.IDChunkSize ids at a time.
 func (r *GormRepository[T]) FindByIDs(ctx context.Context, ids []any, config *configs.GormConfig, args ...any) ([]T, error) {
-	var entities []T
-	query := r.BuildQueryConfig(ctx, In("id", ids), config)
-	err := query.Find(&entities).Error
-	return entities, err
+	var gormConfig configs.GormConfig
+	if config == nil {
+		gormConfig = *r.config()
+	} else {
+		gormConfig = *config
+	}
+
+	entities := make([]T, 0, len(ids))
+	for chunk := range slices.Chunk(ids, gormConfig.ChunkSize()) {
+		var batch []T
+		query := r.BuildQueryConfig(ctx, In("id", chunk), &gormConfig)
+		if err := query.Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		entities = append(entities, batch...)
+	}
+	return entities, nil
 }
 
 func (r *GormRepository[T]) Delete(ctx context.Context, conditions any, args ...any) error {
-	return r.DB.WithContext(ctx).Model(new(T)).Where(conditions).Delete(new(T)).Error
+	_, err := r.DeleteRows(ctx, conditions, args...)
+	return err
+}
+
+// DeleteRows is Delete reporting how many entities were deleted (relations deleted through
+// CascadeSoftDelete are not counted), e.g. to skip follow-up work when nothing matched.
+func (r *GormRepository[T]) DeleteRows(ctx context.Context, conditions any, args ...any) (int64, error) {
+	if emptyConditions(conditions) {
+		if !r.config().AllowFullTableDelete {
+			return 0, ErrFullTableDelete
+		}
+		return r.deleteAll(ctx)
+	}
+	var deleted int64
+	err := r.retryWrite(ctx, func() (err error) {
+		deleted, err = r.deleteWhere(r.db(ctx), func(db *gorm.DB) *gorm.DB {
+			return r.where(db, conditions)
+		})
+		return err
+	})
+	return deleted, err
 }
 
 func (r *GormRepository[T]) DeleteOneByPK(ctx context.Context, id any, args ...any) error {
-	return r.DB.WithContext(ctx).Model(new(T)).Where("id = ?", id).Delete(new(T)).Error
+	return r.retryWrite(ctx, func() error {
+		_, err := r.deleteWhere(r.db(ctx), func(db *gorm.DB) *gorm.DB {
+			return db.Where("id = ?", id)
+		})
+		return err
+	})
+}
+
+// DeleteOneByPKReturning deletes the entity and returns it as it was before the delete,
+// loaded with config. The read and the delete share a transaction. A missing entity
+// yields (nil, nil).
+func (r *GormRepository[T]) DeleteOneByPKReturning(ctx context.Context, id any, config *configs.GormConfig, args ...any) (*T, error) {
+	var deleted *T
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		item, err := r.FindOneByPK(ctx, id, config, args...)
+		if err != nil || item == nil {
+			return err
+		}
+		if err := r.DeleteOneByPK(ctx, id, args...); err != nil {
+			return err
+		}
+		deleted = item
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
 }
 
+// DeleteByIDs deletes the entities with the given ids. Large lists are deleted in chunks of
+// IDChunkSize ids inside a single transaction, so the delete stays all-or-nothing.
 func (r *GormRepository[T]) DeleteByIDs(ctx context.Context, ids []any, args ...any) error {
-	return r.DB.WithContext(ctx).Model(new(T)).Where("id IN (?)", ids).Delete(new(T)).Error
+	byIDs := func(ids []any) scope {
+		return func(db *gorm.DB) *gorm.DB { return db.Where("id IN (?)", ids) }
+	}
+	return r.retryWrite(ctx, func() error {
+		if len(ids) <= r.config().ChunkSize() {
+			_, err := r.deleteWhere(r.db(ctx), byIDs(ids))
+			return err
+		}
+		return r.db(ctx).Transaction(func(tx *gorm.DB) error {
+			for chunk := range slices.Chunk(ids, r.config().ChunkSize()) {
+				if _, err := r.deleteWhere(tx, byIDs(chunk)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
 }
 
 func (r *GormRepository[T]) Count(ctx context.Context, conditions any, args ...any) (int64, error) {
 	var count int64
-	query := r.BuildQueryConditions(ctx, conditions, r.Config)
-	err := query.Count(&count).Error
+	err := r.retry(ctx, func() error {
+		return r.BuildQueryConditions(ctx, conditions, r.config()).Count(&count).Error
+	})
+	return count, err
+}
+
+// CountDistinct counts the distinct non-null values of column among the entities matching
+// conditions (COUNT(DISTINCT column)), e.g. the customers who placed an order. The column
+// is validated against Selectable.
+func (r *GormRepository[T]) CountDistinct(ctx context.Context, column string, conditions any, args ...any) (int64, error) {
+	if err := validateColumns([]string{column}, r.config().Selectable); err != nil {
+		return 0, err
+	}
+	var count int64
+	query := r.BuildQueryConditions(ctx, conditions, r.config())
+	err := query.Model(new(T)).Distinct(column).Count(&count).Error
 	return count, err
 }
 
@@ -181,34 +748,57 @@ func (r *GormRepository[T]) ExistsByPK(ctx context.Context, id any, args ...any)
 
 func (r *GormRepository[T]) Pluck(ctx context.Context, column string, conditions any, args ...any) ([]any, error) {
 	var results []any
-	query := r.BuildQueryConditions(ctx, conditions, r.Config)
+	query := r.BuildQueryConditions(ctx, conditions, r.config())
 	err := query.Model(new(T)).Pluck(column, &results).Error
 	return results, err
 }
 
 func (r *GormRepository[T]) UpdateColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
-	result := r.DB.WithContext(ctx).Model(new(T)).Where("id = ?", id).UpdateColumns(columns)
+	result := r.updateScope(ctx, r.db(ctx).Model(new(T))).Where("id = ?", id).UpdateColumns(columns)
 	return result.Error
 }
 
-func (r *GormRepository[T]) QueryBuilder(ctx context.Context, filter dto.FilterDto, gormConfig *configs.GormConfig, args ...any) (any, error) {
+// QueryBuilder builds the conditions of a list request from the filter: the search term and
+// the Filterable keys. Chain your own conditions onto the result with And/Or.
+func (r *GormRepository[T]) QueryBuilder(ctx context.Context, filter dto.FilterDto, gormConfig *configs.GormConfig, args ...any) (*Condition, error) {
 	var queryStrings []string
 	var queryValues []any
 
 	var config configs.GormConfig
 	if gormConfig == nil {
-		config = *r.Config
+		config = *r.config()
 	} else {
 		config = *gormConfig
 	}
 
 	// Handle search
+	var joins []string
+	var rankParts []string
+	var rankValues []any
 	filterDto := filter.GetBase()
-	if filterDto.Search != nil && len(config.Searchable) > 0 {
+	var search string
+	if filterDto.Search != nil {
+		search = strings.TrimSpace(*filterDto.Search)
+	}
+	if search != "" && utf8.RuneCountInString(search) >= config.MinSearchLength && len(config.Searchable) > 0 {
+		joins = searchJoins(config)
 		var searchParts []string
 		for _, field := range config.Searchable {
-			searchParts = append(searchParts, fmt.Sprintf("lower(%s) LIKE ?", field))
-			queryValues = append(queryValues, fmt.Sprintf("%%%s%%", strings.ToLower(*filterDto.Search)))
+			if len(joins) > 0 && r.TableName != "" && !strings.Contains(field.Key, ".") {
+				// keep base columns unambiguous once related tables are joined
+				field.Key = r.TableName + "." + field.Key
+			}
+			part, value := r.searchCondition(config.SearchStrategy, field, search)
+			searchParts = append(searchParts, part)
+			queryValues = append(queryValues, value)
+			if field.Weight > 0 && config.RankedSearch {
+				rankPart, values := rankedSearchPart(field, search, part, value)
+				rankParts = append(rankParts, rankPart)
+				rankValues = append(rankValues, values...)
+			} else if field.Weight > 0 {
+				rankParts = append(rankParts, fmt.Sprintf("CASE WHEN %s THEN %d ELSE 0 END", part, field.Weight))
+				rankValues = append(rankValues, value)
+			}
 		}
 		queryStrings = append(queryStrings, "("+strings.Join(searchParts, " OR ")+")")
 	}
@@ -218,48 +808,264 @@ func (r *GormRepository[T]) QueryBuilder(ctx context.Context, filter dto.FilterD
 	if err != nil {
 		return nil, err
 	}
+	if config.StrictFilters {
+		if err := checkFilterParams(filterDto.Params, result, config.Filterable); err != nil {
+			return nil, err
+		}
+	}
 
-	for key, value := range result {
-		if prop, ok := config.Filterable[key]; ok {
-			column := prop.ColumnName
-			if column == "" {
-				column = key
+	// Keys are visited in sorted order so the SQL and its args are stable. Keys sharing a
+	// Group are OR-ed together; a member missing from the filter uses the group's value.
+	var groups []string
+	groupParts := map[string][]string{}
+	groupValues := map[string][]any{}
+	for _, key := range slices.Sorted(maps.Keys(config.Filterable)) {
+		prop := config.Filterable[key]
+		lookup := func(suffix string) (any, bool) {
+			value, ok := result[key+suffix]
+			if !ok && prop.Group != "" {
+				value, ok = result[prop.Group+suffix]
 			}
+			return value, ok
+		}
 
-			switch prop.FilterType {
-			case configs.GormFilterTypeEqual:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s = ?", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeIn:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s IN (?)", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeNotIn:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s NOT IN (?)", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeLT:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s < ?", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeGT:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s > ?", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeLTE:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s <= ?", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeGTE:
-				queryStrings = append(queryStrings, fmt.Sprintf("%s >= ?", column))
-				queryValues = append(queryValues, value)
-			case configs.GormFilterTypeRegex:
-				queryStrings = append(queryStrings, fmt.Sprintf("lower(%s) LIKE ?", column))
-				queryValues = append(queryValues, fmt.Sprintf("%%%s%%", strings.ToLower(value.(string))))
+		column := prop.ColumnName
+		if column == "" {
+			column = key
+		}
+		var part string
+		var partValues []any
+		if prop.FilterType == configs.GormFilterTypeDateRange {
+			from, _ := lookup("_from")
+			to, _ := lookup("_to")
+			if part, partValues, err = dateRangeCondition(column, from, to); err != nil {
+				return nil, err
+			}
+			if part == "" {
+				continue
+			}
+		} else if prop.FilterType == configs.GormFilterTypeArrayContains {
+			value, ok := lookup("")
+			if !ok {
+				continue
+			}
+			part, partValues = arrayContains(r.Dialect(), column, value)
+		} else {
+			value, ok := lookup("")
+			if !ok {
+				continue
 			}
+			var partValue any
+			if part, partValue, err = filterCondition(prop.FilterType, column, value); err != nil {
+				return nil, err
+			}
+			if part == "" {
+				continue
+			}
+			partValues = []any{partValue}
+		}
+
+		group := prop.Group
+		if group == "" {
+			group = "\x00" + key // ungrouped keys stand alone
+		}
+		if _, seen := groupParts[group]; !seen {
+			groups = append(groups, group)
+		}
+		groupParts[group] = append(groupParts[group], part)
+		groupValues[group] = append(groupValues[group], partValues...)
+	}
+	for _, group := range groups {
+		if parts := groupParts[group]; len(parts) == 1 {
+			queryStrings = append(queryStrings, parts[0])
+		} else {
+			queryStrings = append(queryStrings, "("+strings.Join(parts, " OR ")+")")
 		}
+		queryValues = append(queryValues, groupValues[group]...)
 	}
 
-	finalQuery := strings.Join(queryStrings, " AND ")
-	return map[string]any{
-		"query": finalQuery,
-		"args":  queryValues,
-	}, nil
+	conditions := &Condition{joins: joins}
+	switch len(queryStrings) {
+	case 0:
+	case 1:
+		conditions.parts = Raw(queryStrings[0], queryValues...).parts
+	default:
+		// parenthesized so the result can be Or-ed as a whole
+		conditions.parts = Raw("("+strings.Join(queryStrings, " AND ")+")", queryValues...).parts
+	}
+	if len(rankParts) > 0 {
+		// search relevance, used by BuildBaseQuery when no sort_key is given
+		conditions.rank = Raw("("+strings.Join(rankParts, " + ")+")", rankValues...)
+	}
+	return conditions, nil
+}
+
+// filterCondition returns the predicate of a Filterable column and its argument, or "" for an
+// unknown filter type. A value the filter type can't take fails with dto.ErrInvalidFilter.
+func filterCondition(filterType configs.GormFilterType, column string, value any) (string, any, error) {
+	switch filterType {
+	case configs.GormFilterTypeEqual:
+		return fmt.Sprintf("%s = ?", column), value, nil
+	case configs.GormFilterTypeIn:
+		return fmt.Sprintf("%s IN (?)", column), value, nil
+	case configs.GormFilterTypeNotIn:
+		return fmt.Sprintf("%s NOT IN (?)", column), value, nil
+	case configs.GormFilterTypeLT:
+		return fmt.Sprintf("%s < ?", column), value, nil
+	case configs.GormFilterTypeGT:
+		return fmt.Sprintf("%s > ?", column), value, nil
+	case configs.GormFilterTypeLTE:
+		return fmt.Sprintf("%s <= ?", column), value, nil
+	case configs.GormFilterTypeGTE:
+		return fmt.Sprintf("%s >= ?", column), value, nil
+	case configs.GormFilterTypeRegex:
+		text := reflect.ValueOf(value)
+		if text.Kind() != reflect.String {
+			return "", nil, fmt.Errorf("%w: %s takes text, got %T", dto.ErrInvalidFilter, column, value)
+		}
+		return fmt.Sprintf("lower(%s) LIKE ?", column), fmt.Sprintf("%%%s%%", strings.ToLower(text.String())), nil
+	}
+	return "", nil, nil
+}
+
+// searchCondition returns the predicate matching a searchable column against the search
+// term, and its argument. The full-text strategy only replaces contains matches.
+func (r *GormRepository[T]) searchCondition(strategy configs.GormSearchStrategy, field configs.GormSearchProperty, term string) (string, any) {
+	column := field.Key
+	if !field.CaseSensitive {
+		column = fmt.Sprintf("lower(%s)", field.Key)
+		term = strings.ToLower(term)
+	}
+	switch field.Mode {
+	case configs.GormSearchModeExact:
+		return fmt.Sprintf("%s = ?", column), term
+	case configs.GormSearchModePrefix:
+		return fmt.Sprintf("%s LIKE ?", column), term + "%"
+	case configs.GormSearchModeSuffix:
+		return fmt.Sprintf("%s LIKE ?", column), "%" + term
+	}
+
+	if strategy == configs.GormSearchStrategyFullText {
+		switch r.Dialect() {
+		case DialectMySQL:
+			return fmt.Sprintf("MATCH(%s) AGAINST(? IN BOOLEAN MODE)", field.Key), term
+		case DialectPostgres:
+			return fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(?)", field.Key), term
+		}
+	}
+	return fmt.Sprintf("%s LIKE ?", column), "%" + term + "%"
+}
+
+// rankedSearchPart returns the RankedSearch score of a weighted column, and its arguments:
+// three times its weight for an exact match, twice for a prefix match and once for any other
+// match of part (the column's search predicate), so "go" ranks "Go" above "Golang" above
+// "Learn Go". Exact-mode columns only score exact matches.
+func rankedSearchPart(field configs.GormSearchProperty, term, part string, value any) (string, []any) {
+	column := field.Key
+	if !field.CaseSensitive {
+		column = fmt.Sprintf("lower(%s)", field.Key)
+		term = strings.ToLower(term)
+	}
+	if field.Mode == configs.GormSearchModeExact {
+		return fmt.Sprintf("CASE WHEN %s = ? THEN %d ELSE 0 END", column, 3*field.Weight), []any{term}
+	}
+	return fmt.Sprintf(
+		"CASE WHEN %s = ? THEN %d WHEN %s LIKE ? THEN %d WHEN %s THEN %d ELSE 0 END",
+		column, 3*field.Weight, column, 2*field.Weight, part, field.Weight,
+	), []any{term, term + "%", value}
+}
+
+// searchRank returns the relevance expression QueryBuilder adds to its conditions, if any.
+func searchRank(conditions any) map[string]any {
+	if cond, ok := conditions.(*Condition); ok {
+		if cond == nil || cond.rank == nil {
+			return nil
+		}
+		return cond.rank.Build()
+	}
+	if conditionsMap, ok := conditions.(map[string]any); ok {
+		if rank, ok := conditionsMap["rank"].(map[string]any); ok {
+			return rank
+		}
+	}
+	return nil
+}
+
+// conditionJoins returns the joins QueryBuilder requested in conditions, if any.
+func conditionJoins(conditions any) []string {
+	if cond, ok := conditions.(*Condition); ok && cond != nil {
+		return cond.joins
+	}
+	if conditionsMap, ok := conditions.(map[string]any); ok {
+		if joins, ok := conditionsMap["joins"].([]string); ok {
+			return joins
+		}
+	}
+	return nil
+}
+
+// normalizeConditions converts the condition forms repository methods accept to the
+// {"query", "args"} map: a *Condition (no need to call Build), a []configs.GormQueryField
+// (see GormConditionBuilder) or a raw SQL string without placeholders. Maps, including
+// QueryBuilder results, and anything else are returned as is.
+func (r *GormRepository[T]) normalizeConditions(conditions any) (any, error) {
+	switch c := conditions.(type) {
+	case *Condition:
+		return c.BuildFor(r.Dialect()), nil
+	case []configs.GormQueryField:
+		if err := ValidateQueryFields(c); err != nil {
+			return nil, err
+		}
+		return GormConditionBuilder(c), nil
+	case string:
+		return map[string]any{"query": c, "args": []any{}}, nil
+	}
+	return conditions, nil
+}
+
+// where adds conditions to query. Besides the forms of normalizeConditions, they may be
+// anything GORM's Where accepts (a column map, a struct...).
+func (r *GormRepository[T]) where(query *gorm.DB, conditions any) *gorm.DB {
+	conditions, err := r.normalizeConditions(conditions)
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+	switch c := conditions.(type) {
+	case nil:
+		return query
+	case map[string]any:
+		if _, ok := c["query"]; !ok {
+			if len(c) == 0 {
+				return query
+			}
+			return query.Where(c)
+		}
+		if q, ok := c["query"].(string); ok && q != "" {
+			args, _ := c["args"].([]any)
+			return query.Where(q, args...)
+		}
+		return query
+	}
+	return query.Where(conditions)
+}
+
+// searchJoins returns the SearchJoins needed by the qualified Searchable columns, skipping
+// the ones already part of Joins.
+func searchJoins(config configs.GormConfig) []string {
+	var joins []string
+	for _, field := range config.Searchable {
+		table, _, ok := strings.Cut(field.Key, ".")
+		if !ok {
+			continue
+		}
+		join, ok := config.SearchJoins[table]
+		if !ok || strings.Contains(config.Joins, join) || slices.Contains(joins, join) {
+			continue
+		}
+		joins = append(joins, join)
+	}
+	return joins
 }
 
 // ResolveListConfig returns a config with ListSelectHandler/ListPreloads applied
@@ -267,7 +1073,7 @@ func (r *GormRepository[T]) QueryBuilder(ctx context.Context, filter dto.FilterD
 func (r *GormRepository[T]) ResolveListConfig(config *configs.GormConfig) *configs.GormConfig {
 	var cfg configs.GormConfig
 	if config == nil {
-		cfg = *r.Config
+		cfg = *r.config()
 	} else {
 		cfg = *config
 	}
@@ -279,15 +1085,47 @@ func (r *GormRepository[T]) ResolveListConfig(config *configs.GormConfig) *confi
 		cfg.Preloads = cfg.ListPreloads
 	}
 
+	if len(cfg.AdditionalPreloads) > 0 {
+		defaults := r.config().ListPreloads
+		if defaults == nil {
+			defaults = r.config().Preloads
+		}
+		cfg.Preloads = mergePreloads(cfg.Preloads, defaults, cfg.AdditionalPreloads)
+		cfg.AdditionalPreloads = nil
+	}
+
 	return &cfg
 }
 
+// mergePreloads adds additional to preloads (falling back to defaults when preloads is nil),
+// replacing entries that target the same relation.
+func mergePreloads(preloads, defaults, additional []configs.GormPreloadConfig) []configs.GormPreloadConfig {
+	if preloads == nil {
+		preloads = defaults
+	}
+
+	merged := make([]configs.GormPreloadConfig, 0, len(preloads)+len(additional))
+	for _, preload := range preloads {
+		overridden := slices.ContainsFunc(additional, func(p configs.GormPreloadConfig) bool {
+			return p.Relation == preload.Relation
+		})
+		if !overridden {
+			merged = append(merged, preload)
+		}
+	}
+	return append(merged, additional...)
+}
+
+// BuildQueryConditions returns a *gorm.DB scoped to T with the configured Joins and the given
+// conditions applied (any form accepted by the repository methods). No select, preload or
+// ordering is added, which makes it suitable for counts and bulk statements. Rows deleted
+// through SoftDeleteColumn are excluded unless UnScoped is set.
 func (r *GormRepository[T]) BuildQueryConditions(ctx context.Context, conditions any, gormConfig *configs.GormConfig) *gorm.DB {
-	query := r.DB.WithContext(ctx).Model(new(T))
+	query := r.db(ctx).Model(new(T))
 
 	var config configs.GormConfig
 	if gormConfig == nil {
-		config = *r.Config
+		config = *r.config()
 	} else {
 		config = *gormConfig
 	}
@@ -296,24 +1134,41 @@ func (r *GormRepository[T]) BuildQueryConditions(ctx context.Context, conditions
 		query = query.Joins(config.Joins)
 	}
 
-	// Accept *Condition directly so callers don't need to call .Build()
-	if cond, ok := conditions.(*Condition); ok {
-		conditions = cond.Build()
+	if onlyTrashed(ctx) {
+		query = r.onlyDeleted(query)
+	} else if withTrashed(ctx) {
+		query = query.Unscoped()
+	} else if !config.UnScoped {
+		query = r.excludeDeleted(query)
 	}
 
-	if conditionsMap, ok := conditions.(map[string]any); ok {
-		if q, ok := conditionsMap["query"].(string); ok && q != "" {
-			args := conditionsMap["args"].([]interface{})
-			query = query.Where(q, args...)
-		}
+	// joins requested by QueryBuilder (see GormConfig.SearchJoins)
+	for _, join := range conditionJoins(conditions) {
+		query = query.Joins(join)
+	}
+	query = r.where(query, conditions)
+	if len(r.scopes) > 0 {
+		query = query.Scopes(r.scopes...)
 	}
 	return query
 }
 
+// BuildQueryConfig returns the query the repository's own reads start from: conditions,
+// Joins, SelectHandler, Preloads and UnScoped applied. It is the supported extension point
+// for custom queries -- chain your own clauses and finish with Find/Scan/Rows:
+//
+//	var rows []SalesRow
+//	err := repo.BuildQueryConfig(ctx, repositories.Eq("orders.status", "paid"), nil).
+//		Joins("JOIN customers ON customers.id = orders.customer_id").
+//		Select("customers.name, SUM(orders.total) AS total").
+//		Group("customers.name").
+//		Scan(&rows).Error
+//
+// A nil config uses the repository's config.
 func (r *GormRepository[T]) BuildQueryConfig(ctx context.Context, conditions any, gormConfig *configs.GormConfig) *gorm.DB {
 	var config configs.GormConfig
 	if gormConfig == nil {
-		config = *r.Config
+		config = *r.config()
 	} else {
 		config = *gormConfig
 	}
@@ -336,26 +1191,12 @@ func (r *GormRepository[T]) BuildQueryConfig(ctx context.Context, conditions any
 	}
 
 	// Handle dynamic Preloads
-	for _, preload := range config.Preloads {
-		if preload.SelectHandler != nil {
-			selects := preload.SelectHandler(lang)
-			var preloadClauses []string
-			for _, f := range selects {
-				alias := f.Alias
-				if alias == "" {
-					alias = f.Column
-				}
-				preloadClauses = append(preloadClauses, fmt.Sprintf("%s AS %s", f.Column, alias))
-			}
-			query = query.Preload(preload.Relation, func(db *gorm.DB) *gorm.DB {
-				if preload.UnScoped {
-					db = db.Unscoped()
-				}
-				return db.Select(strings.Join(preloadClauses, ", "))
-			})
-		} else {
-			query = query.Preload(preload.Relation)
-		}
+	preloads := config.Preloads
+	if len(config.AdditionalPreloads) > 0 {
+		preloads = mergePreloads(preloads, r.config().Preloads, config.AdditionalPreloads)
+	}
+	for _, preload := range preloads {
+		query = r.applyPreload(query, preload, lang)
 	}
 
 	// Get all raw including soft-deleted
@@ -366,11 +1207,14 @@ func (r *GormRepository[T]) BuildQueryConfig(ctx context.Context, conditions any
 	return query
 }
 
+// BuildBaseQuery is BuildQueryConfig plus the list behavior driven by the filter:
+// sorting and the relations requested with ?include=. Pagination is not applied. A relation
+// missing from Includable sets an error (wrapping dto.ErrInvalidInclude) on the query.
 func (r *GormRepository[T]) BuildBaseQuery(ctx context.Context, conditions any, filter dto.FilterDto, gormConfig *configs.GormConfig) *gorm.DB {
 	query := r.BuildQueryConfig(ctx, conditions, gormConfig)
 	var config configs.GormConfig
 	if gormConfig == nil {
-		config = *r.Config
+		config = *r.config()
 	} else {
 		config = *gormConfig
 	}
@@ -378,11 +1222,30 @@ func (r *GormRepository[T]) BuildBaseQuery(ctx context.Context, conditions any,
 	// Apply sorting
 	filterDto := filter.GetBase()
 
-	sortKey := "created_at"
-	if filterDto.SortKey != nil {
-		sortKey = *filterDto.SortKey
-	} else if config.DefaultSort != "" {
-		sortKey = config.DefaultSort
+	var sortKey string
+	var nulls configs.GormSortNulls
+	requested := filterDto.SortKey
+	if requested != nil && (config.Sortable != nil || config.SortExpressions != nil) {
+		if expression, ok := config.SortExpressions[*requested]; ok {
+			sortKey = "(" + expression + ")"
+		} else if prop, ok := config.Sortable[*requested]; ok {
+			sortKey, nulls = cmp.Or(prop.Column, *requested), prop.Nulls
+			if prop.Join != "" && !strings.Contains(config.Joins, prop.Join) && !slices.Contains(conditionJoins(conditions), prop.Join) {
+				query = query.Joins(prop.Join)
+			}
+		} else {
+			query.AddError(fmt.Errorf("%w: unknown sort_key %q", dto.ErrInvalidFilter, *requested))
+			requested = nil
+		}
+	} else if requested != nil {
+		sortKey = *requested
+	}
+	if requested == nil {
+		if config.DefaultSort != "" {
+			sortKey = config.DefaultSort
+		} else {
+			sortKey = r.defaultSortKey()
+		}
 	}
 
 	sortDir := "desc"
@@ -390,11 +1253,124 @@ func (r *GormRepository[T]) BuildBaseQuery(ctx context.Context, conditions any,
 		sortDir = strings.ToLower(*filterDto.SortDir)
 	}
 
-	query = query.Order(fmt.Sprintf("%s %s", sortKey, sortDir))
+	// Rank search results first when the client didn't choose a sort (see GormSearchProperty.Weight)
+	rank := searchRank(conditions)
+	if filterDto.AfterID != nil {
+		query = r.afterID(query, *filterDto.AfterID)
+	} else if requested == nil && rank != nil {
+		order := rank["query"].(string) + " DESC"
+		if sortKey != "" {
+			order += fmt.Sprintf(", %s %s", sortKey, sortDir)
+		}
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                order,
+			Vars:               rank["args"].([]any),
+			WithoutParentheses: true,
+		}})
+	} else if sortKey != "" {
+		query = query.Order(r.Dialect().orderBy(sortKey, sortDir, nulls))
+	}
+
+	// Apply the relations requested with ?include=; anything outside Includable fails the query
+	includes, err := filterDto.IncludeList()
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+	lang := middlewares.GetLangFromContext(ctx)
+	for _, name := range includes {
+		if depth := strings.Count(name, ".") + 1; config.MaxIncludeDepth > 0 && depth > config.MaxIncludeDepth {
+			query.AddError(fmt.Errorf("%w: %s is nested deeper than %d", dto.ErrInvalidInclude, name, config.MaxIncludeDepth))
+			return query
+		}
+		preload, ok := config.Includable[name]
+		if !ok {
+			query.AddError(fmt.Errorf("%w: %s is not includable", dto.ErrInvalidInclude, name))
+			return query
+		}
+		query = r.applyPreload(query, preload, lang)
+	}
 
 	return query
 }
 
+// afterID narrows query to the rows whose primary key is greater than id, in primary key
+// order: a seek page for ?after_id= (infinite scroll), cheaper than an offset and without
+// gaps or repeats. It replaces the requested sort.
+func (r *GormRepository[T]) afterID(query *gorm.DB, id string) *gorm.DB {
+	s, err := r.schema()
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+	if s.PrioritizedPrimaryField == nil {
+		query.AddError(fmt.Errorf("after_id: %s has no primary key", s.Name))
+		return query
+	}
+	var value any = id
+	switch s.PrioritizedPrimaryField.DataType {
+	case schema.Int, schema.Uint:
+		if value, err = strconv.ParseInt(id, 10, 64); err != nil {
+			query.AddError(fmt.Errorf("%w: after_id %q is not an integer", dto.ErrInvalidFilter, id))
+			return query
+		}
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: s.PrioritizedPrimaryField.DBName}
+	return query.Where(clause.Gt{Column: column, Value: value}).Order(clause.OrderByColumn{Column: column})
+}
+
+// defaultSortKey is the sort used when neither the filter nor DefaultSort specifies one:
+// created_at when the model has it, otherwise the primary key (e.g. pivot tables),
+// otherwise no ORDER BY at all.
+func (r *GormRepository[T]) defaultSortKey() string {
+	modelSchema, err := r.schema()
+	if err != nil {
+		return "created_at"
+	}
+	if modelSchema.LookUpField("created_at") != nil {
+		return "created_at"
+	}
+	if modelSchema.PrioritizedPrimaryField != nil {
+		return modelSchema.PrioritizedPrimaryField.DBName
+	}
+	return ""
+}
+
+// schema returns the parsed GORM schema of T (cached by GORM).
+func (r *GormRepository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// applyPreload preloads a single relation, honoring its SelectHandler and UnScoped settings.
+func (r *GormRepository[T]) applyPreload(query *gorm.DB, preload configs.GormPreloadConfig, lang string) *gorm.DB {
+	if preload.SelectHandler == nil {
+		if preload.UnScoped {
+			return query.Preload(preload.Relation, func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+		}
+		return query.Preload(preload.Relation)
+	}
+
+	selects := preload.SelectHandler(lang)
+	var preloadClauses []string
+	for _, f := range selects {
+		alias := f.Alias
+		if alias == "" {
+			alias = f.Column
+		}
+		preloadClauses = append(preloadClauses, fmt.Sprintf("%s AS %s", f.Column, alias))
+	}
+	return query.Preload(preload.Relation, func(db *gorm.DB) *gorm.DB {
+		if preload.UnScoped {
+			db = db.Unscoped()
+		}
+		return db.Select(strings.Join(preloadClauses, ", "))
+	})
+}
+
 // CreateOrUpdate performs an upsert operation. It creates the entity if it doesn't exist,
 // or updates the specified columns if a conflict is found on the given conflictColumns.
 // If updateColumns is empty, all columns are updated on conflict.
@@ -403,6 +1379,15 @@ func (r *GormRepository[T]) CreateOrUpdate(ctx context.Context, entity any, conf
 	if !ok {
 		return nil, fmt.Errorf("invalid type passed to CreateOrUpdate: expected %T", new(T))
 	}
+	if err := r.assignID(ctx, &typedEntity); err != nil {
+		return nil, err
+	}
+	if err := r.checkReferences(ctx, typedEntity); err != nil {
+		return nil, err
+	}
+	if err := r.resolveTrashedDuplicates(ctx, typedEntity); err != nil {
+		return nil, err
+	}
 
 	var onConflict clause.OnConflict
 	if len(conflictColumns) > 0 {
@@ -419,87 +1404,170 @@ func (r *GormRepository[T]) CreateOrUpdate(ctx context.Context, entity any, conf
 		onConflict.UpdateAll = true
 	}
 
-	err := r.DB.WithContext(ctx).Model(new(T)).Clauses(onConflict).Create(&typedEntity).Error
+	err := r.retryWrite(ctx, func() error {
+		query := r.omitNotAllowed(r.db(ctx).Model(new(T)), r.config().CreatableColumns)
+		return query.Clauses(onConflict).Create(&typedEntity).Error
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	val := reflect.ValueOf(typedEntity)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-	idField := val.FieldByName("ID")
-	if !idField.IsValid() {
-		return nil, fmt.Errorf("ID field not found on entity")
-	}
-	switch idField.Kind() {
-	case reflect.String:
-		return idField.String(), nil
-	case reflect.Int, reflect.Int64:
-		return idField.Int(), nil
-	case reflect.Uint, reflect.Uint64:
-		return idField.Uint(), nil
-	default:
-		return nil, fmt.Errorf("unsupported ID type: %s", idField.Kind())
-	}
+	return extractID(typedEntity)
 }
 
-// FindOrCreate finds the first record matching conditions, or creates a new one with createDto.
-// Returns the entity and a boolean indicating whether it was created (true) or found (false).
+// FindOrCreate finds the first record matching conditions (as FindOne does), or creates a new
+// one with createDto the way Create does (generated id, CheckReferences, TrashedUniqueStrategy,
+// CreatableColumns). Returns the entity and a boolean indicating whether it was created (true)
+// or found (false).
 func (r *GormRepository[T]) FindOrCreate(ctx context.Context, conditions any, createDto any, config *configs.GormConfig, args ...any) (*T, bool, error) {
 	entity, ok := createDto.(T)
 	if !ok {
 		return nil, false, fmt.Errorf("invalid type passed to FindOrCreate: expected %T", new(T))
 	}
 
-	query := r.BuildQueryConfig(ctx, conditions, config)
-	result := query.FirstOrCreate(&entity)
-	if result.Error != nil {
-		return nil, false, result.Error
+	found, err := r.FindOne(ctx, conditions, config, args...)
+	if err != nil || found != nil {
+		return found, false, err
+	}
+	if err := r.assignID(ctx, &entity); err != nil {
+		return nil, false, err
+	}
+	if err := r.checkReferences(ctx, entity); err != nil {
+		return nil, false, err
+	}
+	if err := r.resolveTrashedDuplicates(ctx, entity); err != nil {
+		return nil, false, err
 	}
 
-	created := result.RowsAffected > 0
-	return &entity, created, nil
+	err = r.retryWrite(ctx, func() error {
+		return r.omitNotAllowed(r.db(ctx).Model(new(T)), r.config().CreatableColumns).Create(&entity).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &entity, true, nil
 }
 
 // WithTransaction executes the given function within a database transaction.
 // If the function returns an error, the transaction is rolled back.
 // If the function returns nil, the transaction is committed.
 func (r *GormRepository[T]) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
-	return r.DB.WithContext(ctx).Transaction(fn)
+	return r.db(ctx).Transaction(fn)
+}
+
+// Ping checks that the database is reachable, e.g. for a readiness probe.
+func (r *GormRepository[T]) Ping(ctx context.Context) error {
+	sqlDB, err := r.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
 
 // Restore restores a soft-deleted record by its primary key.
-// This only works with models that use GORM's soft delete (DeletedAt field).
+// This works with models that use GORM's soft delete (DeletedAt field) or a SoftDeleteColumn.
 func (r *GormRepository[T]) Restore(ctx context.Context, id any, args ...any) error {
-	return r.DB.WithContext(ctx).Model(new(T)).Unscoped().Where("id = ?", id).UpdateColumn("deleted_at", nil).Error
+	return r.restoreWhere(r.db(ctx), func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ?", id)
+	})
 }
 
 // RestoreByConditions restores soft-deleted records matching the given conditions.
 func (r *GormRepository[T]) RestoreByConditions(ctx context.Context, conditions any, args ...any) error {
-	// Accept *Condition directly
-	if cond, ok := conditions.(*Condition); ok {
-		conditions = cond.Build()
+	return r.restoreWhere(r.db(ctx), func(db *gorm.DB) *gorm.DB {
+		return r.where(db, conditions)
+	})
+}
+
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// validateColumns checks that every column is a plain (optionally table-qualified) identifier
+// and, when allowed is not empty, that it is part of allowed.
+func validateColumns(columns []string, allowed []string) error {
+	for _, column := range columns {
+		if !columnPattern.MatchString(column) {
+			return fmt.Errorf("invalid column: %s", column)
+		}
+		if len(allowed) > 0 && !slices.Contains(allowed, column) {
+			return fmt.Errorf("column not allowed: %s", column)
+		}
 	}
+	return nil
+}
 
-	query := r.DB.WithContext(ctx).Model(new(T)).Unscoped()
-	if conditionsMap, ok := conditions.(map[string]any); ok {
-		if q, ok := conditionsMap["query"].(string); ok && q != "" {
-			queryArgs := conditionsMap["args"].([]interface{})
-			query = query.Where(q, queryArgs...)
+// ErrInvalidID is returned (wrapped) when the id of an entity can't be read after a write: no
+// ID field, a nil pointer ID (the database didn't return one) or an unsupported type.
+var ErrInvalidID = errors.New("invalid id")
+
+// extractID reads the ID field of an entity (embedded fields included).
+//
+// Integers of any width are returned as int64/uint64 and string kinds (including custom
+// string types) as string. Other types are returned in their string form when they
+// implement fmt.Stringer or encoding.TextMarshaler (e.g. uuid.UUID), and [16]byte
+// values are formatted as UUIDs.
+func extractID(entity any) (any, error) {
+	val := reflect.ValueOf(entity)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, fmt.Errorf("%w: entity is nil", ErrInvalidID)
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: unsupported entity type: %s", ErrInvalidID, val.Kind())
+	}
+
+	idField := val.FieldByName("ID")
+	if !idField.IsValid() {
+		return nil, fmt.Errorf("%w: ID field not found on entity", ErrInvalidID)
+	}
+	for idField.Kind() == reflect.Ptr {
+		if idField.IsNil() {
+			return nil, fmt.Errorf("%w: ID of %s is nil", ErrInvalidID, val.Type())
+		}
+		idField = idField.Elem()
+	}
+
+	switch idField.Kind() {
+	case reflect.String:
+		return idField.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return idField.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return idField.Uint(), nil
+	}
+
+	// Prefer the type's own representation, with value or pointer receiver
+	candidates := []any{idField.Interface()}
+	ptr := reflect.New(idField.Type())
+	ptr.Elem().Set(idField)
+	candidates = append(candidates, ptr.Interface())
+	for _, candidate := range candidates {
+		switch v := candidate.(type) {
+		case fmt.Stringer:
+			return v.String(), nil
+		case encoding.TextMarshaler:
+			text, err := v.MarshalText()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+			}
+			return string(text), nil
 		}
 	}
-	return query.UpdateColumn("deleted_at", nil).Error
+
+	if idField.Kind() == reflect.Array && idField.Len() == 16 && idField.Type().Elem().Kind() == reflect.Uint8 {
+		var b [16]byte
+		reflect.Copy(reflect.ValueOf(&b).Elem(), idField)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported ID type: %s", ErrInvalidID, idField.Type())
 }
 
+// Paginate applies OFFSET/LIMIT for the given page, normalized with dto.NormalizePagination.
 func Paginate(page, size int) func(db *gorm.DB) *gorm.DB {
+	page, size = dto.NormalizePagination(page, size)
 	return func(db *gorm.DB) *gorm.DB {
-		if page <= 0 {
-			page = 1
-		}
-		if size <= 0 {
-			size = 10
-		}
 		offset := (page - 1) * size
 		return db.Offset(offset).Limit(size)
 	}
@@ -517,17 +1585,34 @@ func Sort(sort, dir string) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// GormConditionBuilder joins the given fields with AND into the map[string]any condition format.
+//
+// Besides binary operators, IN / NOT IN take a slice in Value, BETWEEN / NOT BETWEEN take
+// their bounds in Values, and IS NULL / IS NOT NULL take no value at all. The fields are not
+// checked: call ValidateQueryFields first (repository methods do).
 func GormConditionBuilder(conditions []configs.GormQueryField) map[string]any {
 	queryStrings := []string{}
 	queryValues := []any{}
 
 	for _, condition := range conditions {
-		operation := condition.Operation
+		operation := strings.ToUpper(strings.TrimSpace(condition.Operation))
 		if operation == "" {
 			operation = "="
 		}
-		queryStrings = append(queryStrings, fmt.Sprintf("%s %s ?", condition.Column, operation))
-		queryValues = append(queryValues, condition.Value)
+
+		switch operation {
+		case configs.GormOperationIn, configs.GormOperationNotIn:
+			queryStrings = append(queryStrings, fmt.Sprintf("%s %s (?)", condition.Column, operation))
+			queryValues = append(queryValues, condition.Value)
+		case configs.GormOperationBetween, configs.GormOperationNotBetween:
+			queryStrings = append(queryStrings, fmt.Sprintf("%s %s ? AND ?", condition.Column, operation))
+			queryValues = append(queryValues, condition.Values...)
+		case configs.GormOperationIsNull, configs.GormOperationIsNotNull:
+			queryStrings = append(queryStrings, fmt.Sprintf("%s %s", condition.Column, operation))
+		default:
+			queryStrings = append(queryStrings, fmt.Sprintf("%s %s ?", condition.Column, operation))
+			queryValues = append(queryValues, condition.Value)
+		}
 	}
 
 	finalQuery := strings.Join(queryStrings, " AND ")
@@ -536,3 +1621,48 @@ func GormConditionBuilder(conditions []configs.GormQueryField) map[string]any {
 		"args":  queryValues,
 	}
 }
+
+// ValidateQueryFields fails with dto.ErrInvalidFilter when a field can't compile to valid SQL:
+// BETWEEN / NOT BETWEEN without exactly two Values.
+func ValidateQueryFields(conditions []configs.GormQueryField) error {
+	for _, condition := range conditions {
+		switch strings.ToUpper(strings.TrimSpace(condition.Operation)) {
+		case configs.GormOperationBetween, configs.GormOperationNotBetween:
+			if len(condition.Values) != 2 {
+				return fmt.Errorf("%w: %s %s takes 2 values, got %d", dto.ErrInvalidFilter, condition.Column, condition.Operation, len(condition.Values))
+			}
+		}
+	}
+	return nil
+}
+
+// checkFilterParams fails with dto.ErrUnknownFilter when a query param is neither a base
+// param, a key of the filter DTO nor a Filterable key or group.
+func checkFilterParams(params []string, filter map[string]any, filterable map[string]configs.GormFilterProperty) error {
+	known := map[string]bool{}
+	for key, prop := range filterable {
+		names := []string{key}
+		if prop.Group != "" {
+			names = append(names, prop.Group)
+		}
+		for _, name := range names {
+			if prop.FilterType == configs.GormFilterTypeDateRange {
+				known[name+"_from"], known[name+"_to"] = true, true
+			} else {
+				known[name] = true
+			}
+		}
+	}
+
+	var unknown []string
+	for _, param := range params {
+		if _, ok := filter[param]; ok || known[param] || slices.Contains(dto.BaseParams, param) {
+			continue
+		}
+		unknown = append(unknown, param)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", dto.ErrUnknownFilter, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,1761 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+type roleName string
+
+type stringerID struct{ n int }
+
+func (s stringerID) String() string { return "id-" + string(rune('0'+s.n)) }
+
+type textID struct{ v string }
+
+func (t *textID) MarshalText() ([]byte, error) { return []byte("text-" + t.v), nil }
+
+func TestExtractID(t *testing.T) {
+	answer := 42
+	tests := []struct {
+		name    string
+		entity  any
+		want    any
+		invalid bool
+	}{
+		{"int", struct{ ID int }{7}, int64(7), false},
+		{"int32", struct{ ID int32 }{-3}, int64(-3), false},
+		{"uint8", struct{ ID uint8 }{200}, uint64(200), false},
+		{"pointer entity", &struct{ ID uint }{9}, uint64(9), false},
+		{"pointer id", struct{ ID *int }{&answer}, int64(42), false},
+		{"custom string kind", struct{ ID roleName }{"admin"}, "admin", false},
+		{"stringer", struct{ ID stringerID }{stringerID{5}}, "id-5", false},
+		{"pointer-receiver text marshaler", struct{ ID textID }{textID{"a"}}, "text-a", false},
+		{"uuid bytes", struct{ ID [16]byte }{[16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}}, "01234567-89ab-cdef-0123-456789abcdef", false},
+		{"embedded", struct{ base struct{ ID int } }{}, nil, true},
+		{"promoted", struct{ Embedded }{Embedded{ID: 11}}, uint64(11), false},
+		{"nil pointer id", struct{ ID *int }{}, nil, true},
+		{"nil entity", (*struct{ ID int })(nil), nil, true},
+		{"no id", struct{ Name string }{"x"}, nil, true},
+		{"unsupported", struct{ ID float64 }{1.5}, nil, true},
+		{"not a struct", 3, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractID(tt.entity)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidID) {
+					t.Errorf("extractID() = %v, %v; want ErrInvalidID", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("extractID() = %#v, %v; want %#v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// Embedded stands in for models.Base: its ID is promoted to the embedding struct.
+type Embedded struct{ ID uint }
+
+func TestFilterCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		filterType configs.GormFilterType
+		value      any
+		query      string
+		arg        any
+		invalid    bool
+	}{
+		{"equal", configs.GormFilterTypeEqual, 1, "status = ?", 1, false},
+		{"in", configs.GormFilterTypeIn, []int{1, 2}, "status IN (?)", []int{1, 2}, false},
+		{"not in", configs.GormFilterTypeNotIn, []int{3}, "status NOT IN (?)", []int{3}, false},
+		{"lt", configs.GormFilterTypeLT, 5, "status < ?", 5, false},
+		{"gte", configs.GormFilterTypeGTE, 5, "status >= ?", 5, false},
+		{"regex", configs.GormFilterTypeRegex, "AcTi", "lower(status) LIKE ?", "%acti%", false},
+		{"regex on a string kind", configs.GormFilterTypeRegex, roleName("Ad"), "lower(status) LIKE ?", "%ad%", false},
+		{"regex on a number", configs.GormFilterTypeRegex, 12, "", nil, true},
+		{"regex on a slice", configs.GormFilterTypeRegex, []string{"a"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, arg, err := filterCondition(tt.filterType, "status", tt.value)
+			if tt.invalid {
+				if !errors.Is(err, dto.ErrInvalidFilter) {
+					t.Errorf("filterCondition() error = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil || query != tt.query {
+				t.Fatalf("filterCondition() = %q, %v; want %q", query, err, tt.query)
+			}
+			if s, ok := arg.(string); ok && s != tt.arg {
+				t.Errorf("arg = %q, want %q", s, tt.arg)
+			}
+		})
+	}
+}
+
+func TestCheckFilterParams(t *testing.T) {
+	filterable := map[string]configs.GormFilterProperty{
+		"status":     {FilterType: configs.GormFilterTypeEqual},
+		"created_at": {FilterType: configs.GormFilterTypeDateRange},
+		"owner_id":   {FilterType: configs.GormFilterTypeEqual, Group: "owner"},
+	}
+	tests := []struct {
+		name    string
+		params  []string
+		filter  map[string]any
+		unknown bool
+	}{
+		{"none", nil, nil, false},
+		{"base params", []string{"page", "per_page", "sort_key", "after_id"}, nil, false},
+		{"filterable key", []string{"status"}, nil, false},
+		{"date range bounds", []string{"created_at_from", "created_at_to"}, nil, false},
+		{"date range key itself", []string{"created_at"}, nil, true},
+		{"group", []string{"owner"}, nil, false},
+		{"dto field", []string{"tenant"}, map[string]any{"tenant": 1}, false},
+		{"typo", []string{"statuss"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkFilterParams(tt.params, tt.filter, filterable)
+			if got := errors.Is(err, dto.ErrUnknownFilter); got != tt.unknown {
+				t.Errorf("checkFilterParams() = %v, want unknown %v", err, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestValidateColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		allowed []string
+		wantErr bool
+	}{
+		{"plain", []string{"name", "users.email"}, nil, false},
+		{"allowed", []string{"name"}, []string{"name", "email"}, false},
+		{"not allowed", []string{"password"}, []string{"name"}, true},
+		{"expression", []string{"name; DROP TABLE users"}, nil, true},
+		{"empty", []string{""}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateColumns(tt.columns, tt.allowed); (err != nil) != tt.wantErr {
+				t.Errorf("validateColumns() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type category struct {
+	models.Base
+	Name     string
+	Products []product `gorm:"foreignKey:CategoryID"`
+}
+
+type product struct {
+	models.Base
+	Name       string
+	Status     string
+	Price      int
+	CategoryID *uint
+	Category   *category
+	SKU        *string `gorm:"uniqueIndex"`
+	Tags       string  // a JSON array
+}
+
+type productFilter struct {
+	dto.BaseFilterDto
+	Status *string
+	Values map[string]any // other filter values, by key
+}
+
+func (f *productFilter) ToMap() (map[string]any, error) {
+	m, _ := f.BaseFilterDto.ToMap()
+	if f.Status != nil {
+		m["status"] = *f.Status
+	}
+	for key, value := range f.Values {
+		m[key] = value
+	}
+	return m, nil
+}
+
+// newTestDB opens a private in-memory SQLite database with the test models migrated. A
+// single connection keeps every query on the same in-memory database.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&category{}, &product{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestRepository(t *testing.T, config *configs.GormConfig) *GormRepository[product] {
+	t.Helper()
+	return NewGormRepository[product](newTestDB(t), config, "products")
+}
+
+// seedProducts creates n products named p1..pn, alternating the active and draft statuses,
+// with prices 1..n.
+func seedProducts(t *testing.T, r *GormRepository[product], n int) []product {
+	t.Helper()
+	products := make([]product, n)
+	for i := range products {
+		products[i] = product{Name: fmt.Sprintf("p%d", i+1), Status: []string{"active", "draft"}[i%2], Price: i + 1}
+	}
+	if err := r.DB.CreateInBatches(&products, 500).Error; err != nil {
+		t.Fatal(err)
+	}
+	return products
+}
+
+func ptr[V any](v V) *V { return &v }
+
+func TestFindAllWithPaging(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *configs.GormConfig
+		conditions any
+		filter     *productFilter
+		build      bool // the conditions come from QueryBuilder, as in the services
+		total      int64
+		names      []string
+		unfiltered int64
+		invalid    bool
+	}{
+		{
+			name:   "first page",
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Page: 1, PerPage: 2, SortKey: ptr("price"), SortDir: ptr("ASC")}},
+			total:  5,
+			names:  []string{"p1", "p2"},
+		},
+		{
+			name:       "conditions",
+			conditions: Eq("status", "active"),
+			filter:     &productFilter{BaseFilterDto: dto.BaseFilterDto{SortKey: ptr("price")}},
+			total:      3,
+			names:      []string{"p5", "p3", "p1"},
+		},
+		{
+			name:   "filterable key",
+			config: &configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{"status": {FilterType: configs.GormFilterTypeEqual}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{SortKey: ptr("price")}, Status: ptr("draft")},
+			build:  true,
+			total:  2,
+			names:  []string{"p4", "p2"},
+		},
+		{
+			name:       "count unfiltered",
+			config:     &configs.GormConfig{CountUnfiltered: true},
+			conditions: Eq("status", "draft"),
+			filter:     &productFilter{BaseFilterDto: dto.BaseFilterDto{SortKey: ptr("price")}},
+			total:      2,
+			names:      []string{"p4", "p2"},
+			unfiltered: 5,
+		},
+		{
+			name:   "sortable key",
+			config: &configs.GormConfig{Sortable: map[string]configs.GormSortProperty{"cost": {Column: "price"}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{PerPage: 2, SortKey: ptr("cost"), SortDir: ptr("ASC")}},
+			total:  5,
+			names:  []string{"p1", "p2"},
+		},
+		{
+			name:   "sort expression",
+			config: &configs.GormConfig{SortExpressions: map[string]string{"margin": "price % 2 * 100 + price"}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{PerPage: 3, SortKey: ptr("margin"), SortDir: ptr("ASC")}},
+			total:  5,
+			names:  []string{"p2", "p4", "p1"},
+		},
+		{
+			name:    "sort key outside Sortable",
+			config:  &configs.GormConfig{Sortable: map[string]configs.GormSortProperty{"cost": {Column: "price"}}},
+			filter:  &productFilter{BaseFilterDto: dto.BaseFilterDto{SortKey: ptr("name")}},
+			invalid: true,
+		},
+		{
+			name:    "sort key outside SortExpressions",
+			config:  &configs.GormConfig{SortExpressions: map[string]string{"margin": "price % 2"}},
+			filter:  &productFilter{BaseFilterDto: dto.BaseFilterDto{SortKey: ptr("price; DROP TABLE products")}},
+			invalid: true,
+		},
+		{
+			name:    "after_id that isn't an integer",
+			filter:  &productFilter{BaseFilterDto: dto.BaseFilterDto{AfterID: ptr("abc")}},
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, tt.config)
+			seedProducts(t, r, 5)
+			ctx := context.Background()
+
+			conditions := tt.conditions
+			if tt.build {
+				built, err := r.QueryBuilder(ctx, tt.filter, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				conditions = built
+			}
+			response, err := r.FindAllWithPaging(ctx, conditions, tt.filter, nil)
+			if tt.invalid {
+				if !errors.Is(err, dto.ErrInvalidFilter) {
+					t.Fatalf("FindAllWithPaging() error = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Total != tt.total {
+				t.Errorf("Total = %d, want %d", response.Total, tt.total)
+			}
+			var names []string
+			for _, p := range response.Data {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("Data = %v, want %v", names, tt.names)
+			}
+			if tt.unfiltered != 0 {
+				if response.Metadata == nil || response.Metadata.TotalUnfiltered != tt.unfiltered || response.Metadata.TotalFiltered != tt.total {
+					t.Errorf("Metadata = %+v, want %d filtered of %d", response.Metadata, tt.total, tt.unfiltered)
+				}
+			}
+		})
+	}
+}
+
+func TestFindAllWithPagingAfterID(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 25)
+	ctx := context.Background()
+
+	// walking the pages (from an id-ordered first one) visits every row once, in key order, while total counts them all
+	var seen []uint
+	var after *string
+	for range 10 {
+		filter := &productFilter{BaseFilterDto: dto.BaseFilterDto{PerPage: 10, AfterID: after, SortKey: ptr("id"), SortDir: ptr("ASC")}}
+		response, err := r.FindAllWithPaging(ctx, nil, filter, &configs.GormConfig{WindowCount: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Total != 25 {
+			t.Fatalf("Total = %d after %v, want 25", response.Total, after)
+		}
+		if len(response.Data) == 0 {
+			break
+		}
+		for _, p := range response.Data {
+			seen = append(seen, p.ID)
+		}
+		after = ptr(fmt.Sprint(seen[len(seen)-1]))
+	}
+	if len(seen) != 25 {
+		t.Fatalf("visited %d rows, want 25", len(seen))
+	}
+	for i, id := range seen {
+		if id != uint(i+1) {
+			t.Fatalf("row %d has id %d: pages skip or repeat rows", i, id)
+		}
+	}
+}
+
+func TestFindAllWithPagingPaginator(t *testing.T) {
+	var calls [][2]int
+	r := newTestRepository(t, &configs.GormConfig{
+		// caps the offset at the second page
+		Paginator: func(page, perPage int) func(db *gorm.DB) *gorm.DB {
+			calls = append(calls, [2]int{page, perPage})
+			return func(db *gorm.DB) *gorm.DB {
+				return db.Offset((min(page, 2) - 1) * perPage).Limit(perPage)
+			}
+		},
+	})
+	seedProducts(t, r, 5)
+
+	filter := &productFilter{BaseFilterDto: dto.BaseFilterDto{Page: 3, PerPage: 2, SortKey: ptr("id"), SortDir: ptr("ASC")}}
+	response, err := r.FindAllWithPaging(context.Background(), nil, filter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(calls, [][2]int{{3, 2}}) {
+		t.Errorf("Paginator calls = %v, want one for page 3 of 2", calls)
+	}
+	var names []string
+	for _, p := range response.Data {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"p3", "p4"}) || response.Total != 5 {
+		t.Errorf("page = %v of %d, want the capped page [p3 p4] of 5", names, response.Total)
+	}
+}
+
+func TestFindAllWithPagingCancelled(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 3)
+
+	// the client goes away while the first query is running
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := r.DB.Callback().Query().Before("gorm:query").Register("test:cancel", func(*gorm.DB) { cancel() })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.FindAllWithPaging(ctx, nil, &productFilter{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FindAllWithPaging() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFindAllIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *configs.GormConfig
+		conditions any
+		filter     dto.FilterDto
+		ids        []uint
+		tooMany    bool
+	}{
+		{name: "all", ids: []uint{1, 2, 3, 4, 5}},
+		{name: "conditions", conditions: Gt("price", 3), ids: []uint{4, 5}},
+		{
+			name:   "filter",
+			config: &configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{"status": {FilterType: configs.GormFilterTypeEqual}}},
+			filter: &productFilter{Status: ptr("active")},
+			ids:    []uint{1, 3, 5},
+		},
+		{
+			name:       "conditions and filter",
+			config:     &configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{"status": {FilterType: configs.GormFilterTypeEqual}}},
+			conditions: Gt("price", 1),
+			filter:     &productFilter{Status: ptr("active")},
+			ids:        []uint{3, 5},
+		},
+		{
+			name:   "search",
+			config: &configs.GormConfig{Searchable: configs.SearchColumns("name")},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("P4")}},
+			ids:    []uint{4},
+		},
+		{name: "over the limit", config: &configs.GormConfig{MaxIDList: 4}, tooMany: true},
+		{name: "at the limit", config: &configs.GormConfig{MaxIDList: 2}, conditions: Lte("price", 2), ids: []uint{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, tt.config)
+			seedProducts(t, r, 5)
+
+			ids, err := r.FindAllIDs(context.Background(), tt.conditions, tt.filter, nil)
+			if tt.tooMany {
+				if !errors.Is(err, ErrTooManyIDs) {
+					t.Fatalf("FindAllIDs() error = %v, want ErrTooManyIDs", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]uint, len(ids))
+			for i, id := range ids {
+				got[i] = uint(id.(int64))
+			}
+			if !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("FindAllIDs() = %v, want %v", got, tt.ids)
+			}
+		})
+	}
+}
+
+func TestIDChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkSize int
+		rows      int
+		delete    int
+	}{
+		{"single statement", 0, 10, 5},
+		{"default chunk size", 0, 2500, 2100},
+		{"configured chunk size", 7, 30, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{IDChunkSize: tt.chunkSize})
+			seedProducts(t, r, tt.rows)
+			ctx := context.Background()
+
+			ids := make([]any, tt.delete)
+			for i := range ids {
+				ids[i] = i + 1
+			}
+			found, err := r.FindByIDs(ctx, ids, nil)
+			if err != nil || len(found) != tt.delete {
+				t.Fatalf("FindByIDs() = %d rows, %v; want %d", len(found), err, tt.delete)
+			}
+			if err := r.DeleteByIDs(ctx, ids); err != nil {
+				t.Fatal(err)
+			}
+			if count, err := r.Count(ctx, nil); err != nil || count != int64(tt.rows-tt.delete) {
+				t.Errorf("Count() = %d, %v; want %d", count, err, tt.rows-tt.delete)
+			}
+		})
+	}
+}
+
+func TestUpdateByPK(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *configs.GormConfig
+		id        uint
+		update    any
+		names     []string // by id, after the update
+		payloadID uint     // the id the payload holds after the call, when it is a *product
+	}{
+		{name: "map", id: 2, update: map[string]any{"name": "two"}, names: []string{"p1", "two", "p3"}},
+		{name: "entity", id: 1, update: product{Name: "one"}, names: []string{"one", "p2", "p3"}},
+		{
+			name:      "entity holding another key",
+			id:        3,
+			update:    &product{Base: models.Base{ID: 1}, Name: "three"},
+			names:     []string{"p1", "p2", "three"},
+			payloadID: 1,
+		},
+		{
+			name:   "updatable columns",
+			config: &configs.GormConfig{UpdatableColumns: []string{"price"}},
+			id:     1,
+			update: map[string]any{"name": "ignored", "price": 10},
+			names:  []string{"p1", "p2", "p3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, tt.config)
+			seedProducts(t, r, 3)
+
+			if err := r.UpdateByPK(context.Background(), tt.id, tt.update); err != nil {
+				t.Fatal(err)
+			}
+			var rows []product
+			r.DB.Order("id").Find(&rows)
+			var names []string
+			for _, row := range rows {
+				names = append(names, row.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("names = %v, want %v", names, tt.names)
+			}
+			if entity, ok := tt.update.(*product); ok && entity.ID != tt.payloadID {
+				t.Errorf("payload id = %d, want it restored to %d", entity.ID, tt.payloadID)
+			}
+		})
+	}
+}
+
+func TestUpdateByPKVersioned(t *testing.T) {
+	tests := []struct {
+		name    string
+		column  string
+		id      uint
+		version int
+		wantErr error
+		name2   string // name of row 2 after the update
+	}{
+		{"current version", "price", 2, 2, nil, "two"},
+		{"stale version", "price", 2, 1, ErrStaleVersion, "p2"},
+		{"missing row", "price", 9, 1, nil, "p2"},
+		{"no version column", "", 2, 2, ErrNoVersionColumn, "p2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{VersionColumn: tt.column})
+			seedProducts(t, r, 3) // price doubles as the version
+
+			err := r.UpdateByPKVersioned(context.Background(), tt.id, tt.version, map[string]any{"name": "two"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateByPKVersioned() error = %v, want %v", err, tt.wantErr)
+			}
+			var row product
+			r.DB.First(&row, 2)
+			if row.Name != tt.name2 {
+				t.Errorf("name = %q, want %q", row.Name, tt.name2)
+			}
+			if tt.wantErr == nil && tt.id == 2 && row.Price != 3 {
+				t.Errorf("version = %d, want it bumped to 3", row.Price)
+			}
+		})
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	tests := []struct {
+		name       string
+		column     string
+		selectable []string
+		conditions any
+		want       int64
+		wantErr    bool
+	}{
+		{"all", "status", nil, nil, 2, false},
+		{"conditions", "status", nil, Gt("price", 4), 1, false},
+		{"selectable", "status", []string{"status"}, nil, 2, false},
+		{"not selectable", "name", []string{"status"}, nil, 0, true},
+		{"expression", "status) FROM products; --", nil, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{Selectable: tt.selectable})
+			seedProducts(t, r, 5)
+
+			got, err := r.CountDistinct(context.Background(), tt.column, tt.conditions)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("CountDistinct() = %d, %v; want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// seedCatalog seeds the products of seedProducts with categories (p1, p2: books; p3: games),
+// tags (p1: go, sql; p2: go) and creation days (p<n>: 2024-01-0<n>, noon UTC).
+func seedCatalog(t *testing.T, r *GormRepository[product]) {
+	t.Helper()
+	seedProducts(t, r, 5)
+	r.DB.Create(&[]category{{Name: "books"}, {Name: "games"}})
+	tags := []string{`["go","sql"]`, `["go"]`, `[]`, `[]`, `[]`}
+	categories := []any{1, 1, 2, nil, nil}
+	for i := range 5 {
+		err := r.DB.Model(&product{}).Where("id = ?", i+1).UpdateColumns(map[string]any{
+			"tags":        tags[i],
+			"category_id": categories[i],
+			"created_at":  time.Date(2024, 1, i+1, 12, 0, 0, 0, time.UTC),
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	filterable := func(key string, filterType configs.GormFilterType) map[string]configs.GormFilterProperty {
+		return map[string]configs.GormFilterProperty{key: {FilterType: filterType}}
+	}
+	tests := []struct {
+		name    string
+		config  configs.GormConfig
+		filter  *productFilter
+		names   []string
+		wantErr error
+	}{
+		{
+			name:   "equal",
+			config: configs.GormConfig{Filterable: filterable("status", configs.GormFilterTypeEqual)},
+			filter: &productFilter{Status: ptr("draft")},
+			names:  []string{"p2", "p4"},
+		},
+		{
+			name:   "in",
+			config: configs.GormConfig{Filterable: filterable("price", configs.GormFilterTypeIn)},
+			filter: &productFilter{Values: map[string]any{"price": []int{1, 3}}},
+			names:  []string{"p1", "p3"},
+		},
+		{
+			name: "bounds on one column",
+			config: configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{
+				"min_price": {ColumnName: "price", FilterType: configs.GormFilterTypeGTE},
+				"max_price": {ColumnName: "price", FilterType: configs.GormFilterTypeLT},
+			}},
+			filter: &productFilter{Values: map[string]any{"min_price": 2, "max_price": 4}},
+			names:  []string{"p2", "p3"},
+		},
+		{
+			name:   "regex",
+			config: configs.GormConfig{Filterable: filterable("name", configs.GormFilterTypeRegex)},
+			filter: &productFilter{Values: map[string]any{"name": "P1"}},
+			names:  []string{"p1"},
+		},
+		{
+			name:   "date range",
+			config: configs.GormConfig{Filterable: filterable("created_at", configs.GormFilterTypeDateRange)},
+			filter: &productFilter{Values: map[string]any{"created_at_from": "2024-01-02", "created_at_to": "2024-01-03"}},
+			names:  []string{"p2", "p3"},
+		},
+		{
+			name:   "open date range",
+			config: configs.GormConfig{Filterable: filterable("created_at", configs.GormFilterTypeDateRange)},
+			filter: &productFilter{Values: map[string]any{"created_at_from": "2024-01-04T00:00:00Z"}},
+			names:  []string{"p4", "p5"},
+		},
+		{
+			name:    "bad date",
+			config:  configs.GormConfig{Filterable: filterable("created_at", configs.GormFilterTypeDateRange)},
+			filter:  &productFilter{Values: map[string]any{"created_at_to": "yesterday"}},
+			wantErr: dto.ErrInvalidFilter,
+		},
+		{
+			name: "group",
+			config: configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{
+				"name":   {FilterType: configs.GormFilterTypeEqual, Group: "q"},
+				"status": {FilterType: configs.GormFilterTypeEqual, Group: "q"},
+				"price":  {FilterType: configs.GormFilterTypeLTE},
+			}},
+			filter: &productFilter{Values: map[string]any{"q": "draft", "price": 3}},
+			names:  []string{"p2"},
+		},
+		{
+			name: "group member with its own value",
+			config: configs.GormConfig{Filterable: map[string]configs.GormFilterProperty{
+				"name":   {FilterType: configs.GormFilterTypeEqual, Group: "q"},
+				"status": {FilterType: configs.GormFilterTypeEqual, Group: "q"},
+			}},
+			filter: &productFilter{Values: map[string]any{"q": "active", "name": "p2"}},
+			names:  []string{"p1", "p2", "p3", "p5"},
+		},
+		{
+			name:   "array contains",
+			config: configs.GormConfig{Filterable: filterable("tags", configs.GormFilterTypeArrayContains)},
+			filter: &productFilter{Values: map[string]any{"tags": "go"}},
+			names:  []string{"p1", "p2"},
+		},
+		{
+			name:   "array contains all",
+			config: configs.GormConfig{Filterable: filterable("tags", configs.GormFilterTypeArrayContains)},
+			filter: &productFilter{Values: map[string]any{"tags": []string{"go", "sql"}}},
+			names:  []string{"p1"},
+		},
+		{
+			name:   "search",
+			config: configs.GormConfig{Searchable: configs.SearchColumns("name", "status")},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr(" DRAFT ")}},
+			names:  []string{"p2", "p4"},
+		},
+		{
+			name:   "search shorter than the minimum",
+			config: configs.GormConfig{Searchable: configs.SearchColumns("name"), MinSearchLength: 3},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("p1")}},
+			names:  []string{"p1", "p2", "p3", "p4", "p5"},
+		},
+		{
+			name:   "prefix search",
+			config: configs.GormConfig{Searchable: []configs.GormSearchProperty{{Key: "status", Mode: configs.GormSearchModePrefix}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("act")}},
+			names:  []string{"p1", "p3", "p5"},
+		},
+		{
+			name:   "suffix search",
+			config: configs.GormConfig{Searchable: []configs.GormSearchProperty{{Key: "status", Mode: configs.GormSearchModeSuffix}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("act")}},
+			names:  nil,
+		},
+		{
+			name:   "exact search",
+			config: configs.GormConfig{Searchable: []configs.GormSearchProperty{{Key: "name", Mode: configs.GormSearchModeExact}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("P4")}},
+			names:  []string{"p4"},
+		},
+		{
+			name:   "case-sensitive search",
+			config: configs.GormConfig{Searchable: []configs.GormSearchProperty{{Key: "name", Mode: configs.GormSearchModeExact, CaseSensitive: true}}},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("P4")}},
+			names:  nil,
+		},
+		{
+			name: "search on a joined column",
+			config: configs.GormConfig{
+				Searchable:  configs.SearchColumns("name", "categories.name"),
+				SearchJoins: map[string]string{"categories": "LEFT JOIN categories ON categories.id = products.category_id"},
+			},
+			filter: &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: ptr("game")}},
+			names:  []string{"p3"},
+		},
+		{
+			name:    "strict filters",
+			config:  configs.GormConfig{StrictFilters: true, Filterable: filterable("status", configs.GormFilterTypeEqual)},
+			filter:  &productFilter{BaseFilterDto: dto.BaseFilterDto{Params: []string{"statuss"}}},
+			wantErr: dto.ErrUnknownFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &tt.config)
+			seedCatalog(t, r)
+			ctx := context.Background()
+
+			conditions, err := r.QueryBuilder(ctx, tt.filter, nil)
+			if tt.wantErr != nil || err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("QueryBuilder() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			rows, err := r.FindAll(ctx, conditions, &dto.BaseFilterDto{SortKey: ptr("products.id"), SortDir: ptr("ASC")}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, row := range rows {
+				names = append(names, row.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("rows = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestWeightedSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranked bool
+		sort   *string
+		search string
+		names  []string
+	}{
+		// a name match (weight 2) outranks a status match (weight 1); ties keep the default sort
+		{"one column", false, nil, "p", []string{"p5", "p4", "p3", "p1"}},
+		{"weights add up", false, nil, "a", []string{"p2", "p5", "p4", "p3", "p1"}},
+		// prefix matches score twice their weight, other matches once
+		{"ranked by match quality", true, nil, "a", []string{"p2", "p5", "p3", "p1", "p4"}},
+		{"explicit sort wins", false, ptr("price"), "a", []string{"p5", "p4", "p3", "p2", "p1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{
+				Searchable: []configs.GormSearchProperty{
+					{Key: "name", Weight: 2},
+					{Key: "status", Weight: 1},
+				},
+				RankedSearch: tt.ranked,
+			})
+			seedCatalog(t, r)
+			r.DB.Model(&product{}).Where("id = ?", 2).Update("name", "a draft") // its name and status match "a"
+			ctx := context.Background()
+
+			filter := &productFilter{BaseFilterDto: dto.BaseFilterDto{Search: &tt.search, SortKey: tt.sort}}
+			conditions, err := r.QueryBuilder(ctx, filter, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := r.FindAll(ctx, conditions, filter, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, row := range rows {
+				ids = append(ids, fmt.Sprint("p", row.ID))
+			}
+			if !reflect.DeepEqual(ids, tt.names) {
+				t.Errorf("rows = %v, want %v", ids, tt.names)
+			}
+		})
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		rows      int
+	}{
+		{"empty", 0, 0},
+		{"one batch", 0, 3},
+		{"several batches", 4, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{CreateBatchSize: tt.batchSize, IDChunkSize: 3})
+			ctx := context.Background()
+			items := make([]any, tt.rows)
+			for i := range items {
+				items[i] = product{Name: fmt.Sprint("p", i+1), Price: i + 1}
+			}
+
+			var statements int
+			r.DB.Callback().Create().Before("gorm:create").Register("test:count", func(*gorm.DB) { statements++ })
+			ids, err := r.BulkCreate(ctx, items)
+			if err != nil || len(ids) != tt.rows {
+				t.Fatalf("BulkCreate() = %v, %v; want %d ids", ids, err, tt.rows)
+			}
+			size := cmp.Or(tt.batchSize, configs.DefaultCreateBatchSize)
+			if want := (tt.rows + size - 1) / size; statements != want {
+				t.Errorf("ran %d inserts, want %d", statements, want)
+			}
+		})
+	}
+
+	r := newTestRepository(t, nil)
+	if _, err := r.BulkCreate(context.Background(), []any{product{}, category{}}); err == nil {
+		t.Error("BulkCreate() with a foreign type succeeded")
+	}
+}
+
+func TestBulkCreateReturning(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		rows      int
+	}{
+		{"empty", 0, 0},
+		{"one batch", 0, 3},
+		{"several batches", 4, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{CreateBatchSize: tt.batchSize})
+			items := make([]any, tt.rows)
+			for i := range items {
+				items[i] = product{Name: fmt.Sprint("p", i+1), Price: i + 1}
+			}
+
+			// the stored rows come back in order
+			created, err := r.BulkCreateReturning(context.Background(), items)
+			if err != nil || len(created) != tt.rows {
+				t.Fatalf("BulkCreateReturning() = %d rows, %v; want %d", len(created), err, tt.rows)
+			}
+			for i, p := range created {
+				if p.ID != uint(i+1) || p.Name != fmt.Sprint("p", i+1) || p.CreatedAt.IsZero() {
+					t.Errorf("row %d = %d %s %v, want id %d, name p%d and a creation time", i, p.ID, p.Name, p.CreatedAt, i+1, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkCreatePartial(t *testing.T) {
+	r := newTestRepository(t, nil)
+	r.DB.Create(&product{Name: "taken", SKU: ptr("a")})
+
+	items := []any{
+		product{Name: "ok 1", SKU: ptr("b")},
+		product{Name: "duplicate", SKU: ptr("a")},
+		category{Name: "wrong type"},
+		product{Name: "ok 2"},
+	}
+	created, failures, err := r.BulkCreatePartial(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[0].Name != "ok 1" || created[1].Name != "ok 2" || created[1].ID == 0 {
+		t.Errorf("created = %+v, want ok 1 and ok 2 with ids", created)
+	}
+	if len(failures) != 2 || failures[0].Index != 1 || failures[1].Index != 2 {
+		t.Errorf("failures = %v, want items 1 and 2", failures)
+	}
+	if count, _ := r.Count(context.Background(), nil); count != 3 {
+		t.Errorf("Count() = %d, want 3", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := r.BulkCreatePartial(ctx, items); !errors.Is(err, context.Canceled) {
+		t.Errorf("BulkCreatePartial() with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+type ticket struct {
+	ID    string `gorm:"primaryKey"`
+	Title string
+}
+
+func TestIDGenerator(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&ticket{}); err != nil {
+		t.Fatal(err)
+	}
+	next := 0
+	r := NewGormRepository[ticket](db, &configs.GormConfig{IDGenerator: func() any {
+		next++
+		return fmt.Sprint("t-", next)
+	}}, "tickets")
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		create func() (any, error)
+		want   any
+	}{
+		{"generated", func() (any, error) { return r.Create(ctx, ticket{Title: "a"}) }, "t-1"},
+		{"given", func() (any, error) { return r.Create(ctx, ticket{ID: "mine", Title: "b"}) }, "mine"},
+		{"bulk", func() (any, error) { return r.BulkCreate(ctx, []any{ticket{Title: "c"}, ticket{Title: "d"}}) }, []string{"t-2", "t-3"}},
+		{"upsert", func() (any, error) { return r.CreateOrUpdate(ctx, ticket{Title: "e"}, []string{"id"}, nil) }, "t-4"},
+		{"find or create", func() (any, error) {
+			found, _, err := r.FindOrCreate(ctx, Eq("title", "f"), ticket{Title: "f"}, nil)
+			if err != nil {
+				return nil, err
+			}
+			return found.ID, nil
+		}, "t-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.create()
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("id = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritableColumns(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{
+		CreatableColumns: []string{"name", "Price"},
+		UpdatableColumns: []string{"price"},
+	})
+	ctx := context.Background()
+
+	id, err := r.Create(ctx, product{Name: "p", Price: 5, Status: "forced"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.UpdateByPK(ctx, id, product{Name: "renamed", Price: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(ctx, Eq("id", id), map[string]any{"status": "forced", "price": 7}); err != nil {
+		t.Fatal(err)
+	}
+	var row product
+	r.DB.First(&row, id)
+	if row.Name != "p" || row.Status != "" || row.Price != 7 {
+		t.Errorf("row = %s/%s/%d, want p//7", row.Name, row.Status, row.Price)
+	}
+
+	created, _, err := r.FindOrCreate(ctx, Eq("name", "q"), product{Name: "q", Price: 5, Status: "forced"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row = product{}
+	r.DB.First(&row, created.ID)
+	if row.Name != "q" || row.Status != "" || row.Price != 5 {
+		t.Errorf("FindOrCreate row = %s/%s/%d, want q//5", row.Name, row.Status, row.Price)
+	}
+}
+
+func TestPatchColumnsByPK(t *testing.T) {
+	tests := []struct {
+		name      string
+		updatable []string
+		columns   map[string]any
+		want      string // name/status/price/sku after the patch
+		invalid   bool
+	}{
+		{"zero values", nil, map[string]any{"price": 0, "status": ""}, "p1//0/<nil>", false},
+		{"json number", nil, map[string]any{"Price": float64(9)}, "p1/active/9/<nil>", false},
+		{"null", nil, map[string]any{"sku": nil}, "p1/active/1/<nil>", false},
+		{"pointer column", nil, map[string]any{"sku": "x"}, "p1/active/1/x", false},
+		{"unknown column", nil, map[string]any{"colour": "red"}, "", true},
+		{"primary key", nil, map[string]any{"id": 5}, "", true},
+		{"not updatable", []string{"price"}, map[string]any{"name": "x"}, "", true},
+		{"wrong type", nil, map[string]any{"price": "lots"}, "", true},
+		{"nothing", nil, map[string]any{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{UpdatableColumns: tt.updatable})
+			seedProducts(t, r, 1)
+
+			err := r.PatchColumnsByPK(context.Background(), 1, tt.columns)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidColumn) {
+					t.Fatalf("PatchColumnsByPK() error = %v, want ErrInvalidColumn", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var row product
+			r.DB.First(&row, 1)
+			sku := "<nil>"
+			if row.SKU != nil {
+				sku = *row.SKU
+			}
+			if got := fmt.Sprintf("%s/%s/%d/%s", row.Name, row.Status, row.Price, sku); got != tt.want {
+				t.Errorf("row = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkSize int
+		items     []product
+		columns   []string
+		prices    []int
+		invalid   bool
+	}{
+		{
+			name:    "per-row values",
+			items:   []product{{Base: models.Base{ID: 1}, Price: 30}, {Base: models.Base{ID: 3}, Price: 10}},
+			columns: []string{"price"},
+			prices:  []int{30, 2, 10, 4},
+		},
+		{
+			name:      "chunks",
+			chunkSize: 1,
+			items:     []product{{Base: models.Base{ID: 4}, Price: 0, Name: "kept"}, {Base: models.Base{ID: 2}, Price: 20}},
+			columns:   []string{"Price"},
+			prices:    []int{1, 20, 3, 0},
+		},
+		{name: "no columns", items: []product{{Base: models.Base{ID: 1}}}, invalid: true},
+		{name: "primary key", items: []product{{Base: models.Base{ID: 1}}}, columns: []string{"id"}, invalid: true},
+		{name: "unknown column", items: []product{{Base: models.Base{ID: 1}}}, columns: []string{"colour"}, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{IDChunkSize: tt.chunkSize})
+			seedProducts(t, r, 4)
+
+			err := r.BulkUpdate(context.Background(), tt.items, tt.columns)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidColumn) {
+					t.Fatalf("BulkUpdate() error = %v, want ErrInvalidColumn", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var prices []int
+			var names []string
+			r.DB.Model(&product{}).Order("id").Pluck("price", &prices)
+			r.DB.Model(&product{}).Order("id").Pluck("name", &names)
+			if !reflect.DeepEqual(prices, tt.prices) {
+				t.Errorf("prices = %v, want %v", prices, tt.prices)
+			}
+			if !reflect.DeepEqual(names, []string{"p1", "p2", "p3", "p4"}) {
+				t.Errorf("names = %v, want them untouched", names)
+			}
+		})
+	}
+
+	r := newTestRepository(t, nil)
+	if err := r.BulkUpdate(context.Background(), []product{{Price: 1}}, []string{"price"}); err == nil {
+		t.Error("BulkUpdate() of an item without a key succeeded")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 4)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		preview  func() (*models.DryRun, error)
+		affected int64
+		sql      string
+		wantErr  error
+	}{
+		{"delete", func() (*models.DryRun, error) { return r.PreviewDelete(ctx, Eq("status", "draft")) }, 2, "UPDATE `products` SET `deleted_at`=", nil},
+		{"delete everything", func() (*models.DryRun, error) { return r.PreviewDelete(ctx, nil) }, 0, "", ErrFullTableDelete},
+		{"update", func() (*models.DryRun, error) {
+			return r.PreviewUpdate(ctx, Gt("price", 1), map[string]any{"name": "x"})
+		}, 3, "UPDATE `products` SET `name`=\"x\"", nil},
+		{"update everything", func() (*models.DryRun, error) { return r.PreviewUpdate(ctx, nil, map[string]any{"name": "x"}) }, 0, "", ErrFullTableUpdate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := tt.preview()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if preview.Affected != tt.affected || len(preview.SQL) != 1 || !strings.HasPrefix(preview.SQL[0], tt.sql) {
+				t.Errorf("preview = %d, %q; want %d, %s...", preview.Affected, preview.SQL, tt.affected, tt.sql)
+			}
+		})
+	}
+
+	// nothing was written
+	var names []string
+	r.DB.Model(&product{}).Order("id").Pluck("name", &names)
+	if count, _ := r.Count(ctx, nil); count != 4 || !reflect.DeepEqual(names, []string{"p1", "p2", "p3", "p4"}) {
+		t.Errorf("after the previews: %d rows %v, want the 4 rows untouched", count, names)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	includable := map[string]configs.GormPreloadConfig{
+		"category":          {Relation: "Category"},
+		"category.products": {Relation: "Category.Products"},
+	}
+	tests := []struct {
+		name     string
+		include  string
+		depth    int
+		category bool
+		siblings bool
+		invalid  bool
+	}{
+		{name: "none"},
+		{name: "relation", include: "category", category: true},
+		{name: "nested", include: "category,category.products", category: true, siblings: true},
+		{name: "too deep", include: "category.products", depth: 1, invalid: true},
+		{name: "not includable", include: "secrets", invalid: true},
+		{name: "malformed", include: "category;drop", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{Includable: includable, MaxIncludeDepth: tt.depth})
+			seedCatalog(t, r)
+
+			filter := &dto.BaseFilterDto{SortKey: ptr("id"), SortDir: ptr("ASC")}
+			if tt.include != "" {
+				filter.Include = &tt.include
+			}
+			rows, err := r.FindAll(context.Background(), Eq("id", 1), filter, nil)
+			if tt.invalid {
+				if !errors.Is(err, dto.ErrInvalidInclude) {
+					t.Fatalf("FindAll() error = %v, want ErrInvalidInclude", err)
+				}
+				return
+			}
+			if err != nil || len(rows) != 1 {
+				t.Fatalf("FindAll() = %v, %v", rows, err)
+			}
+			category := rows[0].Category
+			if (category != nil) != tt.category {
+				t.Fatalf("Category = %+v, want loaded %v", category, tt.category)
+			}
+			if tt.category && (category.Name != "books" || (len(category.Products) == 2) != tt.siblings) {
+				t.Errorf("Category = %s with %d products, want books with siblings %v", category.Name, len(category.Products), tt.siblings)
+			}
+		})
+	}
+}
+
+func TestAdditionalPreloads(t *testing.T) {
+	db := newTestDB(t)
+	r := NewGormRepository[category](db, &configs.GormConfig{
+		Preloads: []configs.GormPreloadConfig{{Relation: "Products"}},
+	}, "categories")
+	db.Create(&category{Name: "books", Products: []product{{Name: "b1"}, {Name: "b2"}}})
+	db.Delete(&product{}, 2)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		config   *configs.GormConfig
+		products int
+	}{
+		{"repository preloads", nil, 1},
+		{"replaced", &configs.GormConfig{Preloads: []configs.GormPreloadConfig{}}, 0},
+		{"extended", &configs.GormConfig{AdditionalPreloads: []configs.GormPreloadConfig{{Relation: "Products", UnScoped: true}}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := r.FindOneByPK(ctx, 1, tt.config)
+			if err != nil || found == nil {
+				t.Fatalf("FindOneByPK() = %v, %v", found, err)
+			}
+			if len(found.Products) != tt.products {
+				t.Errorf("loaded %d products, want %d", len(found.Products), tt.products)
+			}
+		})
+	}
+}
+
+type categoryStats struct {
+	ID     uint
+	Name   string
+	Extras models.Extras `gorm:"-"`
+}
+
+func (categoryStats) TableName() string { return "categories" }
+
+func TestExtras(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&[]category{
+		{Name: "books", Products: []product{{Name: "b1"}, {Name: "b2"}}},
+		{Name: "games"},
+	})
+	r := NewGormRepository[categoryStats](db, &configs.GormConfig{
+		Joins: "LEFT JOIN products ON products.category_id = categories.id",
+		Group: "categories.id",
+		SelectHandler: func(lang string) []configs.GormSelectField {
+			return []configs.GormSelectField{
+				{Column: "categories.id", Alias: "id"},
+				{Column: "categories.name", Alias: "name"},
+				{Column: "COUNT(products.id)", Alias: "product_count"},
+			}
+		},
+	}, "categories")
+	ctx := context.Background()
+
+	response, err := r.FindAllWithPaging(ctx, nil, &dto.BaseFilterDto{SortKey: ptr("categories.id"), SortDir: ptr("ASC")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range response.Data {
+		got = append(got, fmt.Sprintf("%s=%v", row.Name, row.Extras["product_count"]))
+	}
+	if want := []string{"books=2", "games=0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll() = %v, want %v", got, want)
+	}
+
+	found, err := r.FindOne(ctx, Eq("categories.id", 1), nil)
+	if err != nil || found == nil || found.Extras["product_count"] != int64(2) {
+		t.Errorf("FindOne() = %+v, %v; want 2 products in Extras", found, err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *configs.GormConfig
+		fields  []string // requested through the context
+		want    string   // name/status/price loaded
+		invalid bool
+	}{
+		{name: "everything", want: "p1/active/1"},
+		{name: "config fields", config: &configs.GormConfig{Fields: []string{"id", "name"}}, want: "p1//0"},
+		{name: "context fields", fields: []string{"id", "price"}, want: "//1"},
+		{name: "config fields win", config: &configs.GormConfig{Fields: []string{"status"}}, fields: []string{"price"}, want: "/active/0"},
+		{name: "not selectable", config: &configs.GormConfig{Selectable: []string{"name"}}, fields: []string{"price"}, invalid: true},
+		{name: "expression", fields: []string{"price * 2"}, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, tt.config)
+			seedProducts(t, r, 1)
+			ctx := ContextWithFields(context.Background(), tt.fields)
+
+			for name, find := range map[string]func() (*product, error){
+				"FindOne":     func() (*product, error) { return r.FindOne(ctx, Eq("id", 1), nil) },
+				"FindOneByPK": func() (*product, error) { return r.FindOneByPK(ctx, 1, nil) },
+			} {
+				found, err := find()
+				if tt.invalid {
+					if !errors.Is(err, ErrInvalidColumn) {
+						t.Errorf("%s() error = %v, want ErrInvalidColumn", name, err)
+					}
+					continue
+				}
+				if err != nil || found == nil {
+					t.Fatalf("%s() = %v, %v", name, found, err)
+				}
+				if got := fmt.Sprintf("%s/%s/%d", found.Name, found.Status, found.Price); got != tt.want {
+					t.Errorf("%s() = %s, want %s", name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindOneColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		order      string
+		columns    []string
+		selectable []string
+		want       string
+		wantErr    bool
+	}{
+		{name: "columns", columns: []string{"name"}, want: "p1/0"},
+		{name: "ordered", order: "price DESC", columns: []string{"name", "price"}, want: "p3/3"},
+		{name: "none", wantErr: true},
+		{name: "not selectable", columns: []string{"price"}, selectable: []string{"name"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{FindOneOrder: tt.order, Selectable: tt.selectable})
+			seedProducts(t, r, 3)
+
+			found, err := r.FindOneColumns(context.Background(), Gt("price", 0), tt.columns, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("FindOneColumns() = %+v, want an error", found)
+				}
+				return
+			}
+			if err != nil || found == nil {
+				t.Fatalf("FindOneColumns() = %v, %v", found, err)
+			}
+			if got := fmt.Sprintf("%s/%d", found.Name, found.Price); got != tt.want {
+				t.Errorf("FindOneColumns() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindOneOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  string
+		config *configs.GormConfig
+		want   string
+	}{
+		{"primary key", "", nil, "p1"},
+		{"configured", "price DESC", nil, "p3"},
+		{"per call", "", &configs.GormConfig{FindOneOrder: "name DESC"}, "p3"},
+		{"ties broken by key", "status", nil, "p1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{FindOneOrder: tt.order})
+			seedProducts(t, r, 3)
+
+			found, err := r.FindOne(context.Background(), nil, tt.config)
+			if err != nil || found == nil || found.Name != tt.want {
+				t.Errorf("FindOne() = %v, %v; want %s", found, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortNulls(t *testing.T) {
+	tests := []struct {
+		name  string
+		nulls configs.GormSortNulls
+		dir   string
+		names []string
+	}{
+		{"first ascending", configs.GormSortNullsFirst, "ASC", []string{"p1", "p2", "p3"}},
+		{"last ascending", configs.GormSortNullsLast, "ASC", []string{"p2", "p3", "p1"}},
+		{"first descending", configs.GormSortNullsFirst, "DESC", []string{"p1", "p3", "p2"}},
+		{"last descending", configs.GormSortNullsLast, "DESC", []string{"p3", "p2", "p1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{Sortable: map[string]configs.GormSortProperty{
+				"sku": {Nulls: tt.nulls},
+			}})
+			seedProducts(t, r, 3)
+			r.DB.Model(&product{}).Where("id = ?", 2).Update("sku", "a")
+			r.DB.Model(&product{}).Where("id = ?", 3).Update("sku", "b")
+
+			rows, err := r.FindAll(context.Background(), nil, &dto.BaseFilterDto{SortKey: ptr("sku"), SortDir: &tt.dir}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, row := range rows {
+				names = append(names, row.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("rows = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestSortByJoinedColumn(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{Sortable: map[string]configs.GormSortProperty{
+		"category": {Column: "categories.name", Join: "LEFT JOIN categories ON categories.id = products.category_id", Nulls: configs.GormSortNullsLast},
+	}})
+	seedCatalog(t, r)
+
+	filter := &dto.BaseFilterDto{SortKey: ptr("category"), SortDir: ptr("DESC")}
+	response, err := r.FindAllWithPaging(context.Background(), nil, filter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, row := range response.Data {
+		names = append(names, row.Name)
+	}
+	if names[0] != "p3" || response.Total != 5 {
+		t.Errorf("rows = %v of %d, want p3 (games) first of 5", names, response.Total)
+	}
+}
+
+func TestWithScopes(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 4)
+	ctx := context.Background()
+
+	active := r.WithScopes(func(db *gorm.DB) *gorm.DB { return db.Where("status = ?", "active") })
+	cheap := active.WithScopes(func(db *gorm.DB) *gorm.DB { return db.Where("price < ?", 3) })
+
+	tests := []struct {
+		name  string
+		repo  *GormRepository[product]
+		count int64
+	}{
+		{"unscoped", r, 4},
+		{"scoped", active, 2},
+		{"scopes add up", cheap, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if count, err := tt.repo.Count(ctx, nil); err != nil || count != tt.count {
+				t.Errorf("Count() = %d, %v; want %d", count, err, tt.count)
+			}
+			rows, err := tt.repo.FindAll(ctx, nil, &dto.BaseFilterDto{}, nil)
+			if err != nil || int64(len(rows)) != tt.count {
+				t.Errorf("FindAll() = %d rows, %v; want %d", len(rows), err, tt.count)
+			}
+		})
+	}
+}
+
+func TestScanInto(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 5)
+	r.DB.Delete(&product{}, 5)
+
+	type statusTotal struct {
+		Status string
+		Total  int
+	}
+	rows, err := ScanInto[statusTotal](context.Background(), r, func(db *gorm.DB) *gorm.DB {
+		return db.Select("status, SUM(price) AS total").Group("status").Order("status")
+	})
+	want := []statusTotal{{"active", 4}, {"draft", 6}} // p5 is deleted
+	if err != nil || !reflect.DeepEqual(rows, want) {
+		t.Errorf("ScanInto() = %v, %v; want %v", rows, err, want)
+	}
+}
+
+func TestInSet(t *testing.T) {
+	defer func(threshold int) { LargeSetThreshold = threshold }(LargeSetThreshold)
+	LargeSetThreshold = 3
+
+	tests := []struct {
+		name   string
+		values any
+		query  string
+		count  int64
+	}{
+		{"small set", []int{1, 2}, "id IN (?)", 2},
+		{"large set", []int{1, 2, 4, 6, 99}, "id IN (SELECT value FROM json_each(?))", 3},
+		{"large set of strings", []string{"p1", "p2", "p3", "x"}, "name IN (SELECT value FROM json_each(?))", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, nil)
+			seedProducts(t, r, 5)
+
+			column := "id"
+			if _, ok := tt.values.([]string); ok {
+				column = "name"
+			}
+			condition := InSet(column, tt.values)
+			if query := condition.BuildFor(DialectSQLite)["query"]; query != tt.query {
+				t.Errorf("query = %q, want %q", query, tt.query)
+			}
+			if count, err := r.Count(context.Background(), condition); err != nil || count != tt.count {
+				t.Errorf("Count() = %d, %v; want %d", count, err, tt.count)
+			}
+		})
+	}
+}
+
+func TestInSetDialects(t *testing.T) {
+	defer func(threshold int) { LargeSetThreshold = threshold }(LargeSetThreshold)
+	LargeSetThreshold = 1
+
+	values := []any{1, `a"b`}
+	tests := []struct {
+		dialect Dialect
+		query   string
+		arg     any
+	}{
+		{DialectPostgres, "id = ANY(?)", postgresArray(values)},
+		{DialectMySQL, "id IN (SELECT v FROM JSON_TABLE(?, '$[*]' COLUMNS (v VARCHAR(255) PATH '$')) AS set_values)", `[1,"a\"b"]`},
+		{DialectSQLServer, "id IN (SELECT value FROM OPENJSON(?))", `[1,"a\"b"]`},
+		{DialectDefault, "id IN (?)", values},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			built := InSet("id", values).BuildFor(tt.dialect)
+			if built["query"] != tt.query || !reflect.DeepEqual(built["args"], []any{tt.arg}) {
+				t.Errorf("BuildFor() = %q %#v, want %q %#v", built["query"], built["args"], tt.query, tt.arg)
+			}
+		})
+	}
+}
+
+func TestInSetPostgres(t *testing.T) {
+	t.Run("array types", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			values []any
+			want   driver.Value
+		}{
+			{"integers", []any{1, int64(2), uint(3)}, []int64{1, 2, 3}},
+			{"numbers", []any{1, 2.5}, []float64{1, 2.5}},
+			{"strings", []any{"a", `b"c`}, []string{"a", `b"c`}},
+			{"mixed", []any{1, "a"}, []string{"1", "a"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got, err := postgresArray(tt.values).Value(); err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Value() = %#v, %v; want %#v", got, err, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("large set", func(t *testing.T) {
+		r, _ := newPostgresLikeRepository(t, nil)
+		ids := make([]any, 5000)
+		for i := range ids {
+			ids[i] = i + 1
+		}
+		var rows []product
+		stmt := r.BuildQueryConditions(context.Background(), InSet("id", ids), nil).
+			Session(&gorm.Session{DryRun: true}).Find(&rows).Statement
+		if sql := stmt.SQL.String(); !strings.Contains(sql, "WHERE id = ANY(?)") {
+			t.Errorf("SQL = %s, want id = ANY(?)", sql)
+		}
+		if len(stmt.Vars) != 1 {
+			t.Fatalf("bound %d parameters, want the set as one", len(stmt.Vars))
+		}
+		value, err := stmt.Vars[0].(driver.Valuer).Value()
+		if array, ok := value.([]int64); err != nil || !ok || len(array) != 5000 || array[4999] != 5000 {
+			t.Errorf("parameter = %T, %v; want the 5000 ids as a bigint array", value, err)
+		}
+	})
+}
+
+func TestSQLComment(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{SQLComment: true})
+	seedProducts(t, r, 2)
+	recorder := &middlewares.SQLRecorder{}
+	ctx := middlewares.WithSQLRecorder(middlewares.SetRequestID(context.Background(), "req-1*/ DROP"), recorder)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"read", func() error { _, err := r.FindAll(ctx, nil, &dto.BaseFilterDto{}, nil); return err }, "/* model=product op=FindAll req=req-1DROP */ SELECT"},
+		{"count", func() error { _, err := r.Count(ctx, nil); return err }, "/* model=product op=Count req=req-1DROP */ SELECT count(*)"},
+		{"update", func() error { return r.UpdateByPK(ctx, 1, map[string]any{"name": "x"}) }, "/* model=product op=UpdateByPK req=req-1DROP */ UPDATE"},
+		{"inside a transaction", func() error {
+			return r.Transaction(ctx, func(ctx context.Context) error { return r.DeleteOneByPK(ctx, 2) })
+		}, "/* model=product op=DeleteOneByPK req=req-1DROP */ UPDATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(recorder.Queries())
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			queries := recorder.Queries()[before:]
+			if len(queries) == 0 || !strings.HasPrefix(queries[len(queries)-1], tt.want) {
+				t.Errorf("queries = %q, want one starting with %q", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGormRepositoryFromModel(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		name  string
+		table func() (string, error)
+		want  string
+	}{
+		{"naming strategy", func() (string, error) {
+			r, err := NewGormRepositoryFromModel[product](db, nil)
+			if err != nil {
+				return "", err
+			}
+			return r.TableName, nil
+		}, "products"},
+		{"TableName method", func() (string, error) {
+			r, err := NewGormRepositoryFromModel[categoryStats](db, nil)
+			if err != nil {
+				return "", err
+			}
+			return r.TableName, nil
+		}, "categories"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.table(); err != nil || got != tt.want {
+				t.Errorf("TableName = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := NewGormRepositoryFromModel[int](db, nil); err == nil {
+		t.Error("NewGormRepositoryFromModel[int]() succeeded")
+	}
+}
+
+func TestSingleEntityReads(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 3)
+	ctx := context.Background()
+
+	if err := r.Ping(ctx); err != nil {
+		t.Errorf("Ping() = %v", err)
+	}
+	if exists, err := r.ExistsByPK(ctx, 2); err != nil || !exists {
+		t.Errorf("ExistsByPK(2) = %v, %v; want true", exists, err)
+	}
+	if exists, err := r.ExistsByPK(ctx, 9); err != nil || exists {
+		t.Errorf("ExistsByPK(9) = %v, %v; want false", exists, err)
+	}
+	if found, err := r.FindOneByPK(ctx, 9, nil); err != nil || found != nil {
+		t.Errorf("FindOneByPK(9) = %v, %v; want nil, nil", found, err)
+	}
+	if names, err := r.Pluck(ctx, "name", Lt("price", 3)); err != nil || len(names) != 2 {
+		t.Errorf("Pluck() = %v, %v; want 2 names", names, err)
+	}
+
+	updated, err := r.UpdateByPKReturning(ctx, 2, map[string]any{"name": "two"}, nil)
+	if err != nil || updated == nil || updated.Name != "two" || updated.Price != 2 {
+		t.Errorf("UpdateByPKReturning() = %+v, %v; want two", updated, err)
+	}
+	if updated, err := r.UpdateByPKReturning(ctx, 9, map[string]any{"name": "x"}, nil); err != nil || updated != nil {
+		t.Errorf("UpdateByPKReturning(9) = %+v, %v; want nil, nil", updated, err)
+	}
+
+	found, created, err := r.FindOrCreate(ctx, Eq("name", "p1"), product{Name: "p1"}, nil)
+	if err != nil || created || found.ID != 1 {
+		t.Errorf("FindOrCreate(p1) = %+v, %v, %v; want the existing row", found, created, err)
+	}
+	found, created, err = r.FindOrCreate(ctx, Eq("name", "p9"), product{Name: "p9"}, nil)
+	if err != nil || !created || found.ID != 4 {
+		t.Errorf("FindOrCreate(p9) = %+v, %v, %v; want a new row", found, created, err)
+	}
+
+	sqlDB, err := r.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	if err := r.Ping(ctx); err == nil {
+		t.Error("Ping() = nil after the database was closed, want an error")
+	}
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// selectPattern matches the select expressions GroupByScan accepts: a column, or one of
+// COUNT/SUM/AVG/MIN/MAX/DATE applied to a column (COUNT also to *, and to DISTINCT column),
+// each with an optional alias.
+var selectPattern = regexp.MustCompile(`(?i)^(?:(count|sum|avg|min|max|date)\(\s*(distinct\s+)?(\*|[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)?)\s*\)|([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)?))(?:\s+as\s+([A-Za-z_][A-Za-z0-9_]*))?$`)
+
+// GroupByScan runs an aggregate query over the entities matching conditions and returns its
+// rows as maps keyed by column or alias, for reports that don't fit T:
+//
+//	rows, err := repo.GroupByScan(ctx, []string{"DATE(created_at) AS day", "SUM(total) AS sales"}, "day", nil)
+//
+// groupBy is a non-empty, comma-separated list of columns or select aliases; the rows are
+// ordered by it. Columns used in selects and groupBy are validated against Selectable.
+func (r *GormRepository[T]) GroupByScan(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error) {
+	if len(selects) == 0 {
+		return nil, fmt.Errorf("no columns to select")
+	}
+	if strings.TrimSpace(groupBy) == "" {
+		return nil, fmt.Errorf("no columns to group by")
+	}
+
+	allowed := r.config().Selectable
+	columns := make([]string, len(selects))
+	var aliases []string
+	for i, expr := range selects {
+		expr = strings.TrimSpace(expr)
+		match := selectPattern.FindStringSubmatch(expr)
+		if match == nil {
+			return nil, fmt.Errorf("invalid select: %s", expr)
+		}
+		column := match[3] + match[4]
+		if column == "*" {
+			if !strings.EqualFold(match[1], "count") || match[2] != "" {
+				return nil, fmt.Errorf("invalid select: %s", expr)
+			}
+		} else if err := validateColumns([]string{column}, allowed); err != nil {
+			return nil, err
+		}
+		if match[5] != "" {
+			aliases = append(aliases, match[5])
+		}
+		columns[i] = expr
+	}
+
+	var groups []string
+	for _, group := range strings.Split(groupBy, ",") {
+		group = strings.TrimSpace(group)
+		if slices.Contains(aliases, group) {
+			groups = append(groups, group)
+			continue
+		}
+		if err := validateColumns([]string{group}, allowed); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	groupBy = strings.Join(groups, ", ")
+
+	var rows []map[string]any
+	err := r.BuildQueryConditions(ctx, conditions, r.config()).
+		Select(columns).
+		Group(groupBy).
+		Order(groupBy).
+		Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+func TestGroupByScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		selectable []string
+		selects    []string
+		groupBy    string
+		want       []string // the rows, as "group=aggregate"
+		wantErr    bool
+	}{
+		{
+			name:    "count per column",
+			selects: []string{"status", "COUNT(*) AS total"},
+			groupBy: "status",
+			want:    []string{"active=3", "draft=2"},
+		},
+		{
+			name:    "sum by alias",
+			selects: []string{"status AS state", "SUM(price) AS total"},
+			groupBy: "state",
+			want:    []string{"active=9", "draft=6"},
+		},
+		{
+			name:    "count distinct",
+			selects: []string{"status", "count(distinct price) AS total"},
+			groupBy: "status",
+			want:    []string{"active=3", "draft=2"},
+		},
+		{name: "no group", selects: []string{"COUNT(*) AS total"}, groupBy: " ", wantErr: true},
+		{name: "no selects", groupBy: "status", wantErr: true},
+		{name: "sum of star", selects: []string{"SUM(*)"}, groupBy: "status", wantErr: true},
+		{name: "expression", selects: []string{"status"}, groupBy: "status; DROP TABLE products", wantErr: true},
+		{name: "not selectable", selectable: []string{"status"}, selects: []string{"status", "SUM(price)"}, groupBy: "status", wantErr: true},
+		{name: "group not selectable", selectable: []string{"price"}, selects: []string{"SUM(price) AS total"}, groupBy: "status", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{Selectable: tt.selectable})
+			seedProducts(t, r, 5)
+
+			rows, err := r.GroupByScan(context.Background(), tt.selects, tt.groupBy, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GroupByScan() = %v, want an error", rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, row := range rows {
+				group := row["status"]
+				if group == nil {
+					group = row["state"]
+				}
+				got = append(got, fmt.Sprintf("%v=%v", group, row["total"]))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
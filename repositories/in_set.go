@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// LargeSetThreshold is the number of values from which InSet stops binding one parameter per
+// value and passes the whole set as a single array/JSON parameter instead.
+var LargeSetThreshold = 1000
+
+// InSet creates a condition: column IN (values), for sets that may be very large (e.g. 50k
+// ids received from another service). Up to LargeSetThreshold values it is the same as In.
+// Above it, the set is sent as one parameter and expanded by the database, which avoids
+// parameter limits and keeps the statement small:
+//
+//	Postgres:   column = ANY($1)                                 -- $1 = ARRAY[1,2,3]
+//	MySQL:      column IN (SELECT v FROM JSON_TABLE(?, ...))     -- ? = '[1,2,3]'
+//	SQLite:     column IN (SELECT value FROM json_each(?))
+//	SQL Server: column IN (SELECT value FROM OPENJSON(?))
+//
+// Values must be strings or numbers. Other databases always get a plain IN.
+func InSet(column string, values any) *Condition {
+	items, _ := normalizeSlice(values).([]any)
+	if len(items) <= LargeSetThreshold {
+		return In(column, values)
+	}
+
+	return &Condition{
+		parts: []conditionPart{
+			{
+				build: func(dialect Dialect) (string, []any) {
+					switch dialect {
+					case DialectPostgres:
+						return fmt.Sprintf("%s = ANY(?)", column), []any{postgresArray(items)}
+					case DialectMySQL:
+						return fmt.Sprintf("%s IN (SELECT v FROM JSON_TABLE(?, '$[*]' COLUMNS (v VARCHAR(255) PATH '$')) AS set_values)", column), []any{jsonArray(items)}
+					case DialectSQLite:
+						return fmt.Sprintf("%s IN (SELECT value FROM json_each(?))", column), []any{jsonArray(items)}
+					case DialectSQLServer:
+						return fmt.Sprintf("%s IN (SELECT value FROM OPENJSON(?))", column), []any{jsonArray(items)}
+					}
+					return fmt.Sprintf("%s IN (?)", column), []any{items}
+				},
+			},
+		},
+	}
+}
+
+// postgresArray is a set bound as a single Postgres array parameter. GORM expands a slice
+// argument into one placeholder per element but binds a driver.Valuer as is; the driver
+// (pgx) then encodes the typed slice Value returns as an array: bigint[] for integers,
+// double precision[] for other numbers, text[] otherwise.
+type postgresArray []any
+
+func (a postgresArray) Value() (driver.Value, error) {
+	ints, floats, strs := make([]int64, len(a)), make([]float64, len(a)), make([]string, len(a))
+	allInts, allNumbers := true, true
+	for i, item := range a {
+		v := reflect.ValueOf(item)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			ints[i], floats[i] = v.Int(), float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			ints[i], floats[i] = int64(v.Uint()), float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			floats[i] = v.Float()
+			allInts = false
+		default:
+			allInts, allNumbers = false, false
+		}
+		strs[i] = fmt.Sprint(item)
+	}
+	switch {
+	case allInts:
+		return ints, nil
+	case allNumbers:
+		return floats, nil
+	}
+	return strs, nil
+}
+
+// jsonArray renders items as a JSON array.
+func jsonArray(items []any) string {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
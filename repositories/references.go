@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// ErrReferenceNotFound is wrapped by the 422 models.CrudError the creates return when a
+// CheckReferences column points at a row that doesn't exist (or is soft-deleted).
+var ErrReferenceNotFound = errors.New("referenced row not found")
+
+// checkReferences verifies that the rows entities reference through CheckReferences exist.
+// Each referenced table is read once per IDChunkSize distinct keys, whatever the number of
+// entities.
+func (r *GormRepository[T]) checkReferences(ctx context.Context, entities ...T) error {
+	references := r.config().CheckReferences
+	if len(references) == 0 || len(entities) == 0 {
+		return nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	for _, column := range slices.Sorted(maps.Keys(references)) {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("CheckReferences: unknown column %s on %s", column, s.Name)
+		}
+
+		// distinct keys, in the order the entities use them
+		var values []any
+		seen := map[any]bool{}
+		for i := range entities {
+			value, zero := field.ValueOf(ctx, reflect.ValueOf(&entities[i]).Elem())
+			if zero {
+				continue
+			}
+			value = reflect.Indirect(reflect.ValueOf(value)).Interface()
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		reference := references[column]
+		found, err := r.referencedKeys(ctx, reference, values)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if !found[fmt.Sprint(value)] {
+				messageID := cmp.Or(reference.MessageID, "reference_not_found")
+				cause := fmt.Errorf("%w: %s = %v", ErrReferenceNotFound, field.DBName, value)
+				return models.NewCrudError(http.StatusUnprocessableEntity, messageID, cause)
+			}
+		}
+	}
+	return nil
+}
+
+// referencedKeys returns which of values exist in reference's table (in their fmt.Sprint
+// form), leaving out soft-deleted rows.
+func (r *GormRepository[T]) referencedKeys(ctx context.Context, reference configs.GormReference, values []any) (map[string]bool, error) {
+	column := cmp.Or(reference.Column, "id")
+	deleted := r.referenceDeletedColumn(ctx, reference)
+
+	found := map[string]bool{}
+	for chunk := range slices.Chunk(values, r.config().ChunkSize()) {
+		query := r.db(ctx).Table(reference.Table).
+			Where(clause.IN{Column: clause.Column{Name: column}, Values: chunk})
+		if deleted != "" {
+			var alive any // NULL: not deleted
+			if reference.SoftDeleteStrategy == configs.SoftDeleteBoolean {
+				alive = false
+			}
+			query = query.Where(clause.Eq{Column: clause.Column{Name: deleted}, Value: alive})
+		}
+		var keys []any
+		if err := query.Pluck(column, &keys).Error; err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if bytes, ok := key.([]byte); ok { // some drivers scan text keys as bytes
+				key = string(bytes)
+			}
+			found[fmt.Sprint(key)] = true
+		}
+	}
+	return found, nil
+}
+
+// referenceDeletedColumn returns the soft-delete column of reference's table: its
+// SoftDeleteColumn, or "deleted_at" when the table has one, "" otherwise.
+func (r *GormRepository[T]) referenceDeletedColumn(ctx context.Context, reference configs.GormReference) string {
+	if reference.SoftDeleteColumn != "" {
+		return reference.SoftDeleteColumn
+	}
+	if !r.hasColumn(ctx, reference.Table, "deleted_at") {
+		return ""
+	}
+	return "deleted_at"
+}
+
+// columnKey identifies a column of a table in the database a *gorm.DB was opened on.
+type columnKey struct {
+	db            *gorm.Config
+	table, column string
+}
+
+// columns caches hasColumn's answers, which only change with a migration.
+var knownColumns sync.Map // columnKey -> bool
+
+// hasColumn reports whether table has column, asking the database once per table and column.
+func (r *GormRepository[T]) hasColumn(ctx context.Context, table, column string) bool {
+	key := columnKey{db: r.DB.Config, table: table, column: column}
+	if has, ok := knownColumns.Load(key); ok {
+		return has.(bool)
+	}
+	has := r.db(ctx).Migrator().HasColumn(table, column)
+	knownColumns.Store(key, has)
+	return has
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// createVia runs one of the create paths with the given products and returns its error; a
+// skipped BulkCreatePartial item counts as its error.
+func createVia(ctx context.Context, r *GormRepository[product], path string, products ...product) error {
+	items := make([]any, len(products))
+	for i, p := range products {
+		items[i] = p
+	}
+	switch path {
+	case "Create":
+		_, err := r.Create(ctx, products[0])
+		return err
+	case "BulkCreate":
+		_, err := r.BulkCreate(ctx, items)
+		return err
+	case "BulkCreateReturning":
+		_, err := r.BulkCreateReturning(ctx, items)
+		return err
+	case "BulkCreatePartial":
+		_, failures, err := r.BulkCreatePartial(ctx, items)
+		if err == nil && len(failures) > 0 {
+			err = failures[0]
+		}
+		return err
+	case "CreateOrUpdate":
+		_, err := r.CreateOrUpdate(ctx, products[0], nil, nil)
+		return err
+	case "FindOrCreate":
+		_, _, err := r.FindOrCreate(ctx, Eq("name", products[0].Name), products[0], nil)
+		return err
+	}
+	panic("unknown create path " + path)
+}
+
+var createPaths = []string{"Create", "BulkCreate", "BulkCreateReturning", "BulkCreatePartial", "CreateOrUpdate", "FindOrCreate"}
+
+func TestCheckReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		reference  configs.GormReference
+		categoryID *uint
+		missing    bool
+	}{
+		{"existing parent", configs.GormReference{Table: "categories"}, ptr[uint](1), false},
+		{"unset key", configs.GormReference{Table: "categories"}, nil, false},
+		{"missing parent", configs.GormReference{Table: "categories"}, ptr[uint](9), true},
+		{"soft-deleted parent", configs.GormReference{Table: "categories"}, ptr[uint](2), true},
+		{"explicit column", configs.GormReference{Table: "categories", Column: "id", SoftDeleteColumn: "deleted_at"}, ptr[uint](2), true},
+	}
+	for _, path := range createPaths {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				r := newTestRepository(t, &configs.GormConfig{
+					CheckReferences: map[string]configs.GormReference{"category_id": tt.reference},
+				})
+				r.DB.Create(&[]category{{Name: "books"}, {Name: "games"}})
+				r.DB.Delete(&category{}, 2)
+
+				err := createVia(context.Background(), r, path, product{Name: "new", CategoryID: tt.categoryID})
+				if got := errors.Is(err, ErrReferenceNotFound); got != tt.missing {
+					t.Fatalf("error = %v, want missing reference %v", err, tt.missing)
+				}
+				var crudErr *models.CrudError
+				if tt.missing && (!errors.As(err, &crudErr) || crudErr.Code != http.StatusUnprocessableEntity) {
+					t.Errorf("error = %v, want a 422 CrudError", err)
+				}
+				if !tt.missing && err != nil {
+					t.Errorf("error = %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckReferencesChunksKeys(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{
+		IDChunkSize:     3,
+		CheckReferences: map[string]configs.GormReference{"CategoryID": {Table: "categories"}},
+	})
+	categories := make([]category, 10)
+	for i := range categories {
+		categories[i].Name = fmt.Sprint("c", i)
+	}
+	r.DB.Create(&categories)
+
+	// 20 products over 10 categories: 4 chunked queries, each key checked once
+	var queries int
+	r.DB.Callback().Query().Before("gorm:query").Register("test:count", func(db *gorm.DB) {
+		if db.Statement.Table == "categories" {
+			queries++
+		}
+	})
+	items := make([]any, 20)
+	for i := range items {
+		items[i] = product{Name: fmt.Sprint("p", i), CategoryID: ptr(uint(i%10 + 1))}
+	}
+	if _, err := r.BulkCreate(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+	if queries != 4 {
+		t.Errorf("ran %d reference queries, want 4", queries)
+	}
+
+	items = append(items, product{Name: "orphan", CategoryID: ptr[uint](11)})
+	if _, err := r.BulkCreate(context.Background(), items); !errors.Is(err, ErrReferenceNotFound) {
+		t.Errorf("BulkCreate() error = %v, want ErrReferenceNotFound", err)
+	}
+}
+
+func TestReferenceDeletedColumnCached(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{
+		CheckReferences: map[string]configs.GormReference{"category_id": {Table: "categories"}},
+	})
+	r.DB.Create(&category{Name: "books"})
+
+	// the migrator reads the table's columns through a Row statement
+	var lookups int
+	r.DB.Callback().Row().Before("gorm:row").Register("test:count", func(*gorm.DB) { lookups++ })
+	for i := range 3 {
+		if _, err := r.Create(context.Background(), product{Name: fmt.Sprint("p", i), CategoryID: ptr[uint](1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("looked up the deleted_at column %d times, want 1", lookups)
+	}
+}
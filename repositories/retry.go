@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+// IsTransientError reports whether err is likely to succeed when the operation runs again:
+// a deadlock, a serialization failure, a lock wait timeout, a busy SQLite database or a
+// dropped connection. Cancelled and timed-out contexts are not transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Postgres (pgx, lib/pq): serialization_failure, deadlock_detected, connection exceptions
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		return state == "40001" || state == "40P01" || strings.HasPrefix(state, "08")
+	}
+
+	message := err.Error()
+	for _, transient := range []string{
+		"Error 1213", // MySQL: deadlock found
+		"Error 1205", // MySQL: lock wait timeout exceeded
+		"database is locked",
+		"connection reset by peer",
+		"broken pipe",
+	} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryableWriteError reports whether a write that failed with err is known not to have
+// been applied, so running it again can't apply it twice: a deadlock, a serialization
+// failure or a connection the driver reports as bad before using it. A dropped connection
+// mid-statement is not: the write may have committed.
+func IsRetryableWriteError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		return state == "40001" || state == "40P01"
+	}
+	return strings.Contains(err.Error(), "Error 1213") // MySQL: deadlock found
+}
+
+// retry runs the read fn under the repository's Retry policy, waiting with exponential
+// backoff between runs. Inside a transaction fn runs once: a failed statement aborts the
+// whole transaction, which is retried by the outermost Transaction instead.
+func (r *GormRepository[T]) retry(ctx context.Context, fn func() error) error {
+	policy := r.config().Retry
+	if policy == nil {
+		return fn()
+	}
+	transient := policy.IsTransient
+	if transient == nil {
+		transient = IsTransientError
+	}
+	return r.retryOn(ctx, transient, fn)
+}
+
+// retryWrite is retry for a write: it runs again only on the errors of
+// GormRetry.IsRetryableWrite, which leave the write unapplied.
+func (r *GormRepository[T]) retryWrite(ctx context.Context, fn func() error) error {
+	policy := r.config().Retry
+	if policy == nil {
+		return fn()
+	}
+	retryable := policy.IsRetryableWrite
+	if retryable == nil {
+		retryable = IsRetryableWriteError
+	}
+	return r.retryOn(ctx, retryable, fn)
+}
+
+func (r *GormRepository[T]) retryOn(ctx context.Context, transient func(err error) bool, fn func() error) error {
+	if _, inTx := TxFromContext(ctx); inTx {
+		return fn()
+	}
+
+	policy := r.config().Retry
+	attempts := policy.Attempts
+	if attempts <= 0 {
+		attempts = configs.DefaultRetryAttempts
+	}
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = configs.DefaultRetryBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || !transient(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
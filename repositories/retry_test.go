@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestRetryErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+		write     bool
+	}{
+		{"nil", nil, false, false},
+		{"postgres serialization failure", sqlStateError("40001"), true, true},
+		{"postgres deadlock", fmt.Errorf("update: %w", sqlStateError("40P01")), true, true},
+		{"postgres connection failure", sqlStateError("08006"), true, false},
+		{"postgres unique violation", sqlStateError("23505"), false, false},
+		{"mysql deadlock", errors.New("Error 1213 (40001): Deadlock found"), true, true},
+		{"mysql lock wait timeout", errors.New("Error 1205 (HY000): Lock wait timeout exceeded"), true, false},
+		{"sqlite busy", errors.New("database is locked"), true, false},
+		{"bad connection", fmt.Errorf("query: %w", driver.ErrBadConn), true, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true, false},
+		{"connection reset", errors.New("read tcp: connection reset by peer"), true, false},
+		{"broken pipe", errors.New("write tcp: broken pipe"), true, false},
+		{"cancelled", context.Canceled, false, false},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), false, false},
+		{"other", errors.New("syntax error"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.transient {
+				t.Errorf("IsTransientError() = %v, want %v", got, tt.transient)
+			}
+			if got := IsRetryableWriteError(tt.err); got != tt.write {
+				t.Errorf("IsRetryableWriteError() = %v, want %v", got, tt.write)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	deadlock := sqlStateError("40P01")
+	reset := errors.New("connection reset by peer")
+	tests := []struct {
+		name     string
+		policy   *configs.GormRetry
+		write    bool
+		inTx     bool
+		errs     []error // returned by the successive runs, nil afterwards
+		runs     int
+		wantFail bool
+	}{
+		{"no policy", nil, false, false, []error{deadlock}, 1, true},
+		{"read retried until success", &configs.GormRetry{Attempts: 3}, false, false, []error{deadlock, reset}, 3, false},
+		{"attempts cap", &configs.GormRetry{Attempts: 2}, false, false, []error{deadlock, deadlock, deadlock}, 2, true},
+		{"permanent error", &configs.GormRetry{Attempts: 3}, false, false, []error{errors.New("syntax error")}, 1, true},
+		{"write retried on deadlock", &configs.GormRetry{Attempts: 3}, true, false, []error{deadlock}, 2, false},
+		{"write not retried on reset", &configs.GormRetry{Attempts: 3}, true, false, []error{reset}, 1, true},
+		{"custom write classifier", &configs.GormRetry{Attempts: 3, IsRetryableWrite: func(err error) bool { return err == reset }}, true, false, []error{reset}, 2, false},
+		{"custom read classifier", &configs.GormRetry{Attempts: 3, IsTransient: func(error) bool { return false }}, false, false, []error{deadlock}, 1, true},
+		{"inside a transaction", &configs.GormRetry{Attempts: 3}, false, true, []error{deadlock}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.policy != nil {
+				tt.policy.Backoff = time.Microsecond
+			}
+			r := &GormRepository[struct{}]{Config: &configs.GormConfig{Retry: tt.policy}}
+			ctx := context.Background()
+			if tt.inTx {
+				ctx = ContextWithTx(ctx, &gorm.DB{})
+			}
+
+			runs := 0
+			fn := func() error {
+				runs++
+				if runs <= len(tt.errs) {
+					return tt.errs[runs-1]
+				}
+				return nil
+			}
+			var err error
+			if tt.write {
+				err = r.retryWrite(ctx, fn)
+			} else {
+				err = r.retry(ctx, fn)
+			}
+			if runs != tt.runs {
+				t.Errorf("runs = %d, want %d", runs, tt.runs)
+			}
+			if (err != nil) != tt.wantFail {
+				t.Errorf("err = %v, want failure %v", err, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	r := &GormRepository[struct{}]{Config: &configs.GormConfig{
+		Retry: &configs.GormRetry{Attempts: 5, Backoff: time.Hour},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	err := r.retry(ctx, func() error {
+		runs++
+		cancel()
+		return sqlStateError("40001")
+	})
+	if runs != 1 || err == nil {
+		t.Errorf("runs = %d, err = %v; want a single failed run", runs, err)
+	}
+}
+
+func TestRetriedWrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, r *GormRepository[product]) error
+		want  []string // the product names left afterwards
+	}{
+		{
+			"DeleteByIDs",
+			func(ctx context.Context, r *GormRepository[product]) error {
+				return r.DeleteByIDs(ctx, []any{1, 2})
+			},
+			[]string{"p3"},
+		},
+		{
+			"DeleteByIDs in chunks",
+			func(ctx context.Context, r *GormRepository[product]) error {
+				return r.DeleteByIDs(ctx, []any{1, 2, 3})
+			},
+			nil,
+		},
+		{
+			"CreateOrUpdate",
+			func(ctx context.Context, r *GormRepository[product]) error {
+				_, err := r.CreateOrUpdate(ctx, product{Base: models.Base{ID: 2}, Name: "renamed"}, []string{"id"}, []string{"name"})
+				return err
+			},
+			[]string{"p1", "renamed", "p3"},
+		},
+		{
+			"BulkUpdate",
+			func(ctx context.Context, r *GormRepository[product]) error {
+				items := []product{{Base: models.Base{ID: 1}, Name: "a"}, {Base: models.Base{ID: 3}, Name: "c"}}
+				return r.BulkUpdate(ctx, items, []string{"name"})
+			},
+			[]string{"a", "p2", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{
+				IDChunkSize: 2,
+				Retry:       &configs.GormRetry{Attempts: 2, Backoff: time.Microsecond},
+			})
+			seedProducts(t, r, 3)
+
+			// the first write statement fails with a serialization failure, which leaves it unapplied
+			runs := 0
+			fail := func(db *gorm.DB) {
+				if runs++; runs == 1 {
+					db.AddError(sqlStateError("40001"))
+				}
+			}
+			r.DB.Callback().Create().Before("gorm:create").Register("test:fail", fail)
+			r.DB.Callback().Update().Before("gorm:update").Register("test:fail", fail)
+			r.DB.Callback().Delete().Before("gorm:delete").Register("test:fail", fail)
+
+			if err := tt.write(context.Background(), r); err != nil {
+				t.Fatalf("write error = %v, want it retried", err)
+			}
+			if runs < 2 {
+				t.Errorf("ran %d write statements, want a retry", runs)
+			}
+			var names []string
+			if err := r.DB.Model(&product{}).Order("id").Pluck("name", &names).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ScanInto runs a report over repo's table and scans the rows into D, a struct that isn't
+// T (joins, aggregates...). build gets the query the repository starts from -- Joins, soft
+// delete, UnScoped and WithScopes applied, no select, preload or order -- and adds its own
+// clauses:
+//
+//	rows, err := repositories.ScanInto[SalesRow](ctx, orderRepo, func(db *gorm.DB) *gorm.DB {
+//		return db.Joins("JOIN customers ON customers.id = orders.customer_id").
+//			Select("customers.name, SUM(orders.total) AS total").
+//			Group("customers.name")
+//	})
+//
+// Go methods can't have type parameters, hence a function taking the repository.
+func ScanInto[D any, T any](ctx context.Context, repo *GormRepository[T], build func(db *gorm.DB) *gorm.DB) ([]D, error) {
+	var rows []D
+	err := repo.retry(ctx, func() error {
+		rows = nil
+		query := repo.BuildQueryConditions(ctx, nil, nil)
+		if repo.config().UnScoped {
+			query = query.Unscoped()
+		}
+		return build(query).Scan(&rows).Error
+	})
+	return rows, err
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+// customSoftDelete reports whether T is soft-deleted through GormConfig.SoftDeleteColumn
+// rather than a gorm.DeletedAt field.
+func (r *GormRepository[T]) customSoftDelete() bool {
+	return r.config().SoftDeleteColumn != ""
+}
+
+func (r *GormRepository[T]) softDeleteColumn() string {
+	if r.customSoftDelete() {
+		return r.config().SoftDeleteColumn
+	}
+	return "deleted_at"
+}
+
+func (r *GormRepository[T]) booleanSoftDelete() bool {
+	return r.customSoftDelete() && r.config().SoftDeleteStrategy == configs.SoftDeleteBoolean
+}
+
+// notDeleted is the value of the soft-delete column for rows that are not deleted.
+func (r *GormRepository[T]) notDeleted() any {
+	if r.booleanSoftDelete() {
+		return false
+	}
+	return nil
+}
+
+// excludeDeleted filters out rows marked through SoftDeleteColumn. gorm.DeletedAt models
+// are filtered by GORM itself.
+func (r *GormRepository[T]) excludeDeleted(query *gorm.DB) *gorm.DB {
+	if !r.customSoftDelete() {
+		return query
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: r.config().SoftDeleteColumn}
+	return query.Where(clause.Eq{Column: column, Value: r.notDeleted()})
+}
+
+// softDelete deletes the rows matched by query: it marks SoftDeleteColumn when configured
+// and lets GORM delete (or soft-delete through gorm.DeletedAt) otherwise. It returns the
+// number of rows deleted.
+func (r *GormRepository[T]) softDelete(query *gorm.DB) (int64, error) {
+	if !r.customSoftDelete() {
+		result := query.Delete(new(T))
+		return result.RowsAffected, result.Error
+	}
+
+	var deleted any = time.Now()
+	if r.booleanSoftDelete() {
+		deleted = true
+	}
+	result := r.excludeDeleted(query).UpdateColumn(r.config().SoftDeleteColumn, deleted)
+	return result.RowsAffected, result.Error
+}
+
+// restore clears the soft-delete column of the rows matched by query.
+func (r *GormRepository[T]) restore(query *gorm.DB) error {
+	return query.Unscoped().UpdateColumn(r.softDeleteColumn(), r.notDeleted()).Error
+}
+
+type onlyTrashedKey struct{}
+
+// ContextWithOnlyTrashed returns a copy of ctx whose reads return only soft-deleted rows (a
+// trash view), e.g. for admins reviewing what to restore.
+func ContextWithOnlyTrashed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, onlyTrashedKey{}, true)
+}
+
+func onlyTrashed(ctx context.Context) bool {
+	trashed, _ := ctx.Value(onlyTrashedKey{}).(bool)
+	return trashed
+}
+
+type withTrashedKey struct{}
+
+// ContextWithTrashed returns a copy of ctx whose reads and updates also reach soft-deleted
+// rows, e.g. to fix a deleted record without restoring it (it stays deleted). Without it,
+// soft-deleted rows don't exist for updates: Update answers nil (a 404 in the controllers).
+func ContextWithTrashed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withTrashedKey{}, true)
+}
+
+func withTrashed(ctx context.Context) bool {
+	trashed, _ := ctx.Value(withTrashedKey{}).(bool)
+	return trashed
+}
+
+// updateScope limits an update by primary key to the rows that aren't soft-deleted, unless
+// ctx was made with ContextWithTrashed.
+func (r *GormRepository[T]) updateScope(ctx context.Context, query *gorm.DB) *gorm.DB {
+	if withTrashed(ctx) {
+		return query.Unscoped()
+	}
+	return r.excludeDeleted(query)
+}
+
+// onlyDeleted narrows query to the soft-deleted rows. A model without a soft-delete column
+// fails the query.
+func (r *GormRepository[T]) onlyDeleted(query *gorm.DB) *gorm.DB {
+	column := clause.Column{Table: clause.CurrentTable, Name: r.softDeleteColumn()}
+	if r.booleanSoftDelete() {
+		return query.Where(clause.Eq{Column: column, Value: true})
+	}
+	if !r.customSoftDelete() {
+		if s, err := r.schema(); err != nil || s.LookUpField("deleted_at") == nil {
+			query.AddError(fmt.Errorf("%T has no soft-delete column", *new(T)))
+			return query
+		}
+	}
+	return query.Unscoped().Where(clause.Neq{Column: column, Value: nil})
+}
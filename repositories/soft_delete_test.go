@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+)
+
+// note has no gorm.DeletedAt: it is soft-deleted through GormConfig.SoftDeleteColumn.
+type note struct {
+	ID        uint
+	Title     string
+	RemovedAt *time.Time
+	IsDeleted bool
+}
+
+func TestSoftDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *configs.GormConfig
+	}{
+		{"gorm.DeletedAt", nil},
+		{"timestamp column", &configs.GormConfig{SoftDeleteColumn: "removed_at"}},
+		{"boolean column", &configs.GormConfig{SoftDeleteColumn: "is_deleted", SoftDeleteStrategy: configs.SoftDeleteBoolean}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			ctx := context.Background()
+
+			var steps []func(t *testing.T)
+			if tt.config == nil {
+				r := NewGormRepository[product](db, nil, "products")
+				seedProducts(t, r, 3)
+				steps = softDeleteSteps(ctx, r, "name", func(p product) string { return p.Name })
+			} else {
+				if err := db.AutoMigrate(&note{}); err != nil {
+					t.Fatal(err)
+				}
+				db.Create(&[]note{{Title: "p1"}, {Title: "p2"}, {Title: "p3"}})
+				r := NewGormRepository[note](db, tt.config, "notes")
+				steps = softDeleteSteps(ctx, r, "title", func(n note) string { return n.Title })
+			}
+			for _, s := range steps {
+				s(t)
+			}
+		})
+	}
+}
+
+// softDeleteSteps deletes row 2 of three rows named p1..p3 (in column) and checks the reads
+// and updates treat it as deleted until it is restored.
+func softDeleteSteps[T any](ctx context.Context, r *GormRepository[T], column string, name func(T) string) []func(t *testing.T) {
+	names := func(t *testing.T, ctx context.Context) []string {
+		t.Helper()
+		rows, err := r.FindAll(ctx, nil, &dto.BaseFilterDto{SortKey: ptr("id"), SortDir: ptr("ASC")}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, row := range rows {
+			got = append(got, name(row))
+		}
+		return got
+	}
+	expect := func(t *testing.T, what string, got, want any) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", what, got, want)
+		}
+	}
+	return []func(t *testing.T){
+		func(t *testing.T) {
+			deleted, err := r.DeleteRows(ctx, Eq("id", 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect(t, "deleted", deleted, int64(1))
+			// deleting it again finds nothing
+			deleted, _ = r.DeleteRows(ctx, Eq("id", 2))
+			expect(t, "deleted again", deleted, int64(0))
+		},
+		func(t *testing.T) {
+			expect(t, "FindAll", names(t, ctx), []string{"p1", "p3"})
+			expect(t, "only trashed", names(t, ContextWithOnlyTrashed(ctx)), []string{"p2"})
+			count, _ := r.Count(ctx, nil)
+			expect(t, "Count", count, int64(2))
+			found, _ := r.FindOneByPK(ctx, 2, nil)
+			expect(t, "FindOneByPK", found == nil, true)
+		},
+		func(t *testing.T) {
+			// updates don't reach the deleted row unless asked to
+			if err := r.UpdateByPK(ctx, 2, map[string]any{column: "changed"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.UpdateColumnsByPK(ctx, 2, map[string]any{column: "changed"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Update(ContextWithTrashed(ctx), Eq("id", 2), map[string]any{column: "fixed"}); err != nil {
+				t.Fatal(err)
+			}
+		},
+		func(t *testing.T) {
+			if err := r.Restore(ctx, 2); err != nil {
+				t.Fatal(err)
+			}
+			expect(t, "restored", names(t, ctx), []string{"p1", "fixed", "p3"})
+			expect(t, "only trashed after restore", names(t, ContextWithOnlyTrashed(ctx)), []string(nil))
+		},
+	}
+}
+
+func TestCascadeSoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	categories := NewGormRepository[category](db, &configs.GormConfig{
+		CascadeSoftDelete: []string{"Products"},
+		CascadeRestore:    true,
+	}, "categories")
+	db.Create(&[]category{
+		{Name: "books", Products: []product{{Name: "b1"}, {Name: "b2"}, {Name: "b3"}}},
+		{Name: "games", Products: []product{{Name: "g1"}}},
+	})
+	products := NewGormRepository[product](db, nil, "products")
+
+	alive := func() []string {
+		var names []string
+		db.Model(&product{}).Order("id").Pluck("name", &names)
+		return names
+	}
+
+	// b3 was deleted on its own, before its category
+	if err := products.DeleteOneByPK(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	deleted, err := categories.DeleteRows(ctx, Eq("name", "books"))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteRows() = %d, %v; want 1 category", deleted, err)
+	}
+	if got := alive(); !reflect.DeepEqual(got, []string{"g1"}) {
+		t.Errorf("after the delete: %v, want [g1]", got)
+	}
+
+	if err := categories.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := alive(); !reflect.DeepEqual(got, []string{"b1", "b2", "g1"}) {
+		t.Errorf("after the restore: %v, want [b1 b2 g1]", got)
+	}
+
+	bad := NewGormRepository[category](db, &configs.GormConfig{CascadeSoftDelete: []string{"Missing"}}, "categories")
+	if err := bad.DeleteOneByPK(ctx, 2); err == nil {
+		t.Error("DeleteOneByPK() with an unknown cascade relation succeeded")
+	}
+}
+
+func TestDeleteGuards(t *testing.T) {
+	tests := []struct {
+		name       string
+		allow      bool
+		conditions any
+		deleted    int64
+		wantErr    error
+	}{
+		{"nil", false, nil, 0, ErrFullTableDelete},
+		{"empty condition", false, &Condition{}, 0, ErrFullTableDelete},
+		{"empty map", false, map[string]any{}, 0, ErrFullTableDelete},
+		{"empty query", false, map[string]any{"query": ""}, 0, ErrFullTableDelete},
+		{"zero struct", false, product{}, 0, ErrFullTableDelete},
+		{"allowed", true, nil, 3, nil},
+		{"condition", false, Lte("price", 2), 2, nil},
+		{"raw string", false, "price > 2", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{AllowFullTableDelete: tt.allow})
+			seedProducts(t, r, 3)
+
+			deleted, err := r.DeleteRows(context.Background(), tt.conditions)
+			if !errors.Is(err, tt.wantErr) || deleted != tt.deleted {
+				t.Errorf("DeleteRows() = %d, %v; want %d, %v", deleted, err, tt.deleted, tt.wantErr)
+			}
+			if err := r.Update(context.Background(), tt.conditions, map[string]any{"name": "x"}); tt.wantErr != nil && !errors.Is(err, ErrFullTableUpdate) {
+				t.Errorf("Update() error = %v, want ErrFullTableUpdate", err)
+			}
+		})
+	}
+}
+
+func TestDeleteAllAndTruncate(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 3)
+	ctx := context.Background()
+
+	if err := r.DeleteAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var kept int64
+	r.DB.Unscoped().Model(&product{}).Count(&kept)
+	if count, _ := r.Count(ctx, nil); count != 0 || kept != 3 {
+		t.Errorf("after DeleteAll: %d visible, %d stored; want 0, 3 (soft-deleted)", count, kept)
+	}
+
+	if err := r.Truncate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	r.DB.Unscoped().Model(&product{}).Count(&kept)
+	if kept != 0 {
+		t.Errorf("after Truncate: %d stored, want 0", kept)
+	}
+}
+
+func TestDeleteOneByPKReturning(t *testing.T) {
+	r := newTestRepository(t, nil)
+	seedProducts(t, r, 2)
+	ctx := context.Background()
+
+	deleted, err := r.DeleteOneByPKReturning(ctx, 2, nil)
+	if err != nil || deleted == nil || deleted.Name != "p2" {
+		t.Fatalf("DeleteOneByPKReturning() = %+v, %v; want p2", deleted, err)
+	}
+	if deleted, err = r.DeleteOneByPKReturning(ctx, 2, nil); err != nil || deleted != nil {
+		t.Errorf("second DeleteOneByPKReturning() = %+v, %v; want nil, nil", deleted, err)
+	}
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// sqlComment is prepended to the statement GORM builds: /* model=User op=FindAll req=... */
+type sqlComment string
+
+func (c sqlComment) Build(builder clause.Builder) {
+	builder.WriteString("/* ")
+	builder.WriteString(string(c))
+	builder.WriteString(" */")
+}
+
+// ModifyStatement attaches the comment before whichever statement the query turns into.
+func (c sqlComment) ModifyStatement(stmt *gorm.Statement) {
+	for _, name := range []string{"SELECT", "INSERT", "UPDATE", "DELETE"} {
+		current := stmt.Clauses[name]
+		current.BeforeExpression = c
+		stmt.Clauses[name] = current
+	}
+}
+
+// withSQLComment tags db's statements with T's model name, the repository method running
+// them and the request id from ctx, when SQLComment is enabled.
+func (r *GormRepository[T]) withSQLComment(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if !r.config().SQLComment {
+		return db
+	}
+
+	parts := []string{"model=" + commentValue(reflect.TypeOf(new(T)).Elem().Name())}
+	if op := repositoryOperation(); op != "" {
+		parts = append(parts, "op="+op)
+	}
+	if id := middlewares.GetRequestID(ctx); id != "" {
+		parts = append(parts, "req="+commentValue(id))
+	}
+	// a new session keeps the returned db reusable for several queries
+	return db.Clauses(sqlComment(strings.Join(parts, " "))).Session(&gorm.Session{})
+}
+
+var repositoryPrefix = reflect.TypeOf(GormRepository[struct{}]{}).PkgPath() + ".(*GormRepository["
+
+// repositoryOperation returns the outermost exported GormRepository method on the call
+// stack, so helpers report the operation they serve (FindAllWithPaging, not BuildBaseQuery).
+// Methods called inside a Transaction callback report themselves.
+func repositoryOperation() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	op := ""
+	for {
+		frame, more := frames.Next()
+		if rest, ok := strings.CutPrefix(frame.Function, repositoryPrefix); ok {
+			if _, method, ok := strings.Cut(rest, "])."); ok {
+				method, _, _ = strings.Cut(method, ".") // closures: FindAll.func1
+				if method != "" && method[0] >= 'A' && method[0] <= 'Z' {
+					if method == "Transaction" && op != "" {
+						break
+					}
+					op = method
+				}
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return op
+}
+
+// commentValue drops anything but letters, digits and -_.: so a value (a client-sent
+// request id) can't close the comment.
+func commentValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.:", r):
+			return r
+		}
+		return -1
+	}, value)
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type afterCommitKey struct{}
+
+// afterCommitHooks buffers the functions registered with AfterCommit inside a transaction.
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+}
+
+func (h *afterCommitHooks) add(hooks ...func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hooks...)
+}
+
+// take empties the buffer and returns the hooks it held.
+func (h *afterCommitHooks) take() []func(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hooks := h.hooks
+	h.hooks = nil
+	return hooks
+}
+
+func afterCommitFromContext(ctx context.Context) *afterCommitHooks {
+	hooks, _ := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	return hooks
+}
+
+// ContextWithTx returns a copy of ctx carrying tx. Repositories called with that context
+// run their queries on tx instead of their own connection. Functions registered with
+// AfterCommit on that context wait for Committed, once tx has committed.
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, &afterCommitHooks{})
+}
+
+// TxFromContext returns the transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// AfterCommit runs fn once the transaction carried by ctx has committed, e.g. to publish
+// an event only for changes that were kept: nested transactions hand their functions to the
+// enclosing one, and a rollback drops them. Without a transaction fn runs right away. fn
+// gets a context without the transaction.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if _, inTx := TxFromContext(ctx); inTx {
+		if hooks := afterCommitFromContext(ctx); hooks != nil {
+			hooks.add(fn)
+			return
+		}
+	}
+	fn(ctx)
+}
+
+// Committed runs the functions registered with AfterCommit on ctx, a context returned by
+// ContextWithTx, once its transaction has committed. Transaction does it by itself; only
+// callers managing their own transaction need it.
+func Committed(ctx context.Context) {
+	hooks := afterCommitFromContext(ctx)
+	if hooks == nil {
+		return
+	}
+	ctx = context.WithValue(ctx, txKey{}, (*gorm.DB)(nil))
+	for _, fn := range hooks.take() {
+		fn(ctx)
+	}
+}
+
+// db returns the transaction carried by ctx, or the repository connection otherwise.
+// Queries built on it must start with Model(new(T)) (or an entity), never Table(r.TableName):
+// without T's schema GORM skips soft delete and hooks, so Delete would remove the rows.
+func (r *GormRepository[T]) db(ctx context.Context) *gorm.DB {
+	db := r.DB
+	if tx, ok := TxFromContext(ctx); ok {
+		db = tx
+	}
+	return withSQLRecorder(ctx, r.withSQLComment(ctx, db.WithContext(ctx)))
+}
+
+// Transaction runs fn inside a database transaction. The context passed to fn carries the
+// transaction, so every repository (of any entity) called with it joins the transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise; calling
+// Transaction with a context that already carries one opens a nested transaction (savepoint).
+//
+// All repositories sharing a transaction must use the same database. With GormConfig.Retry,
+// a transaction failing with a deadlock, a serialization failure or a bad connection (see
+// IsRetryableWriteError) runs again from the start, so fn must be safe to repeat.
+//
+// Functions registered with AfterCommit inside fn run once the outermost transaction has
+// committed.
+func (r *GormRepository[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	var txCtx context.Context
+	err := r.retryWrite(ctx, func() error {
+		return r.db(ctx).Transaction(func(tx *gorm.DB) error {
+			// a retried attempt starts over with no hooks
+			txCtx = ContextWithTx(ctx, tx)
+			return fn(txCtx)
+		})
+	})
+	if err != nil || txCtx == nil {
+		return err
+	}
+	// hand the hooks to the enclosing transaction, or run them now that this one committed
+	for _, hook := range afterCommitFromContext(txCtx).take() {
+		AfterCommit(ctx, hook)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+func TestAfterCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), runs *int) error
+		runs int
+	}{
+		{
+			"without a transaction",
+			func(ctx context.Context, _ *GormRepository[product], hook func(ctx context.Context), runs *int) error {
+				AfterCommit(ctx, hook)
+				if *runs != 1 {
+					t.Errorf("ran %d times right away, want 1", *runs)
+				}
+				return nil
+			},
+			1,
+		},
+		{
+			"on commit",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), runs *int) error {
+				return r.Transaction(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, hook)
+					if *runs != 0 {
+						t.Errorf("ran %d times before the commit", *runs)
+					}
+					return nil
+				})
+			},
+			1,
+		},
+		{
+			"dropped on rollback",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), _ *int) error {
+				return r.Transaction(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, hook)
+					return errors.New("rollback")
+				})
+			},
+			0,
+		},
+		{
+			"nested run by the outermost",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), runs *int) error {
+				return r.Transaction(ctx, func(ctx context.Context) error {
+					err := r.Transaction(ctx, func(ctx context.Context) error {
+						AfterCommit(ctx, hook)
+						return nil
+					})
+					if *runs != 0 {
+						t.Errorf("ran %d times before the outer commit", *runs)
+					}
+					return err
+				})
+			},
+			1,
+		},
+		{
+			"nested rollback dropped",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), _ *int) error {
+				return r.Transaction(ctx, func(ctx context.Context) error {
+					_ = r.Transaction(ctx, func(ctx context.Context) error {
+						AfterCommit(ctx, hook)
+						return errors.New("rollback")
+					})
+					return nil
+				})
+			},
+			0,
+		},
+		{
+			"retried attempt run once",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), _ *int) error {
+				attempts := 0
+				return r.Transaction(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, hook)
+					if attempts++; attempts == 1 {
+						return sqlStateError("40001")
+					}
+					return nil
+				})
+			},
+			1,
+		},
+		{
+			"own transaction",
+			func(ctx context.Context, r *GormRepository[product], hook func(ctx context.Context), runs *int) error {
+				var txCtx context.Context
+				err := r.DB.Transaction(func(tx *gorm.DB) error {
+					txCtx = ContextWithTx(ctx, tx)
+					AfterCommit(txCtx, hook)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				if *runs != 0 {
+					t.Errorf("ran %d times before Committed", *runs)
+				}
+				Committed(txCtx)
+				return nil
+			},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, &configs.GormConfig{
+				Retry: &configs.GormRetry{Attempts: 2, Backoff: time.Microsecond},
+			})
+			runs := 0
+			hook := func(ctx context.Context) {
+				if _, inTx := TxFromContext(ctx); inTx {
+					t.Error("hook got a context carrying the transaction")
+				}
+				runs++
+			}
+
+			_ = tt.run(context.Background(), r, hook, &runs)
+			if runs != tt.runs {
+				t.Errorf("ran %d times, want %d", runs, tt.runs)
+			}
+		})
+	}
+}
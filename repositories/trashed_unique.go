@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+// ErrTrashedDuplicate is matched (errors.Is) by the TrashedDuplicateError the creates return.
+var ErrTrashedDuplicate = errors.New("value held by a soft-deleted row")
+
+// TrashedDuplicateError reports that a TrashedUniqueColumns value of a new row is still held
+// by a soft-deleted one. Restore ID instead of creating a new row, or delete it for good.
+// The creates wrap it in a 409 models.CrudError ("trashed_duplicate"); reach it with errors.As.
+type TrashedDuplicateError struct {
+	Column string
+	Value  any
+	ID     any // primary key of the soft-deleted row
+}
+
+func (e *TrashedDuplicateError) Error() string {
+	return fmt.Sprintf("%s: %s = %v (restore id %v)", ErrTrashedDuplicate, e.Column, e.Value, e.ID)
+}
+
+func (e *TrashedDuplicateError) Unwrap() error {
+	return ErrTrashedDuplicate
+}
+
+// resolveTrashedDuplicates applies TrashedUniqueStrategy to the TrashedUniqueColumns values
+// of entities still held by soft-deleted rows: reject fails with a TrashedDuplicateError,
+// release sets the column of those rows, and only those, to NULL so the insert can take
+// the value.
+func (r *GormRepository[T]) resolveTrashedDuplicates(ctx context.Context, entities ...T) error {
+	config := r.config()
+	if len(config.TrashedUniqueColumns) == 0 || len(entities) == 0 {
+		return nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return fmt.Errorf("TrashedUniqueColumns: %s has no primary key", s.Name)
+	}
+	release := config.TrashedUniqueStrategy == configs.TrashedUniqueRelease
+
+	for _, column := range config.TrashedUniqueColumns {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("TrashedUniqueColumns: unknown column %s on %s", column, s.Name)
+		}
+
+		var values []any
+		seen := map[any]bool{}
+		for i := range entities {
+			value, zero := field.ValueOf(ctx, reflect.ValueOf(&entities[i]).Elem())
+			if zero {
+				continue
+			}
+			value = reflect.Indirect(reflect.ValueOf(value)).Interface()
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+
+		target := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		for chunk := range slices.Chunk(values, config.ChunkSize()) {
+			query := r.onlyDeleted(r.db(ctx).Model(new(T))).Where(clause.IN{Column: target, Values: chunk})
+			if release {
+				if err := query.UpdateColumn(field.DBName, nil).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			var held []map[string]any
+			if err := query.Select(s.PrioritizedPrimaryField.DBName, field.DBName).Limit(1).Find(&held).Error; err != nil {
+				return err
+			}
+			if len(held) > 0 {
+				value := scannedValue(held[0][field.DBName])
+				for _, candidate := range chunk {
+					if fmt.Sprint(candidate) == fmt.Sprint(value) {
+						value = candidate
+						break
+					}
+				}
+				id := scannedValue(held[0][s.PrioritizedPrimaryField.DBName])
+				cause := &TrashedDuplicateError{Column: field.DBName, Value: value, ID: id}
+				return models.NewConflictError("trashed_duplicate", cause)
+			}
+		}
+	}
+	return nil
+}
+
+// scannedValue returns a value scanned into a map as plain data: GORM scans the fields of a
+// model with their own types (pointers included), and some drivers scan text as bytes.
+func scannedValue(value any) any {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if bytes, ok := v.Interface().([]byte); ok {
+		return string(bytes)
+	}
+	return v.Interface()
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/models"
+)
+
+func TestTrashedUnique(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy configs.TrashedUniqueStrategy
+		sku      string
+		conflict bool
+	}{
+		{"reject by default", "", "a", true},
+		{"reject", configs.TrashedUniqueReject, "a", true},
+		{"release", configs.TrashedUniqueRelease, "a", false},
+		{"value no trashed row holds", configs.TrashedUniqueReject, "z", false},
+	}
+	for _, path := range createPaths {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				r := newTestRepository(t, &configs.GormConfig{
+					TrashedUniqueColumns:  []string{"SKU"},
+					TrashedUniqueStrategy: tt.strategy,
+				})
+				r.DB.Create(&[]product{{Name: "old a", SKU: ptr("a")}, {Name: "old b", SKU: ptr("b")}})
+				r.DB.Delete(&product{}, []uint{1, 2})
+
+				err := createVia(context.Background(), r, path, product{Name: "new", SKU: &tt.sku})
+				var duplicate *TrashedDuplicateError
+				if got := errors.As(err, &duplicate); got != tt.conflict {
+					t.Fatalf("error = %v, want conflict %v", err, tt.conflict)
+				}
+				if tt.conflict {
+					var crudErr *models.CrudError
+					if !errors.As(err, &crudErr) || crudErr.Code != http.StatusConflict {
+						t.Errorf("error = %v, want a 409 CrudError", err)
+					}
+					if duplicate.Value != tt.sku || fmt.Sprint(duplicate.ID) != "1" {
+						t.Errorf("TrashedDuplicateError = %+v, want sku %s held by 1", duplicate, tt.sku)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// only the trashed row holding the value gave it up
+				var trashed []product
+				r.DB.Unscoped().Where("deleted_at IS NOT NULL").Order("id").Find(&trashed)
+				want := []*string{ptr("a"), ptr("b")}
+				if tt.strategy == configs.TrashedUniqueRelease {
+					want[0] = nil
+				}
+				for i, row := range trashed {
+					if (row.SKU == nil) != (want[i] == nil) || (row.SKU != nil && *row.SKU != *want[i]) {
+						t.Errorf("trashed row %d sku = %v, want %v", row.ID, row.SKU, want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTrashedUniqueAcrossChunks(t *testing.T) {
+	r := newTestRepository(t, &configs.GormConfig{
+		IDChunkSize:          2,
+		TrashedUniqueColumns: []string{"sku"},
+	})
+	r.DB.Create(&product{Name: "old", SKU: ptr("e")})
+	r.DB.Delete(&product{}, 1)
+
+	// the held value is in the last chunk
+	var items []any
+	for _, sku := range []string{"a", "b", "c", "d", "e"} {
+		items = append(items, product{Name: sku, SKU: ptr(sku)})
+	}
+	_, err := r.BulkCreate(context.Background(), items)
+	var duplicate *TrashedDuplicateError
+	if !errors.As(err, &duplicate) || duplicate.Value != "e" {
+		t.Fatalf("BulkCreate() error = %v, want sku e held", err)
+	}
+}
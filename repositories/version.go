@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleVersion is returned by UpdateByPKVersioned when the entity was changed since the
+// caller read it (its version moved on).
+var ErrStaleVersion = errors.New("stale version")
+
+// ErrNoVersionColumn is returned by UpdateByPKVersioned when GormConfig.VersionColumn is not
+// set, e.g. for an If-Match update of an entity without a version.
+var ErrNoVersionColumn = errors.New("versioned updates need GormConfig.VersionColumn")
+
+// UpdateByPKVersioned is UpdateByPK with optimistic locking on GormConfig.VersionColumn: the
+// update applies only while the row still has version, and increments it. A changed version
+// fails with ErrStaleVersion; a missing row updates nothing and returns nil, like UpdateByPK.
+func (r *GormRepository[T]) UpdateByPKVersioned(ctx context.Context, id any, version any, updateDto any, args ...any) error {
+	column := r.config().VersionColumn
+	if column == "" {
+		return ErrNoVersionColumn
+	}
+
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		// bumping the version first locks the row until the update commits
+		bump := r.updateScope(ctx, r.db(ctx).Model(new(T))).
+			Where("id = ?", id).
+			Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: version}).
+			UpdateColumn(column, gorm.Expr("? + 1", clause.Column{Name: column}))
+		if bump.Error != nil {
+			return bump.Error
+		}
+		if bump.RowsAffected == 0 {
+			exists, err := r.ExistsByPK(ctx, id)
+			if err != nil || !exists {
+				return err
+			}
+			return ErrStaleVersion
+		}
+
+		model, values := updateModel[T](updateDto)
+		defer r.clearPK(ctx, model)()
+		query := r.omitNotAllowed(r.updateScope(ctx, r.db(ctx).Model(model)), r.config().UpdatableColumns)
+		return query.Omit(column).Where("id = ?", id).Updates(values).Error
+	})
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/aghiadodeh/go-crud/configs"
+)
+
+const windowTotalColumn = "window_total"
+
+// windowCountable reports whether the list query can fetch its total with COUNT(*) OVER():
+// the database must support it, and the query must select the plain entity columns
+// without grouping or preloads (the rows are scanned by hand).
+func (r *GormRepository[T]) windowCountable(query *gorm.DB, config *configs.GormConfig) bool {
+	if r.Dialect() != DialectPostgres || config.Group != "" {
+		return false
+	}
+	return !customSelect(query) && len(query.Statement.Preloads) == 0
+}
+
+// findWithWindowCount runs the page query with COUNT(*) OVER() and returns the rows with the
+// total of the unpaginated result. The total is only known when the page is not empty.
+func (r *GormRepository[T]) findWithWindowCount(ctx context.Context, query *gorm.DB) ([]T, int64, error) {
+	var rows []map[string]any
+	query = query.Select(fmt.Sprintf("?.*, COUNT(*) OVER() AS %s", windowTotalColumn), clause.Table{Name: clause.CurrentTable})
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	for _, row := range rows {
+		total, _ = strconv.ParseInt(fmt.Sprint(row[windowTotalColumn]), 10, 64)
+		delete(row, windowTotalColumn)
+	}
+	entities, err := r.decodeRows(ctx, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entities, total, nil
+}
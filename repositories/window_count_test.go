@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+)
+
+// postgresLike is SQLite reporting itself as Postgres, to run the Postgres-only paths
+// (COUNT(*) OVER(), RETURNING) that SQLite supports too.
+type postgresLike struct {
+	gorm.Dialector
+}
+
+func (postgresLike) Name() string { return "postgres" }
+
+// newPostgresLikeRepository is newTestRepository on postgresLike. It counts the statements
+// run, by kind.
+func newPostgresLikeRepository(t *testing.T, config *configs.GormConfig) (*GormRepository[product], map[string]int) {
+	t.Helper()
+	db, err := gorm.Open(postgresLike{sqlite.Open(":memory:")}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&category{}, &product{}); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := map[string]int{}
+	count := func(kind string) func(*gorm.DB) {
+		return func(*gorm.DB) { statements[kind]++ }
+	}
+	db.Callback().Query().Before("gorm:query").Register("test:query", count("query"))
+	db.Callback().Create().Before("gorm:create").Register("test:create", count("create"))
+	db.Callback().Update().Before("gorm:update").Register("test:update", count("update"))
+	return NewGormRepository[product](db, config, "products"), statements
+}
+
+func TestWindowCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *configs.GormConfig
+		filter  *dto.BaseFilterDto
+		total   int64
+		rows    int
+		queries int
+	}{
+		{"single query", &configs.GormConfig{WindowCount: true}, &dto.BaseFilterDto{PerPage: 2}, 5, 2, 1},
+		{"disabled", nil, &dto.BaseFilterDto{PerPage: 2}, 5, 2, 2},
+		{"empty page counts separately", &configs.GormConfig{WindowCount: true}, &dto.BaseFilterDto{Page: 9, PerPage: 2}, 5, 0, 2},
+		{"after_id counts separately", &configs.GormConfig{WindowCount: true}, &dto.BaseFilterDto{PerPage: 2, AfterID: ptr("3")}, 5, 2, 2},
+		{"preloads count separately", &configs.GormConfig{WindowCount: true, Preloads: []configs.GormPreloadConfig{{Relation: "Category"}}}, &dto.BaseFilterDto{PerPage: 2}, 5, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, statements := newPostgresLikeRepository(t, tt.config)
+			seedProducts(t, r, 5)
+			statements["query"] = 0
+
+			response, err := r.FindAllWithPaging(context.Background(), nil, tt.filter, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Total != tt.total || len(response.Data) != tt.rows {
+				t.Errorf("page = %d rows of %d, want %d of %d", len(response.Data), response.Total, tt.rows, tt.total)
+			}
+			for _, row := range response.Data {
+				if row.ID == 0 || row.Name == "" || row.CreatedAt.IsZero() {
+					t.Errorf("row = %+v, want it fully loaded", row)
+				}
+			}
+			if statements["query"] != tt.queries {
+				t.Errorf("ran %d queries, want %d", statements["query"], tt.queries)
+			}
+		})
+	}
+}
+
+func TestWindowCountSQL(t *testing.T) {
+	r, _ := newPostgresLikeRepository(t, &configs.GormConfig{WindowCount: true})
+	seedProducts(t, r, 5)
+	recorder := &middlewares.SQLRecorder{}
+	ctx := middlewares.WithSQLRecorder(context.Background(), recorder)
+
+	if _, err := r.FindAllWithPaging(ctx, nil, &dto.BaseFilterDto{PerPage: 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	queries := recorder.Queries()
+	if len(queries) != 1 ||
+		!strings.HasPrefix(queries[0], "SELECT `products`.*, COUNT(*) OVER() AS window_total FROM `products`") ||
+		!strings.HasSuffix(queries[0], "LIMIT 2") {
+		t.Errorf("queries = %q, want a single windowed page query", queries)
+	}
+}
+
+func TestReturningPaths(t *testing.T) {
+	r, statements := newPostgresLikeRepository(t, nil)
+	seedProducts(t, r, 2)
+	ctx := context.Background()
+
+	t.Run("UpdateByPKReturning", func(t *testing.T) {
+		clear(statements)
+		payload := &product{Name: "renamed"}
+		updated, err := r.UpdateByPKReturning(ctx, 2, payload, nil)
+		if err != nil || updated == nil || updated.ID != 2 || updated.Name != "renamed" || updated.Price != 2 {
+			t.Fatalf("UpdateByPKReturning() = %+v, %v; want row 2 renamed", updated, err)
+		}
+		if statements["update"] != 1 || statements["query"] != 0 {
+			t.Errorf("ran %v, want a single update", statements)
+		}
+		if payload.ID != 0 || payload.Price != 0 {
+			t.Errorf("payload = %+v, want it untouched", payload)
+		}
+	})
+
+	t.Run("UpdateByPKReturning on a missing row", func(t *testing.T) {
+		updated, err := r.UpdateByPKReturning(ctx, 9, map[string]any{"name": "x"}, nil)
+		if err != nil || updated != nil {
+			t.Errorf("UpdateByPKReturning() = %+v, %v; want nil, nil", updated, err)
+		}
+	})
+
+	t.Run("UpdateByPKReturning with nothing to set", func(t *testing.T) {
+		updated, err := r.UpdateByPKReturning(ctx, 1, map[string]any{}, nil)
+		if err != nil || updated == nil || updated.ID != 1 {
+			t.Errorf("UpdateByPKReturning() = %+v, %v; want row 1", updated, err)
+		}
+	})
+
+	t.Run("BulkCreateReturning", func(t *testing.T) {
+		clear(statements)
+		created, err := r.BulkCreateReturning(ctx, []any{product{Name: "a"}, product{Name: "b"}})
+		if err != nil || len(created) != 2 || created[0].ID != 3 || created[1].Name != "b" || created[1].CreatedAt.IsZero() {
+			t.Fatalf("BulkCreateReturning() = %+v, %v", created, err)
+		}
+		if statements["create"] != 1 || statements["query"] != 0 {
+			t.Errorf("ran %v, want a single insert", statements)
+		}
+	})
+}
+
+func TestUpdateByPKReturningSQL(t *testing.T) {
+	r, _ := newPostgresLikeRepository(t, &configs.GormConfig{
+		Retry: &configs.GormRetry{Attempts: 2, Backoff: time.Microsecond},
+	})
+	seedProducts(t, r, 2)
+	recorder := &middlewares.SQLRecorder{}
+	ctx := middlewares.WithSQLRecorder(context.Background(), recorder)
+
+	// the first attempt fails with a serialization failure before reaching the database
+	attempts := 0
+	r.DB.Callback().Update().Before("gorm:update").Register("test:fail", func(db *gorm.DB) {
+		if attempts++; attempts == 1 {
+			db.AddError(sqlStateError("40001"))
+		}
+	})
+
+	updated, err := r.UpdateByPKReturning(ctx, 2, map[string]any{"name": "two"}, nil)
+	if err != nil || updated == nil || updated.Name != "two" || updated.Price != 2 {
+		t.Fatalf("UpdateByPKReturning() = %+v, %v; want row 2 renamed", updated, err)
+	}
+	if attempts != 2 {
+		t.Errorf("ran %d attempts, want the update retried once", attempts)
+	}
+	queries := recorder.Queries()
+	if len(queries) != 1 ||
+		!strings.HasPrefix(queries[0], "UPDATE `products` SET `name`=\"two\"") ||
+		!strings.HasSuffix(queries[0], "WHERE id = 2 AND `products`.`deleted_at` IS NULL RETURNING *") {
+		t.Errorf("queries = %q, want a single UPDATE ... RETURNING", queries)
+	}
+}
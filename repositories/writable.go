@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// assignID sets the primary key of entity from IDGenerator when the key is zero-valued.
+func (r *GormRepository[T]) assignID(ctx context.Context, entity *T) error {
+	if r.config().IDGenerator == nil {
+		return nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	field := s.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(entity).Elem()
+	if _, zero := field.ValueOf(ctx, value); !zero {
+		return nil
+	}
+	return field.Set(ctx, value, r.config().IDGenerator())
+}
+
+// omitNotAllowed restricts the columns an insert or update may write to allowed
+// (CreatableColumns/UpdatableColumns), by column or field name. Primary keys and
+// auto-managed timestamps stay writable; relations must be listed by field name to be
+// saved. An empty allowlist leaves the query untouched.
+func (r *GormRepository[T]) omitNotAllowed(query *gorm.DB, allowed []string) *gorm.DB {
+	if len(allowed) == 0 {
+		return query
+	}
+	s, err := r.schema()
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+
+	var omit []string
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			continue
+		}
+		if !slices.Contains(allowed, field.DBName) && !slices.Contains(allowed, field.Name) {
+			omit = append(omit, field.DBName)
+		}
+	}
+	for name := range s.Relationships.Relations {
+		if !slices.Contains(allowed, name) {
+			omit = append(omit, name)
+		}
+	}
+	if len(omit) == 0 {
+		return query
+	}
+	return query.Omit(omit...)
+}
+
+// ErrInvalidColumn is returned (wrapped) by PatchColumnsByPK for a column that doesn't exist,
+// isn't updatable or can't hold the value sent.
+var ErrInvalidColumn = errors.New("invalid column")
+
+// PatchColumnsByPK updates the given columns of an entity, zero values and nulls included.
+// Keys are column or field names, checked against UpdatableColumns (any non-key column when it
+// is empty). Values are converted to the field's type, e.g. JSON numbers to integers and
+// strings to time.Time, so a decoded JSON body can be passed as is.
+func (r *GormRepository[T]) PatchColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
+	updates, err := r.patchValues(ctx, columns)
+	if err != nil {
+		return err
+	}
+	return r.UpdateColumnsByPK(ctx, id, updates, args...)
+}
+
+// patchValues maps columns to the column names and typed values UpdateColumns expects.
+func (r *GormRepository[T]) patchValues(ctx context.Context, columns map[string]any) (map[string]any, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: no columns to update", ErrInvalidColumn)
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := r.config().UpdatableColumns
+	entity := reflect.New(s.ModelType).Elem()
+	updates := make(map[string]any, len(columns))
+	for key, value := range columns {
+		field := s.LookUpField(key)
+		if field == nil || field.DBName == "" || field.PrimaryKey || !field.Updatable {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidColumn, key)
+		}
+		if len(allowed) > 0 && !slices.Contains(allowed, field.DBName) && !slices.Contains(allowed, field.Name) {
+			return nil, fmt.Errorf("%w: %s is not updatable", ErrInvalidColumn, key)
+		}
+		if value == nil {
+			updates[field.DBName] = nil
+			continue
+		}
+		if err := field.Set(ctx, entity, value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidColumn, key, err)
+		}
+		updates[field.DBName], _ = field.ValueOf(ctx, entity)
+	}
+	return updates, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// AuditRecord is one entry of the audit trail written by BaseCrudService.
+// Before/After hold the entity's exported fields keyed by field name.
+type AuditRecord struct {
+	Actor     any
+	Model     string
+	Operation CrudOperation
+	ID        any
+	Before    map[string]any         // nil for creates
+	After     map[string]any         // nil for deletes
+	Changes   map[string]AuditChange // updates only: the fields whose value changed
+	Timestamp time.Time
+}
+
+type AuditChange struct {
+	From any
+	To   any
+}
+
+// AuditSink persists audit records (a table, a log stream, an external service...).
+type AuditSink interface {
+	Write(ctx context.Context, record AuditRecord) error
+}
+
+// entityFields flattens the exported fields of a struct (or pointer to struct) into a map,
+// descending into embedded structs.
+func entityFields(entity any) map[string]any {
+	val := reflect.ValueOf(entity)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := map[string]any{}
+	collectFields(val, fields)
+	return fields
+}
+
+func collectFields(val reflect.Value, fields map[string]any) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(val.Field(i), fields)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields[field.Name] = val.Field(i).Interface()
+	}
+}
+
+// diffFields returns the fields whose value differs between before and after.
+func diffFields(before, after map[string]any) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	for name, to := range after {
+		from := before[name]
+		if !reflect.DeepEqual(from, to) {
+			changes[name] = AuditChange{From: from, To: to}
+		}
+	}
+	return changes
+}
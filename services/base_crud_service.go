@@ -2,46 +2,181 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
 	"github.com/aghiadodeh/go-crud/models"
 	"github.com/aghiadodeh/go-crud/repositories"
 )
 
 type BaseCrudService[T any, C any, R repositories.BaseRepository[T, C]] struct {
 	Repository R
+	// Publisher is notified after every successful Create/Update/Delete; inside a transaction
+	// (WithTx or the repository's), once it has committed.
+	Publisher EventPublisher
+	// Audit enables the audit trail when set: every mutation writes an AuditRecord to it, in
+	// the mutation's transaction.
+	Audit AuditSink
+	// AuditActor resolves the acting user recorded in AuditRecord.Actor
+	// (defaults to middlewares.GetActor).
+	AuditActor func(ctx context.Context) any
+	// AfterFind post-processes the entities of every FindAll, FindAllWithPaging and
+	// FindAllWithCursor result (derived fields, masking, data from another store...). It runs
+	// before the pagination metadata is finalized: entities it drops or adds are taken off or
+	// added to the total. Cursors keep pointing at the rows read, so paging goes on from
+	// where the query stopped.
+	AfterFind func(ctx context.Context, items []T) ([]T, error)
 }
 
 func NewBaseCrudService[T any, C any, R repositories.BaseRepository[T, C]](repository R) *BaseCrudService[T, C, R] {
-	return &BaseCrudService[T, C, R]{Repository: repository}
+	return &BaseCrudService[T, C, R]{Repository: repository, Publisher: NoopEventPublisher{}}
 }
 
 func (s *BaseCrudService[T, C, R]) Create(ctx context.Context, createDto any, config *C, args ...any) (*T, error) {
-	id, err := s.Repository.Create(ctx, createDto, args...)
+	var id any
+	var item *T
+	err := s.mutate(ctx, func(ctx context.Context) (err error) {
+		if id, err = s.Repository.Create(ctx, createDto, args...); err != nil {
+			return err
+		}
+		if item, err = s.FindOneByPKOrError(ctx, id, config, args...); err != nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationCreate, id, nil, item)
+	})
 	if err != nil {
 		return nil, err
 	}
-	item, err := s.Repository.FindOneByPK(ctx, id, config, args...)
-	return item, err
+	s.publish(ctx, CrudOperationCreate, id, item)
+	return item, nil
 }
 
+// Update updates the entity by its primary key and returns it reloaded with config.
+// A missing entity yields (nil, nil): the follow-up read doubles as the existence check,
+// because rows-affected can't be trusted for that (MySQL reports 0 for unchanged rows).
 func (s *BaseCrudService[T, C, R]) Update(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error) {
-	if err := s.Repository.UpdateByPK(ctx, id, updateDto, args...); err != nil {
+	return s.update(ctx, id, func(ctx context.Context) (*T, error) {
+		return s.Repository.UpdateByPKReturning(ctx, id, updateDto, config, args...)
+	}, args...)
+}
+
+// UpdateVersioned is Update with optimistic locking (see GormConfig.VersionColumn): it fails
+// with repositories.ErrStaleVersion when the entity's version is no longer version.
+func (s *BaseCrudService[T, C, R]) UpdateVersioned(ctx context.Context, id any, version any, updateDto any, config *C, args ...any) (*T, error) {
+	return s.update(ctx, id, s.reloading(id, config, func(ctx context.Context) error {
+		return s.Repository.UpdateByPKVersioned(ctx, id, version, updateDto, args...)
+	}, args...), args...)
+}
+
+// PatchColumns updates the given columns (zero values and nulls included) restricted to the
+// repository's UpdatableColumns, and returns the entity reloaded with config, or nil if missing.
+func (s *BaseCrudService[T, C, R]) PatchColumns(ctx context.Context, id any, columns map[string]any, config *C, args ...any) (*T, error) {
+	return s.update(ctx, id, s.reloading(id, config, func(ctx context.Context) error {
+		return s.Repository.PatchColumnsByPK(ctx, id, columns, args...)
+	}, args...), args...)
+}
+
+// update runs write, which returns the updated entity (nil when it is missing), then audits
+// and publishes it.
+func (s *BaseCrudService[T, C, R]) update(ctx context.Context, id any, write func(ctx context.Context) (*T, error), args ...any) (*T, error) {
+	var item *T
+	err := s.mutate(ctx, func(ctx context.Context) error {
+		before, err := s.auditSnapshot(ctx, id, args...)
+		if err != nil {
+			return err
+		}
+		if item, err = write(ctx); err != nil || item == nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationUpdate, id, before, item)
+	})
+	if err != nil || item == nil {
 		return nil, err
 	}
-	return s.Repository.FindOneByPK(ctx, id, config, args...)
+	s.publish(ctx, CrudOperationUpdate, id, item)
+	return item, nil
+}
+
+// reloading turns a write that returns nothing into one for update, reloading the entity
+// with config afterwards.
+func (s *BaseCrudService[T, C, R]) reloading(id any, config *C, write func(ctx context.Context) error, args ...any) func(ctx context.Context) (*T, error) {
+	return func(ctx context.Context) (*T, error) {
+		if err := write(ctx); err != nil {
+			return nil, err
+		}
+		return s.Repository.FindOneByPK(ctx, id, config, args...)
+	}
 }
 
 func (s *BaseCrudService[T, C, R]) UpdateColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error {
-	return s.Repository.UpdateColumnsByPK(ctx, id, columns, args...)
+	err := s.mutate(ctx, func(ctx context.Context) error {
+		before, err := s.auditSnapshot(ctx, id, args...)
+		if err != nil {
+			return err
+		}
+		if err := s.Repository.UpdateColumnsByPK(ctx, id, columns, args...); err != nil {
+			return err
+		}
+		if s.Audit == nil {
+			return nil
+		}
+		after, err := s.Repository.FindOneByPK(ctx, id, nil, args...)
+		if err != nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationUpdate, id, before, after)
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, CrudOperationUpdate, id, columns)
+	return nil
 }
 
 func (s *BaseCrudService[T, C, R]) FindAll(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error) {
-	return s.Repository.FindAll(ctx, conditions, filter, config, args...)
+	items, err := s.Repository.FindAll(ctx, conditions, filter, config, args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.afterFind(ctx, items)
 }
 
 func (s *BaseCrudService[T, C, R]) FindAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error) {
-	return s.Repository.FindAllWithPaging(ctx, conditions, filter, config, args...)
+	response, err := s.Repository.FindAllWithPaging(ctx, conditions, filter, config, args...)
+	if err != nil {
+		return nil, err
+	}
+	read := len(response.Data)
+	if response.Data, err = s.afterFind(ctx, response.Data); err != nil {
+		return nil, err
+	}
+	if changed := int64(len(response.Data) - read); changed != 0 {
+		response.Total = max(response.Total+changed, 0)
+		if response.Metadata != nil {
+			response.Metadata.TotalFiltered = response.Total
+		}
+	}
+	return response, nil
+}
+
+func (s *BaseCrudService[T, C, R]) FindAllWithCursor(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error) {
+	response, err := s.Repository.FindAllWithCursor(ctx, conditions, cursor, limit, config, args...)
+	if err != nil {
+		return nil, err
+	}
+	if response.Data, err = s.afterFind(ctx, response.Data); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// afterFind runs the AfterFind hook, when set, on a list result.
+func (s *BaseCrudService[T, C, R]) afterFind(ctx context.Context, items []T) ([]T, error) {
+	if s.AfterFind == nil {
+		return items, nil
+	}
+	return s.AfterFind(ctx, items)
 }
 
 func (s *BaseCrudService[T, C, R]) FindOne(ctx context.Context, conditions any, config *C, args ...any) (*T, error) {
@@ -52,26 +187,133 @@ func (s *BaseCrudService[T, C, R]) FindOneByPK(ctx context.Context, id any, conf
 	return s.Repository.FindOneByPK(ctx, id, config, args...)
 }
 
+// FindOneByPKOrError is FindOneByPK returning models.ErrNotFound instead of (nil, nil).
+func (s *BaseCrudService[T, C, R]) FindOneByPKOrError(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	item, err := s.Repository.FindOneByPK(ctx, id, config, args...)
+	if err == nil && item == nil {
+		return nil, models.ErrNotFound
+	}
+	return item, err
+}
+
+func (s *BaseCrudService[T, C, R]) FindOneColumns(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error) {
+	return s.Repository.FindOneColumns(ctx, conditions, columns, config, args...)
+}
+
 func (s *BaseCrudService[T, C, R]) FindByIDs(ctx context.Context, ids []any, config *C, args ...any) ([]T, error) {
 	return s.Repository.FindByIDs(ctx, ids, config, args...)
 }
 
+// FindAllIDs returns the primary keys of every entity matching conditions, across all pages.
+func (s *BaseCrudService[T, C, R]) FindAllIDs(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error) {
+	return s.Repository.FindAllIDs(ctx, conditions, filter, config, args...)
+}
+
+// Delete deletes the entities matching conditions. The audit record and the event are
+// skipped when nothing matched.
 func (s *BaseCrudService[T, C, R]) Delete(ctx context.Context, conditions any, args ...any) error {
-	return s.Repository.Delete(ctx, conditions, args...)
+	var deleted int64
+	err := s.mutate(ctx, func(ctx context.Context) (err error) {
+		if deleted, err = s.Repository.DeleteRows(ctx, conditions, args...); err != nil || deleted == 0 {
+			return err
+		}
+		return s.audit(ctx, CrudOperationDelete, nil, nil, nil)
+	})
+	if err != nil || deleted == 0 {
+		return err
+	}
+	s.publish(ctx, CrudOperationDelete, nil, nil)
+	return nil
 }
 
 func (s *BaseCrudService[T, C, R]) DeleteOneByPK(ctx context.Context, id any, args ...any) error {
-	return s.Repository.DeleteOneByPK(ctx, id, args...)
+	err := s.mutate(ctx, func(ctx context.Context) error {
+		before, err := s.auditSnapshot(ctx, id, args...)
+		if err != nil {
+			return err
+		}
+		if err := s.Repository.DeleteOneByPK(ctx, id, args...); err != nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationDelete, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, CrudOperationDelete, id, nil)
+	return nil
+}
+
+// DeleteOneByPKReturning deletes the entity and returns it as it was, or nil if it didn't exist.
+func (s *BaseCrudService[T, C, R]) DeleteOneByPKReturning(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	var item *T
+	err := s.mutate(ctx, func(ctx context.Context) (err error) {
+		if item, err = s.Repository.DeleteOneByPKReturning(ctx, id, config, args...); err != nil || item == nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationDelete, id, item, nil)
+	})
+	if err != nil || item == nil {
+		return nil, err
+	}
+	s.publish(ctx, CrudOperationDelete, id, item)
+	return item, nil
 }
 
 func (s *BaseCrudService[T, C, R]) DeleteByIDs(ctx context.Context, ids []any, args ...any) error {
-	return s.Repository.DeleteByIDs(ctx, ids, args...)
+	err := s.mutate(ctx, func(ctx context.Context) error {
+		if err := s.Repository.DeleteByIDs(ctx, ids, args...); err != nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationDelete, ids, nil, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, CrudOperationDelete, ids, nil)
+	return nil
+}
+
+// Restore brings a soft-deleted entity back and returns it reloaded with config. An entity
+// that doesn't exist (or was hard-deleted) yields (nil, nil).
+func (s *BaseCrudService[T, C, R]) Restore(ctx context.Context, id any, config *C, args ...any) (*T, error) {
+	var item *T
+	err := s.mutate(ctx, func(ctx context.Context) (err error) {
+		if err := s.Repository.Restore(ctx, id, args...); err != nil {
+			return err
+		}
+		if item, err = s.Repository.FindOneByPK(ctx, id, config, args...); err != nil || item == nil {
+			return err
+		}
+		return s.audit(ctx, CrudOperationRestore, id, nil, item)
+	})
+	if err != nil || item == nil {
+		return nil, err
+	}
+	s.publish(ctx, CrudOperationRestore, id, item)
+	return item, nil
+}
+
+// PreviewDelete returns the number of rows Delete would remove and its SQL, without running it,
+// so admin tools can ask for confirmation first.
+func (s *BaseCrudService[T, C, R]) PreviewDelete(ctx context.Context, conditions any, args ...any) (*models.DryRun, error) {
+	return s.Repository.PreviewDelete(ctx, conditions, args...)
+}
+
+// PreviewUpdate returns the number of rows an update by conditions would change and its SQL,
+// without running it.
+func (s *BaseCrudService[T, C, R]) PreviewUpdate(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error) {
+	return s.Repository.PreviewUpdate(ctx, conditions, updateDto, args...)
 }
 
 func (s *BaseCrudService[T, C, R]) Count(ctx context.Context, conditions any, args ...any) (int64, error) {
 	return s.Repository.Count(ctx, conditions, args...)
 }
 
+func (s *BaseCrudService[T, C, R]) CountDistinct(ctx context.Context, column string, conditions any, args ...any) (int64, error) {
+	return s.Repository.CountDistinct(ctx, column, conditions, args...)
+}
+
 func (s *BaseCrudService[T, C, R]) Exists(ctx context.Context, conditions any, args ...any) (bool, error) {
 	return s.Repository.Exists(ctx, conditions, args...)
 }
@@ -84,6 +326,79 @@ func (s *BaseCrudService[T, C, R]) Pluck(ctx context.Context, column string, con
 	return s.Repository.Pluck(ctx, column, conditions, args...)
 }
 
-func (s *BaseCrudService[T, C, R]) QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (any, error) {
+func (s *BaseCrudService[T, C, R]) GroupByScan(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error) {
+	return s.Repository.GroupByScan(ctx, selects, groupBy, conditions, args...)
+}
+
+func (s *BaseCrudService[T, C, R]) Facets(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error) {
+	return s.Repository.Facets(ctx, conditions, facetColumns, args...)
+}
+
+func (s *BaseCrudService[T, C, R]) QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error) {
 	return s.Repository.QueryBuilder(ctx, filter, config, args...)
 }
+
+func (s *BaseCrudService[T, C, R]) publish(ctx context.Context, operation CrudOperation, id any, entity any) {
+	if s.Publisher == nil {
+		return
+	}
+	event := CrudEvent{
+		Model:     modelName[T](),
+		Operation: operation,
+		ID:        id,
+		Entity:    entity,
+		Actor:     middlewares.GetActor(ctx),
+	}
+	if pending := pendingEventsFromContext(ctx); pending != nil {
+		pending.add(pendingEvent{publisher: s.Publisher, event: event})
+		return
+	}
+	// inside a repository transaction (Transaction or ContextWithTx), wait for its commit
+	repositories.AfterCommit(ctx, func(ctx context.Context) { s.Publisher.Publish(ctx, event) })
+}
+
+// mutate runs fn, a mutation and its audit record, in one transaction when auditing is
+// enabled, so a failed AuditSink.Write rolls the mutation back. fn gets the transaction's
+// context. Events are published by the caller once mutate returns.
+func (s *BaseCrudService[T, C, R]) mutate(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.Audit == nil {
+		return fn(ctx)
+	}
+	return s.Repository.Transaction(ctx, fn)
+}
+
+// auditSnapshot loads the current state of an entity before a mutation, only when auditing is enabled.
+func (s *BaseCrudService[T, C, R]) auditSnapshot(ctx context.Context, id any, args ...any) (*T, error) {
+	if s.Audit == nil {
+		return nil, nil
+	}
+	return s.Repository.FindOneByPK(ctx, id, nil, args...)
+}
+
+func (s *BaseCrudService[T, C, R]) audit(ctx context.Context, operation CrudOperation, id any, before *T, after *T) error {
+	if s.Audit == nil {
+		return nil
+	}
+
+	record := AuditRecord{
+		Model:     modelName[T](),
+		Operation: operation,
+		ID:        id,
+		Timestamp: time.Now(),
+	}
+	if s.AuditActor != nil {
+		record.Actor = s.AuditActor(ctx)
+	} else {
+		record.Actor = middlewares.GetActor(ctx)
+	}
+	if before != nil {
+		record.Before = entityFields(before)
+	}
+	if after != nil {
+		record.After = entityFields(after)
+	}
+	if operation == CrudOperationUpdate {
+		record.Changes = diffFields(record.Before, record.After)
+	}
+	return s.Audit.Write(ctx, record)
+}
@@ -0,0 +1,549 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/aghiadodeh/go-crud/configs"
+	"github.com/aghiadodeh/go-crud/crudtest"
+	"github.com/aghiadodeh/go-crud/dto"
+	"github.com/aghiadodeh/go-crud/middlewares"
+	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
+	"github.com/aghiadodeh/go-crud/services"
+)
+
+type role struct {
+	ID    uint
+	Name  string
+	Level int
+}
+
+type mockRepository = crudtest.MockRepository[role, configs.GormConfig]
+
+type eventLog struct{ events []services.CrudEvent }
+
+func (l *eventLog) Publish(ctx context.Context, event services.CrudEvent) {
+	l.events = append(l.events, event)
+}
+
+type auditLog struct {
+	records []services.AuditRecord
+	err     error
+}
+
+func (l *auditLog) Write(ctx context.Context, record services.AuditRecord) error {
+	l.records = append(l.records, record)
+	return l.err
+}
+
+func operations[E any](items []E, operation func(E) services.CrudOperation) []services.CrudOperation {
+	var ops []services.CrudOperation
+	for _, item := range items {
+		ops = append(ops, operation(item))
+	}
+	return ops
+}
+
+func TestMutationsPublishAndAudit(t *testing.T) {
+	admin := &role{ID: 1, Name: "admin", Level: 1}
+	owner := &role{ID: 1, Name: "owner", Level: 1}
+	found := func(item *role) func(context.Context, any, *configs.GormConfig, ...any) (*role, error) {
+		return func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return item, nil }
+	}
+
+	tests := []struct {
+		name     string
+		mock     func(m *mockRepository)
+		run      func(ctx context.Context, s *services.GormCrudService[role]) error
+		auditErr error
+		wantErr  bool
+		events   []services.CrudOperation
+		audits   []services.CrudOperation
+		changes  map[string]services.AuditChange // of the first audit record
+	}{
+		{
+			name: "create",
+			mock: func(m *mockRepository) {
+				m.CreateFunc = func(context.Context, any, ...any) (any, error) { return uint(1), nil }
+				m.FindOneByPKFunc = found(admin)
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.Create(ctx, role{Name: "admin"}, nil)
+				return err
+			},
+			events: []services.CrudOperation{services.CrudOperationCreate},
+			audits: []services.CrudOperation{services.CrudOperationCreate},
+		},
+		{
+			name: "create failure",
+			mock: func(m *mockRepository) {
+				m.CreateFunc = func(context.Context, any, ...any) (any, error) { return nil, errors.New("duplicate") }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.Create(ctx, role{Name: "admin"}, nil)
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "update",
+			mock: func(m *mockRepository) {
+				m.FindOneByPKFunc = found(admin)
+				m.UpdateByPKReturningFunc = func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) { return owner, nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.Update(ctx, 1, map[string]any{"name": "owner"}, nil)
+				return err
+			},
+			events:  []services.CrudOperation{services.CrudOperationUpdate},
+			audits:  []services.CrudOperation{services.CrudOperationUpdate},
+			changes: map[string]services.AuditChange{"Name": {From: "admin", To: "owner"}},
+		},
+		{
+			name: "update of a missing entity",
+			mock: func(m *mockRepository) {
+				m.FindOneByPKFunc = found(nil)
+				m.UpdateByPKReturningFunc = func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) { return nil, nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.Update(ctx, 9, map[string]any{"name": "owner"}, nil)
+				return err
+			},
+		},
+		{
+			name: "patch columns",
+			mock: func(m *mockRepository) {
+				calls := 0
+				m.FindOneByPKFunc = func(context.Context, any, *configs.GormConfig, ...any) (*role, error) {
+					calls++
+					if calls == 1 {
+						return admin, nil
+					}
+					return &role{ID: 1, Name: "admin"}, nil
+				}
+				m.PatchColumnsByPKFunc = func(context.Context, any, map[string]any, ...any) error { return nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.PatchColumns(ctx, 1, map[string]any{"level": 0}, nil)
+				return err
+			},
+			events:  []services.CrudOperation{services.CrudOperationUpdate},
+			audits:  []services.CrudOperation{services.CrudOperationUpdate},
+			changes: map[string]services.AuditChange{"Level": {From: 1, To: 0}},
+		},
+		{
+			name: "delete by conditions",
+			mock: func(m *mockRepository) {
+				m.DeleteRowsFunc = func(context.Context, any, ...any) (int64, error) { return 2, nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				return s.Delete(ctx, map[string]any{"level": 1})
+			},
+			events: []services.CrudOperation{services.CrudOperationDelete},
+			audits: []services.CrudOperation{services.CrudOperationDelete},
+		},
+		{
+			name: "delete matching nothing",
+			mock: func(m *mockRepository) {
+				m.DeleteRowsFunc = func(context.Context, any, ...any) (int64, error) { return 0, nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				return s.Delete(ctx, map[string]any{"level": 7})
+			},
+		},
+		{
+			name: "delete by pk",
+			mock: func(m *mockRepository) {
+				m.FindOneByPKFunc = found(admin)
+				m.DeleteOneByPKFunc = func(context.Context, any, ...any) error { return nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				return s.DeleteOneByPK(ctx, 1)
+			},
+			events: []services.CrudOperation{services.CrudOperationDelete},
+			audits: []services.CrudOperation{services.CrudOperationDelete},
+		},
+		{
+			name: "restore",
+			mock: func(m *mockRepository) {
+				m.RestoreFunc = func(context.Context, any, ...any) error { return nil }
+				m.FindOneByPKFunc = found(admin)
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				_, err := s.Restore(ctx, 1, nil)
+				return err
+			},
+			events: []services.CrudOperation{services.CrudOperationRestore},
+			audits: []services.CrudOperation{services.CrudOperationRestore},
+		},
+		{
+			name: "audit failure rolls back",
+			mock: func(m *mockRepository) {
+				m.FindOneByPKFunc = found(admin)
+				m.DeleteOneByPKFunc = func(context.Context, any, ...any) error { return nil }
+			},
+			run: func(ctx context.Context, s *services.GormCrudService[role]) error {
+				return s.DeleteOneByPK(ctx, 1)
+			},
+			auditErr: errors.New("audit store down"),
+			wantErr:  true,
+			audits:   []services.CrudOperation{services.CrudOperationDelete},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockRepository{}
+			tt.mock(repository)
+			events, audits := &eventLog{}, &auditLog{err: tt.auditErr}
+			service := services.NewGormCrudService[role](repository)
+			service.Publisher = events
+			service.Audit = audits
+
+			ctx := middlewares.SetActor(context.Background(), "alice")
+			if err := tt.run(ctx, service); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			gotEvents := operations(events.events, func(e services.CrudEvent) services.CrudOperation { return e.Operation })
+			if !reflect.DeepEqual(gotEvents, tt.events) {
+				t.Errorf("events = %v, want %v", gotEvents, tt.events)
+			}
+			gotAudits := operations(audits.records, func(r services.AuditRecord) services.CrudOperation { return r.Operation })
+			if !reflect.DeepEqual(gotAudits, tt.audits) {
+				t.Errorf("audit records = %v, want %v", gotAudits, tt.audits)
+			}
+			for _, event := range events.events {
+				if event.Model != "role" || event.Actor != "alice" {
+					t.Errorf("event = %+v, want model role by alice", event)
+				}
+			}
+			for _, record := range audits.records {
+				if record.Actor != "alice" {
+					t.Errorf("audit actor = %v, want alice", record.Actor)
+				}
+			}
+			if tt.changes != nil && !reflect.DeepEqual(audits.records[0].Changes, tt.changes) {
+				t.Errorf("changes = %v, want %v", audits.records[0].Changes, tt.changes)
+			}
+			if len(repository.CallsTo("Transaction")) != 1 {
+				t.Errorf("ran %d transactions, want the mutation in one", len(repository.CallsTo("Transaction")))
+			}
+		})
+	}
+}
+
+func TestMutationsWithoutAudit(t *testing.T) {
+	repository := &mockRepository{
+		UpdateByPKReturningFunc: func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) {
+			return &role{ID: 1, Name: "owner"}, nil
+		},
+	}
+	events := &eventLog{}
+	service := services.NewGormCrudService[role](repository)
+	service.Publisher = events
+
+	if _, err := service.Update(context.Background(), 1, map[string]any{"name": "owner"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls := repository.Calls(); len(calls) != 1 || calls[0].Method != "UpdateByPKReturning" {
+		t.Errorf("calls = %v, want a single UpdateByPKReturning", calls)
+	}
+	if len(events.events) != 1 || events.events[0].Actor != nil {
+		t.Errorf("events = %+v, want one anonymous update", events.events)
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	repository := &mockRepository{
+		DeleteByIDsFunc: func(context.Context, []any, ...any) error { return nil },
+	}
+	audits := &auditLog{}
+	service := services.NewGormCrudService[role](repository)
+	service.Audit = audits
+	service.AuditActor = func(context.Context) any { return "system" }
+
+	if err := service.DeleteByIDs(context.Background(), []any{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(audits.records) != 1 || audits.records[0].Actor != "system" || !reflect.DeepEqual(audits.records[0].ID, []any{1, 2}) {
+		t.Errorf("audit records = %+v, want one by system for ids 1 and 2", audits.records)
+	}
+}
+
+func TestAfterFind(t *testing.T) {
+	page := []role{{ID: 1}, {ID: 2}, {ID: 3}}
+	tests := []struct {
+		name     string
+		hook     func(ctx context.Context, items []role) ([]role, error)
+		total    int64
+		metadata bool
+		rows     int
+		want     int64
+		wantErr  bool
+	}{
+		{"no hook", nil, 10, false, 3, 10, false},
+		{"unchanged", func(_ context.Context, items []role) ([]role, error) { return items, nil }, 10, true, 3, 10, false},
+		{"drops one", func(_ context.Context, items []role) ([]role, error) { return items[1:], nil }, 10, true, 2, 9, false},
+		{"adds one", func(_ context.Context, items []role) ([]role, error) { return append(items, role{ID: 9}), nil }, 10, false, 4, 11, false},
+		{"never below zero", func(context.Context, []role) ([]role, error) { return nil, nil }, 1, false, 0, 0, false},
+		{"error", func(context.Context, []role) ([]role, error) { return nil, errors.New("masking failed") }, 10, false, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockRepository{
+				FindAllWithPagingFunc: func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) (*models.ListResponse[role], error) {
+					response := &models.ListResponse[role]{Total: tt.total, Data: append([]role(nil), page...)}
+					if tt.metadata {
+						response.Metadata = &models.ListMetadata{TotalFiltered: tt.total, TotalUnfiltered: 50}
+					}
+					return response, nil
+				},
+				FindAllFunc: func(context.Context, any, dto.FilterDto, *configs.GormConfig, ...any) ([]role, error) {
+					return append([]role(nil), page...), nil
+				},
+			}
+			service := services.NewGormCrudService[role](repository)
+			service.AfterFind = tt.hook
+
+			response, err := service.FindAllWithPaging(context.Background(), nil, &dto.BaseFilterDto{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(response.Data) != tt.rows || response.Total != tt.want {
+				t.Errorf("page = %d rows of %d, want %d of %d", len(response.Data), response.Total, tt.rows, tt.want)
+			}
+			if tt.metadata && (response.Metadata.TotalFiltered != tt.want || response.Metadata.TotalUnfiltered != 50) {
+				t.Errorf("metadata = %+v, want filtered %d of 50", response.Metadata, tt.want)
+			}
+
+			items, err := service.FindAll(context.Background(), nil, &dto.BaseFilterDto{}, nil)
+			if err != nil || len(items) != tt.rows {
+				t.Errorf("FindAll() = %d items, %v; want %d", len(items), err, tt.rows)
+			}
+		})
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	created := func(context.Context, any, *configs.GormConfig, ...any) (*role, error) {
+		return &role{ID: 1, Name: "admin"}, nil
+	}
+	create := func(ctx context.Context, tx services.IBaseCrudService[role, configs.GormConfig]) error {
+		_, err := tx.Create(ctx, role{Name: "admin"}, nil)
+		return err
+	}
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, s *services.GormCrudService[role], events *eventLog) error
+		retried bool
+		events  int
+	}{
+		{
+			"published on commit",
+			func(ctx context.Context, s *services.GormCrudService[role], events *eventLog) error {
+				return s.WithTx(ctx, func(ctx context.Context, tx services.IBaseCrudService[role, configs.GormConfig]) error {
+					if err := create(ctx, tx); err != nil {
+						return err
+					}
+					if len(events.events) != 0 {
+						t.Errorf("published %d events before the commit", len(events.events))
+					}
+					return nil
+				})
+			},
+			false,
+			1,
+		},
+		{
+			"dropped on rollback",
+			func(ctx context.Context, s *services.GormCrudService[role], _ *eventLog) error {
+				return s.WithTx(ctx, func(ctx context.Context, tx services.IBaseCrudService[role, configs.GormConfig]) error {
+					if err := create(ctx, tx); err != nil {
+						return err
+					}
+					return errors.New("rollback")
+				})
+			},
+			false,
+			0,
+		},
+		{
+			"nested published by the outermost",
+			func(ctx context.Context, s *services.GormCrudService[role], events *eventLog) error {
+				return s.WithTx(ctx, func(ctx context.Context, tx services.IBaseCrudService[role, configs.GormConfig]) error {
+					if err := s.WithTx(ctx, create); err != nil {
+						return err
+					}
+					if len(events.events) != 0 {
+						t.Errorf("published %d events before the outer commit", len(events.events))
+					}
+					return create(ctx, tx)
+				})
+			},
+			false,
+			2,
+		},
+		{
+			"retried attempt published once",
+			func(ctx context.Context, s *services.GormCrudService[role], _ *eventLog) error {
+				return s.WithTx(ctx, create)
+			},
+			true,
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockRepository{
+				CreateFunc:      func(context.Context, any, ...any) (any, error) { return uint(1), nil },
+				FindOneByPKFunc: created,
+			}
+			if tt.retried {
+				// the first attempt fails with a retryable error after raising its events
+				repository.TransactionFunc = func(ctx context.Context, fn func(context.Context) error) error {
+					_ = fn(ctx)
+					return fn(ctx)
+				}
+			}
+			events := &eventLog{}
+			service := services.NewGormCrudService[role](repository)
+			service.Publisher = events
+
+			_ = tt.run(context.Background(), service, events)
+			if len(events.events) != tt.events {
+				t.Errorf("published %d events, want %d", len(events.events), tt.events)
+			}
+		})
+	}
+}
+
+func TestEventsInRepositoryTransaction(t *testing.T) {
+	update := func(ctx context.Context, s *services.GormCrudService[role]) error {
+		_, err := s.Update(ctx, 1, map[string]any{"name": "owner"}, nil)
+		return err
+	}
+	tests := []struct {
+		name   string
+		run    func(ctx context.Context, s *services.GormCrudService[role], repository *mockRepository, events *eventLog) error
+		events int
+	}{
+		{
+			"published on commit",
+			func(ctx context.Context, s *services.GormCrudService[role], repository *mockRepository, events *eventLog) error {
+				return repository.Transaction(ctx, func(ctx context.Context) error {
+					if err := update(ctx, s); err != nil {
+						return err
+					}
+					if len(events.events) != 0 {
+						t.Errorf("published %d events before the commit", len(events.events))
+					}
+					return nil
+				})
+			},
+			1,
+		},
+		{
+			"dropped on rollback",
+			func(ctx context.Context, s *services.GormCrudService[role], repository *mockRepository, _ *eventLog) error {
+				return repository.Transaction(ctx, func(ctx context.Context) error {
+					if err := update(ctx, s); err != nil {
+						return err
+					}
+					return errors.New("rollback")
+				})
+			},
+			0,
+		},
+		{
+			"WithTx inside published by the outer commit",
+			func(ctx context.Context, s *services.GormCrudService[role], repository *mockRepository, events *eventLog) error {
+				return repository.Transaction(ctx, func(ctx context.Context) error {
+					err := s.WithTx(ctx, func(ctx context.Context, _ services.IBaseCrudService[role, configs.GormConfig]) error {
+						return update(ctx, s)
+					})
+					if len(events.events) != 0 {
+						t.Errorf("published %d events before the outer commit", len(events.events))
+					}
+					return err
+				})
+			},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockRepository{
+				UpdateByPKReturningFunc: func(context.Context, any, any, *configs.GormConfig, ...any) (*role, error) {
+					return &role{ID: 1, Name: "owner"}, nil
+				},
+				// a transaction managed outside the repository, as with ContextWithTx
+				TransactionFunc: func(ctx context.Context, fn func(context.Context) error) error {
+					txCtx := repositories.ContextWithTx(ctx, &gorm.DB{})
+					if err := fn(txCtx); err != nil {
+						return err
+					}
+					repositories.Committed(txCtx)
+					return nil
+				},
+			}
+			events := &eventLog{}
+			service := services.NewGormCrudService[role](repository)
+			service.Publisher = events
+
+			_ = tt.run(context.Background(), service, repository, events)
+			if len(events.events) != tt.events {
+				t.Errorf("published %d events, want %d", len(events.events), tt.events)
+			}
+		})
+	}
+}
+
+func TestFindOneByPKOrError(t *testing.T) {
+	tests := []struct {
+		name string
+		item *role
+		err  error
+		want error
+	}{
+		{"found", &role{ID: 1}, nil, nil},
+		{"missing", nil, nil, models.ErrNotFound},
+		{"failure", nil, context.DeadlineExceeded, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mockRepository{
+				FindOneByPKFunc: func(context.Context, any, *configs.GormConfig, ...any) (*role, error) { return tt.item, tt.err },
+			}
+			item, err := services.NewGormCrudService[role](repository).FindOneByPKOrError(context.Background(), 1, nil)
+			if !errors.Is(err, tt.want) || (tt.want == nil && item != tt.item) {
+				t.Errorf("FindOneByPKOrError() = %v, %v; want %v", item, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewDoesNotWrite(t *testing.T) {
+	preview := &models.DryRun{Affected: 3, SQL: []string{"DELETE FROM roles WHERE level = 1"}}
+	repository := &mockRepository{
+		PreviewDeleteFunc: func(context.Context, any, ...any) (*models.DryRun, error) { return preview, nil },
+	}
+	events := &eventLog{}
+	service := services.NewGormCrudService[role](repository)
+	service.Publisher = events
+
+	got, err := service.PreviewDelete(context.Background(), map[string]any{"level": 1})
+	if err != nil || got != preview {
+		t.Fatalf("PreviewDelete() = %+v, %v", got, err)
+	}
+	if calls := repository.Calls(); len(calls) != 1 || len(events.events) != 0 {
+		t.Errorf("calls = %v, events = %v; want only the preview", calls, events.events)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"reflect"
+)
+
+type CrudOperation string
+
+const (
+	CrudOperationCreate  CrudOperation = "create"
+	CrudOperationUpdate  CrudOperation = "update"
+	CrudOperationDelete  CrudOperation = "delete"
+	CrudOperationRestore CrudOperation = "restore"
+)
+
+// CrudEvent describes a successful mutation performed through BaseCrudService.
+type CrudEvent struct {
+	Model     string        // entity type name, e.g. "Role"
+	Operation CrudOperation // create, update, delete or restore
+	ID        any           // primary key, a slice of keys for DeleteByIDs, nil for condition-based deletes
+	Entity    any           // the persisted entity when available (created/updated *T, updated columns map)
+	Actor     any           // the acting user (middlewares.GetActor), nil when anonymous
+}
+
+// EventPublisher receives CrudEvents after each successful mutation.
+// Implementations (Kafka, NATS, webhooks...) own their delivery and error handling.
+type EventPublisher interface {
+	Publish(ctx context.Context, event CrudEvent)
+}
+
+// NoopEventPublisher discards every event. It is the default publisher.
+type NoopEventPublisher struct{}
+
+func (NoopEventPublisher) Publish(ctx context.Context, event CrudEvent) {}
+
+func modelName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().Name()
+}
@@ -5,23 +5,38 @@ import (
 
 	"github.com/aghiadodeh/go-crud/dto"
 	"github.com/aghiadodeh/go-crud/models"
+	"github.com/aghiadodeh/go-crud/repositories"
 )
 
 type IBaseCrudService[T any, C any] interface {
 	Create(ctx context.Context, createDto any, config *C, args ...any) (*T, error)
 	Update(ctx context.Context, id any, updateDto any, config *C, args ...any) (*T, error)
+	UpdateVersioned(ctx context.Context, id any, version any, updateDto any, config *C, args ...any) (*T, error)
 	UpdateColumnsByPK(ctx context.Context, id any, columns map[string]any, args ...any) error
+	PatchColumns(ctx context.Context, id any, columns map[string]any, config *C, args ...any) (*T, error)
 	FindAll(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]T, error)
 	FindAllWithPaging(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) (*models.ListResponse[T], error)
+	FindAllWithCursor(ctx context.Context, conditions any, cursor string, limit int, config *C, args ...any) (*models.CursorResponse[T], error)
 	FindOne(ctx context.Context, conditions any, config *C, args ...any) (*T, error)
 	FindOneByPK(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	FindOneByPKOrError(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	FindOneColumns(ctx context.Context, conditions any, columns []string, config *C, args ...any) (*T, error)
 	FindByIDs(ctx context.Context, ids []any, config *C, args ...any) ([]T, error)
+	FindAllIDs(ctx context.Context, conditions any, filter dto.FilterDto, config *C, args ...any) ([]any, error)
 	Delete(ctx context.Context, conditions any, args ...any) error
 	DeleteOneByPK(ctx context.Context, id any, args ...any) error
+	DeleteOneByPKReturning(ctx context.Context, id any, config *C, args ...any) (*T, error)
 	DeleteByIDs(ctx context.Context, ids []any, args ...any) error
+	Restore(ctx context.Context, id any, config *C, args ...any) (*T, error)
+	PreviewDelete(ctx context.Context, conditions any, args ...any) (*models.DryRun, error)
+	PreviewUpdate(ctx context.Context, conditions any, updateDto any, args ...any) (*models.DryRun, error)
 	Count(ctx context.Context, conditions any, args ...any) (int64, error)
+	CountDistinct(ctx context.Context, column string, conditions any, args ...any) (int64, error)
 	Exists(ctx context.Context, conditions any, args ...any) (bool, error)
 	ExistsByPK(ctx context.Context, id any, args ...any) (bool, error)
 	Pluck(ctx context.Context, column string, conditions any, args ...any) ([]any, error)
-	QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (any, error)
+	GroupByScan(ctx context.Context, selects []string, groupBy string, conditions any, args ...any) ([]map[string]any, error)
+	Facets(ctx context.Context, conditions any, facetColumns []string, args ...any) (map[string]map[string]int64, error)
+	QueryBuilder(ctx context.Context, filter dto.FilterDto, config *C, args ...any) (*repositories.Condition, error)
+	WithTx(ctx context.Context, fn func(ctx context.Context, tx IBaseCrudService[T, C]) error) error
 }
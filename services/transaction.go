@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aghiadodeh/go-crud/repositories"
+)
+
+type pendingEventsKey struct{}
+
+// pendingEvents buffers the events raised inside WithTx until the transaction commits.
+type pendingEvents struct {
+	mu     sync.Mutex
+	events []pendingEvent
+}
+
+type pendingEvent struct {
+	publisher EventPublisher
+	event     CrudEvent
+}
+
+func (p *pendingEvents) add(events ...pendingEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, events...)
+}
+
+// take empties the buffer and returns the events it held.
+func (p *pendingEvents) take() []pendingEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := p.events
+	p.events = nil
+	return events
+}
+
+// flush publishes the buffered events, outside the lock so publishers may raise new ones.
+// Inside an enclosing repository transaction they wait for its commit.
+func (p *pendingEvents) flush(ctx context.Context) {
+	for _, pending := range p.take() {
+		repositories.AfterCommit(ctx, func(ctx context.Context) { pending.publisher.Publish(ctx, pending.event) })
+	}
+}
+
+func pendingEventsFromContext(ctx context.Context) *pendingEvents {
+	pending, _ := ctx.Value(pendingEventsKey{}).(*pendingEvents)
+	return pending
+}
+
+// WithTx runs fn inside a transaction and passes it a context bound to that transaction
+// together with the service itself. Every service or repository called with that context
+// (whatever its entity) joins the transaction, so multi-entity use cases commit or roll
+// back as a whole:
+//
+//	err := orders.WithTx(ctx, func(ctx context.Context, tx services.IBaseCrudService[Order, configs.GormConfig]) error {
+//		order, err := tx.Create(ctx, orderDto, nil)
+//		if err != nil {
+//			return err
+//		}
+//		if err := products.UpdateColumnsByPK(ctx, productID, map[string]any{"stock": gorm.Expr("stock - ?", qty)}); err != nil {
+//			return err
+//		}
+//		_, err = ledger.Create(ctx, entryFor(order), nil)
+//		return err
+//	})
+//
+// Audit records are written inside the transaction; events are published only once the
+// outermost transaction has committed, and dropped when it rolls back.
+func (s *BaseCrudService[T, C, R]) WithTx(ctx context.Context, fn func(ctx context.Context, tx IBaseCrudService[T, C]) error) error {
+	var pending *pendingEvents
+	err := s.Repository.Transaction(ctx, func(txCtx context.Context) error {
+		// a retried attempt starts over, so the events of a rolled-back one are dropped
+		pending = &pendingEvents{}
+		return fn(context.WithValue(txCtx, pendingEventsKey{}, pending), s)
+	})
+	if err != nil {
+		return err
+	}
+
+	if outer := pendingEventsFromContext(ctx); outer != nil {
+		outer.add(pending.take()...)
+		return nil
+	}
+	pending.flush(ctx)
+	return nil
+}